@@ -0,0 +1,90 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/giantswarm/microerror"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	capa "sigs.k8s.io/cluster-api-provider-aws/v2/api/v1beta2"
+	eks "sigs.k8s.io/cluster-api-provider-aws/v2/controlplane/eks/api/v1beta2"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/giantswarm/capa-iam-operator/v3/pkg/iam"
+	"github.com/giantswarm/capa-iam-operator/v3/pkg/key"
+)
+
+// loadAdditionalPolicyStatements returns the additional policy statements of a cluster from the ConfigMap referenced by
+// the key.AdditionalStatementsAnnotation annotation on the given object.
+func loadAdditionalPolicyStatements(ctx context.Context, ctrlClient client.Client, o client.Object) (iam.AdditionalPolicyStatements, error) {
+	name := key.GetAnnotation(o, key.AdditionalStatementsAnnotation)
+	if name == "" {
+		return nil, nil
+	}
+
+	cm := &corev1.ConfigMap{}
+	err := ctrlClient.Get(ctx, types.NamespacedName{Name: name, Namespace: o.GetNamespace()}, cm)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get ConfigMap %s/%s referenced by annotation %q: %w", o.GetNamespace(), name, key.AdditionalStatementsAnnotation, err)
+	}
+
+	statements, err := iam.ParseAdditionalPolicyStatements(cm.Data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid additional policy statements in ConfigMap %s/%s: %w", o.GetNamespace(), name, err)
+	}
+
+	return statements, nil
+}
+
+// additionalStatementsIndex is the field index of AWSClusters and AWSManagedControlPlanes by the ConfigMap they
+// reference with the key.AdditionalStatementsAnnotation annotation.
+const additionalStatementsIndex = "metadata.annotations.additionalStatementsConfigMap"
+
+// indexAdditionalStatements returns the values of additionalStatementsIndex for an object.
+func indexAdditionalStatements(o client.Object) []string {
+	name := key.GetAnnotation(o, key.AdditionalStatementsAnnotation)
+	if name == "" {
+		return nil
+	}
+	return []string{name}
+}
+
+// referencingClusters returns the names of the clusters in the namespace of a ConfigMap whose AWSCluster or
+// AWSManagedControlPlane reference the ConfigMap with the key.AdditionalStatementsAnnotation annotation. The objects
+// are looked up through additionalStatementsIndex, so ConfigMaps that are not referenced cost no more than an index
+// lookup.
+func referencingClusters(ctx context.Context, ctrlClient client.Client, cm client.Object) ([]string, error) {
+	opts := []client.ListOption{
+		client.InNamespace(cm.GetNamespace()),
+		client.MatchingFields{additionalStatementsIndex: cm.GetName()},
+	}
+
+	var objects []client.Object
+
+	awsClusters := &capa.AWSClusterList{}
+	err := ctrlClient.List(ctx, awsClusters, opts...)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+	for i := range awsClusters.Items {
+		objects = append(objects, &awsClusters.Items[i])
+	}
+
+	eksClusters := &eks.AWSManagedControlPlaneList{}
+	err = ctrlClient.List(ctx, eksClusters, opts...)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+	for i := range eksClusters.Items {
+		objects = append(objects, &eksClusters.Items[i])
+	}
+
+	var clusterNames []string
+	for _, o := range objects {
+		if clusterName := o.GetLabels()[key.ClusterNameLabel]; clusterName != "" {
+			clusterNames = append(clusterNames, clusterName)
+		}
+	}
+	return clusterNames, nil
+}
@@ -29,7 +29,9 @@ import (
 	"sigs.k8s.io/cluster-api/util"
 
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
 	capa "sigs.k8s.io/cluster-api-provider-aws/v2/api/v1beta2"
+	expcapi "sigs.k8s.io/cluster-api/exp/api/v1beta1"
 	"sigs.k8s.io/cluster-api/util/patch"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -47,6 +49,18 @@ type AWSMachineTemplateReconciler struct {
 	EnableRoute53Role bool
 	AWSClient         awsclient.AwsClientInterface
 	IAMClientFactory  func(aws.Config, string) iam.IAMClient
+	// Recorder records events on the objects referencing AWS identities.
+	Recorder record.EventRecorder
+
+	ManagedPolicyRoleTypes []string
+	PermissionsBoundary    string
+	RoleSettings           iam.RoleSettings
+	PolicyTemplates        PolicyTemplatesConfig
+	ConfigMaps             *ConfigMapWatch
+	// IRSARoleTypes lists the IRSA roles created for clusters unless overridden per cluster. Nil means all.
+	IRSARoleTypes []string
+	// ManageOIDCProviders enables creating and deleting the IAM OIDC providers of the IRSA trust domains of clusters.
+	ManageOIDCProviders bool
 }
 
 // +kubebuilder:rbac:groups=infrastructure.cluster.x-k8s.io,resources=awsmachinetemplates,verbs=get;list;watch;create;update;patch;delete
@@ -57,7 +71,7 @@ type AWSMachineTemplateReconciler struct {
 // move the current state of the cluster closer to the desired state.
 // For more details, check Reconcile and its Result here:
 // - https://pkg.go.dev/sigs.k8s.io/controller-runtime@v0.8.3/pkg/reconcile
-func (r *AWSMachineTemplateReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+func (r *AWSMachineTemplateReconciler) Reconcile(ctx context.Context, req ctrl.Request) (result ctrl.Result, reterr error) {
 	logger := log.FromContext(ctx)
 
 	awsMachineTemplate := &capa.AWSMachineTemplate{}
@@ -119,31 +133,86 @@ func (r *AWSMachineTemplateReconciler) Reconcile(ctx context.Context, req ctrl.R
 		return ctrl.Result{}, microerror.Mask(err)
 	}
 
-	awsClusterRoleIdentity, err := key.GetAWSClusterRoleIdentity(ctx, r.Client, awsCluster.Spec.IdentityRef.Name)
+	// errors of AWS calls decide how the reconciliation is retried from here on
+	defer func() {
+		result, reterr = handleReconcileResult(ctx, r.Client, r.Recorder, awsCluster, "AWSMachineTemplate "+awsMachineTemplate.Name, awsMachineTemplate.DeletionTimestamp != nil, result, reterr)
+	}()
+
+	awsClientConfig, err := getAWSClientConfig(ctx, r.Client, r.AWSClient, r.Recorder, awsCluster, awsCluster.Spec.IdentityRef, awsCluster.Spec.Region)
 	if err != nil {
-		logger.Error(err, "could not get AWSClusterRoleIdentity")
-		return ctrl.Result{}, microerror.Mask(err)
+		logger.Error(err, "Failed to get aws client session")
+		return ctrl.Result{}, err
 	}
 
-	awsClientConfig, err := r.AWSClient.GetAWSClientConfig(awsClusterRoleIdentity.Spec.RoleArn, awsCluster.Spec.Region)
+	roleSettings, err := key.GetRoleSettings(awsCluster, r.RoleSettings)
 	if err != nil {
-		logger.Error(err, "Failed to get aws client session")
+		logger.Error(err, "invalid role settings")
+		return ctrl.Result{}, err
+	}
+
+	irsaRoleTypes, err := key.GetIRSARoleTypes(awsCluster, r.IRSARoleTypes)
+	if err != nil {
+		logger.Error(err, "invalid IRSA role types")
+		return ctrl.Result{}, err
+	}
+
+	serviceAccounts, err := key.GetServiceAccounts(awsCluster)
+	if err != nil {
+		logger.Error(err, "invalid service accounts")
+		return ctrl.Result{}, err
+	}
+
+	oidcProviderThumbprints, err := key.GetOIDCProviderThumbprints(awsCluster)
+	if err != nil {
+		logger.Error(err, "invalid OIDC provider thumbprints")
+		return ctrl.Result{}, err
+	}
+
+	var karpenter iam.KarpenterConfig
+	if role == iam.ControlPlaneRole {
+		karpenter, err = getKarpenterConfig(ctx, r.Client, awsCluster, clusterName)
+		if err != nil {
+			logger.Error(err, "failed to get Karpenter configuration")
+			return ctrl.Result{}, err
+		}
+	}
+
+	policyTemplates, err := r.PolicyTemplates.load(ctx, r.Client, awsMachineTemplate.Namespace)
+	if err != nil {
+		logger.Error(err, "failed to load policy templates")
+		return ctrl.Result{}, err
+	}
+
+	additionalStatements, err := loadAdditionalPolicyStatements(ctx, r.Client, awsCluster)
+	if err != nil {
+		logger.Error(err, "failed to load additional policy statements")
 		return ctrl.Result{}, err
 	}
 
 	var iamService *iam.IAMService
 	{
 		c := iam.IAMServiceConfig{
-			AWSConfig:             &awsClientConfig,
-			ClusterIsBeingDeleted: cluster.DeletionTimestamp != nil,
-			ClusterName:           clusterName,
-			ClusterRelease:        cluster.Labels[GiantSwarmReleaseLabel],
-			MainRoleName:          awsMachineTemplate.Spec.Template.Spec.IAMInstanceProfile,
-			Log:                   logger,
-			RoleType:              role,
-			Region:                awsCluster.Spec.Region,
-			IAMClientFactory:      r.IAMClientFactory,
-			CustomTags:            awsCluster.Spec.AdditionalTags,
+			AWSConfig:               &awsClientConfig,
+			ClusterIsBeingDeleted:   cluster.DeletionTimestamp != nil,
+			ClusterName:             clusterName,
+			ClusterRelease:          cluster.Labels[GiantSwarmReleaseLabel],
+			MainRoleName:            awsMachineTemplate.Spec.Template.Spec.IAMInstanceProfile,
+			Log:                     logger,
+			RoleType:                role,
+			Region:                  awsCluster.Spec.Region,
+			IAMClientFactory:        r.IAMClientFactory,
+			CustomTags:              awsCluster.Spec.AdditionalTags,
+			ManagedPolicyRoleTypes:  r.ManagedPolicyRoleTypes,
+			PermissionsBoundary:     key.GetPermissionsBoundary(awsCluster, r.PermissionsBoundary),
+			RoleSettings:            roleSettings,
+			PolicyTemplates:         policyTemplates,
+			AdditionalStatements:    additionalStatements,
+			IRSARoleTypes:           irsaRoleTypes,
+			ServiceAccounts:         serviceAccounts,
+			FeatureGates:            map[string]bool{iam.FeatureGateKarpenter: len(karpenter.NodeRoleNames) > 0},
+			Karpenter:               karpenter,
+			ManageOIDCProviders:     r.ManageOIDCProviders,
+			OIDCProviderThumbprints: oidcProviderThumbprints,
 		}
 		iamService, err = iam.New(c)
 		if err != nil {
@@ -155,7 +224,7 @@ func (r *AWSMachineTemplateReconciler) Reconcile(ctx context.Context, req ctrl.R
 	if awsMachineTemplate.DeletionTimestamp != nil {
 		return r.reconcileDelete(ctx, iamService, awsMachineTemplate, clusterName, req.Namespace, role)
 	}
-	return r.reconcileNormal(ctx, iamService, awsMachineTemplate, awsCluster, clusterName, role)
+	return r.reconcileNormal(ctx, iamService, awsClientConfig, awsMachineTemplate, awsCluster, clusterName, role)
 }
 
 func (r *AWSMachineTemplateReconciler) reconcileDelete(ctx context.Context, iamService *iam.IAMService, awsMachineTemplate *capa.AWSMachineTemplate, clusterName, namespace, role string) (ctrl.Result, error) {
@@ -177,6 +246,11 @@ func (r *AWSMachineTemplateReconciler) reconcileDelete(ctx context.Context, iamS
 				if err != nil {
 					return ctrl.Result{}, err
 				}
+
+				err = iamService.DeleteOIDCProviders()
+				if err != nil {
+					return ctrl.Result{}, err
+				}
 			}
 		}
 	}
@@ -221,7 +295,7 @@ func (r *AWSMachineTemplateReconciler) reconcileDelete(ctx context.Context, iamS
 	return ctrl.Result{}, nil
 }
 
-func (r *AWSMachineTemplateReconciler) reconcileNormal(ctx context.Context, iamService *iam.IAMService, awsMachineTemplate *capa.AWSMachineTemplate, awsCluster *capa.AWSCluster, clusterName, role string) (ctrl.Result, error) {
+func (r *AWSMachineTemplateReconciler) reconcileNormal(ctx context.Context, iamService *iam.IAMService, awsClientConfig aws.Config, awsMachineTemplate *capa.AWSMachineTemplate, awsCluster *capa.AWSCluster, clusterName, role string) (ctrl.Result, error) {
 	logger := log.FromContext(ctx)
 
 	// add finalizer to AWSMachineTemplate
@@ -263,14 +337,7 @@ func (r *AWSMachineTemplateReconciler) reconcileNormal(ctx context.Context, iamS
 		// route53 role depends on IRSA role
 		if r.EnableRoute53Role {
 			logger.Info("reconciling IRSA roles")
-			identityRefName := awsCluster.Spec.IdentityRef.Name
-			awsClusterRoleIdentity, err := key.GetAWSClusterRoleIdentity(ctx, r.Client, identityRefName)
-			if err != nil {
-				logger.Error(err, "could not get AWSClusterRoleIdentity")
-				return ctrl.Result{}, errors.WithStack(err)
-			}
-
-			accountID, err := key.GetAWSAccountID(awsClusterRoleIdentity)
+			accountID, err := r.AWSClient.GetAWSAccountID(ctx, awsCluster.Spec.IdentityRef, awsClientConfig)
 			if err != nil {
 				logger.Error(err, "Could not get account ID")
 				return ctrl.Result{}, errors.WithStack(err)
@@ -286,6 +353,15 @@ func (r *AWSMachineTemplateReconciler) reconcileNormal(ctx context.Context, iamS
 
 			irsaTrustDomains := key.GetIRSATrustDomains(awsMachineTemplate, awsCluster, irsaDomain)
 
+			missingTrustDomains, err := iamService.ReconcileOIDCProviders(accountID, irsaTrustDomains)
+			if err != nil {
+				return ctrl.Result{}, errors.WithStack(err)
+			}
+			err = reportOIDCProviders(ctx, r.Client, r.Recorder, awsCluster, missingTrustDomains)
+			if err != nil {
+				return ctrl.Result{}, errors.WithStack(err)
+			}
+
 			err = iamService.ReconcileRolesForIRSA(accountID, irsaTrustDomains)
 			if err != nil {
 				return ctrl.Result{}, errors.WithStack(err)
@@ -300,5 +376,7 @@ func (r *AWSMachineTemplateReconciler) reconcileNormal(ctx context.Context, iamS
 func (r *AWSMachineTemplateReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&capa.AWSMachineTemplate{}).
+		Watches(&expcapi.MachinePool{}, enqueueControlPlaneTemplatesForKarpenterMachinePool(mgr.GetClient())).
+		WatchesRawSource(r.ConfigMaps.Source(func() client.ObjectList { return &capa.AWSMachineTemplateList{} })).
 		Complete(r)
 }
@@ -167,7 +167,8 @@ var _ = Describe("AWSMachineTemplateReconciler", func() {
 	expectedRoleStatusesOnSuccess := []RoleInfo{
 		// Control plane node
 		{
-			ExpectedName: "the-profile",
+			ExpectedName:        "the-profile",
+			ExpectedDescription: "Control plane nodes of cluster test-cluster, managed by capa-iam-operator",
 
 			ExpectedAssumeRolePolicyDocument: `{
   "Version": "2012-10-17",
@@ -318,7 +319,7 @@ var _ = Describe("AWSMachineTemplateReconciler", func() {
         "secretsmanager:GetSecretValue",
         "secretsmanager:DeleteSecret"
       ],
-      "Resource": "arn:*:secretsmanager:*:*:secret:aws.cluster.x-k8s.io/*",
+      "Resource": "arn:aws:secretsmanager:*:*:secret:aws.cluster.x-k8s.io/*",
       "Effect": "Allow"
     }
   ]
@@ -348,7 +349,7 @@ var _ = Describe("AWSMachineTemplateReconciler", func() {
 
 	When("a role does not exist", func() {
 		BeforeEach(func() {
-			mockAwsClient.EXPECT().GetAWSClientConfig("arn:aws:iam::012345678901:role/giantswarm-test-capa-controller", "eu-west-1").Return(*cfg, nil)
+			mockAwsClient.EXPECT().GetAWSClientConfig(gomock.Any(), &capa.AWSIdentityReference{Name: "test-1", Kind: "AWSClusterRoleIdentity"}, namespace, "eu-west-1").Return(*cfg, nil)
 			for _, info := range expectedRoleStatusesOnSuccess {
 				mockIAMClient.EXPECT().GetRole(context.TODO(), &awsiam.GetRoleInput{
 					RoleName: aws.String(info.ExpectedName),
@@ -362,10 +363,18 @@ var _ = Describe("AWSMachineTemplateReconciler", func() {
 					AssumeRolePolicyDocument: aws.String(info.ExpectedAssumeRolePolicyDocument),
 					RoleName:                 aws.String(info.ExpectedName),
 					Tags:                     expectedIAMTags,
+					Path:                     aws.String("/"),
+					Description:              aws.String(info.ExpectedDescription),
+					MaxSessionDuration:       aws.Int32(3600),
 				}).Return(&awsiam.CreateRoleOutput{}, nil)
 
+				mockIAMClient.EXPECT().GetInstanceProfile(context.TODO(), &awsiam.GetInstanceProfileInput{
+					InstanceProfileName: aws.String(info.ExpectedName),
+				}).Return(nil, &awsiamtypes.NoSuchEntityException{})
+
 				mockIAMClient.EXPECT().CreateInstanceProfile(context.TODO(), &awsiam.CreateInstanceProfileInput{
 					InstanceProfileName: aws.String(info.ExpectedName),
+					Path:                aws.String("/"),
 					Tags:                expectedIAMTags,
 				}).Return(&awsiam.CreateInstanceProfileOutput{}, nil)
 
@@ -385,12 +394,6 @@ var _ = Describe("AWSMachineTemplateReconciler", func() {
 						Tags: expectedIAMTags,
 					},
 				}, nil)
-				if info.ExpectedName == externalDnsRoleInfo.ExpectedName || info.ExpectedName == certManagerRoleInfo.ExpectedName || info.ExpectedName == ALBControllerRoleInfo.ExpectedName || info.ExpectedName == ebsCsiDriverRoleInfo.ExpectedName || info.ExpectedName == efsCsiDriverRoleInfo.ExpectedName || info.ExpectedName == clusterAutoscalerRoleInfo.ExpectedName {
-					mockIAMClient.EXPECT().UpdateAssumeRolePolicy(context.TODO(), &awsiam.UpdateAssumeRolePolicyInput{
-						PolicyDocument: aws.String(info.ExpectedAssumeRolePolicyDocument),
-						RoleName:       aws.String(info.ExpectedName),
-					}).Return(&awsiam.UpdateAssumeRolePolicyOutput{}, nil)
-				}
 				mockIAMClient.EXPECT().GetRolePolicy(context.TODO(), &awsiam.GetRolePolicyInput{
 					PolicyName: aws.String(info.ExpectedPolicyName),
 					RoleName:   aws.String(info.ExpectedName),
@@ -401,8 +404,31 @@ var _ = Describe("AWSMachineTemplateReconciler", func() {
 					PolicyDocument: aws.String(info.ExpectedPolicyDocument),
 					RoleName:       aws.String(info.ExpectedName),
 				}).Return(&awsiam.PutRolePolicyOutput{}, nil)
+
+				mockIAMClient.EXPECT().ListAttachedRolePolicies(context.TODO(), &awsiam.ListAttachedRolePoliciesInput{
+					RoleName: aws.String(info.ExpectedName),
+				}).Return(&awsiam.ListAttachedRolePoliciesOutput{}, nil)
+
+				// IRSA roles used to share the inline policy name of the control plane role
+				if info.ExpectedPolicyName != "control-plane-test-cluster-policy" {
+					mockIAMClient.EXPECT().ListRolePolicies(context.TODO(), &awsiam.ListRolePoliciesInput{
+						RoleName: aws.String(info.ExpectedName),
+					}).Return(&awsiam.ListRolePoliciesOutput{}, nil)
+				}
 			}
 
+			mockAwsClient.EXPECT().GetAWSAccountID(gomock.Any(), &capa.AWSIdentityReference{Name: "test-1", Kind: "AWSClusterRoleIdentity"}, gomock.Any()).Return("012345678901", nil)
+
+			// OIDC providers are not managed by default, only checked
+			mockIAMClient.EXPECT().GetOpenIDConnectProvider(context.TODO(), &awsiam.GetOpenIDConnectProviderInput{
+				OpenIDConnectProviderArn: aws.String("arn:aws:iam::012345678901:oidc-provider/irsa.test.gaws.gigantic.io"),
+			}).Return(&awsiam.GetOpenIDConnectProviderOutput{}, nil)
+
+			// the cluster has no KarpenterMachinePools, so the Karpenter controller role is only looked up to delete it
+			mockIAMClient.EXPECT().GetRole(context.TODO(), &awsiam.GetRoleInput{
+				RoleName: aws.String("test-cluster-karpenter-controller-role"),
+			}).Return(nil, &awsiamtypes.NoSuchEntityException{})
+
 			_, reconcileErr = reconciler.Reconcile(ctx, req)
 			Expect(reconcileErr).To(BeNil())
 		})
@@ -428,6 +454,7 @@ var _ = Describe("AWSMachineTemplateReconciler", func() {
 			for _, info := range expectedRoleStatusesOnSuccess {
 				mockIAMClient.EXPECT().CreateInstanceProfile(context.TODO(), &awsiam.CreateInstanceProfileInput{
 					InstanceProfileName: aws.String(info.ExpectedName),
+					Path:                aws.String("/"),
 					Tags:                expectedIAMTags,
 				}).Return(&awsiam.CreateInstanceProfileOutput{}, nil)
 
@@ -18,11 +18,13 @@ package controllers
 
 import (
 	"context"
+	"slices"
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/giantswarm/microerror"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/client-go/tools/record"
 	eks "sigs.k8s.io/cluster-api-provider-aws/v2/controlplane/eks/api/v1beta2"
 	"sigs.k8s.io/cluster-api/util"
 	"sigs.k8s.io/cluster-api/util/patch"
@@ -41,9 +43,23 @@ type AWSManagedControlPlaneReconciler struct {
 	client.Client
 	AWSClient        awsclient.AwsClientInterface
 	IAMClientFactory func(aws.Config, string) iam.IAMClient
+	// EKSClientFactory creates the EKS clients. Nil means the client of the AWS SDK.
+	EKSClientFactory func(aws.Config, string) iam.EKSClient
+	// Recorder records events on the objects referencing AWS identities.
+	Recorder record.EventRecorder
+
+	ManagedPolicyRoleTypes []string
+	PermissionsBoundary    string
+	RoleSettings           iam.RoleSettings
+	PolicyTemplates        PolicyTemplatesConfig
+	ConfigMaps             *ConfigMapWatch
+	// IRSARoleTypes lists the IRSA roles created for clusters unless overridden per cluster. Nil means all.
+	IRSARoleTypes []string
+	// ManageOIDCProviders enables creating and deleting the IAM OIDC providers of the IRSA trust domains of clusters.
+	ManageOIDCProviders bool
 }
 
-func (r *AWSManagedControlPlaneReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+func (r *AWSManagedControlPlaneReconciler) Reconcile(ctx context.Context, req ctrl.Request) (result ctrl.Result, reterr error) {
 	logger := log.FromContext(ctx)
 
 	eksCluster := &eks.AWSManagedControlPlane{}
@@ -72,31 +88,89 @@ func (r *AWSManagedControlPlaneReconciler) Reconcile(ctx context.Context, req ct
 		}, nil
 	}
 
-	awsClusterRoleIdentity, err := key.GetAWSClusterRoleIdentity(ctx, r.Client, eksCluster.Spec.IdentityRef.Name)
+	// errors of AWS calls decide how the reconciliation is retried from here on
+	defer func() {
+		result, reterr = handleReconcileResult(ctx, r.Client, r.Recorder, eksCluster, "AWSManagedControlPlane "+eksCluster.Name, false, result, reterr)
+	}()
+
+	awsClientConfig, err := getAWSClientConfig(ctx, r.Client, r.AWSClient, r.Recorder, eksCluster, eksCluster.Spec.IdentityRef, eksCluster.Spec.Region)
 	if err != nil {
-		logger.Error(err, "could not get AWSClusterRoleIdentity")
+		logger.Error(err, "Failed to get aws client session")
 		return ctrl.Result{}, microerror.Mask(err)
 	}
 
-	awsClientConfig, err := r.AWSClient.GetAWSClientConfig(awsClusterRoleIdentity.Spec.RoleArn, eksCluster.Spec.Region)
+	roleSettings, err := key.GetRoleSettings(eksCluster, r.RoleSettings)
 	if err != nil {
-		logger.Error(err, "Failed to get aws client session")
+		logger.Error(err, "invalid role settings")
+		return ctrl.Result{}, microerror.Mask(err)
+	}
+
+	irsaRoleTypes, err := key.GetIRSARoleTypes(eksCluster, r.IRSARoleTypes)
+	if err != nil {
+		logger.Error(err, "invalid IRSA role types")
+		return ctrl.Result{}, microerror.Mask(err)
+	}
+
+	serviceAccounts, err := key.GetServiceAccounts(eksCluster)
+	if err != nil {
+		logger.Error(err, "invalid service accounts")
+		return ctrl.Result{}, microerror.Mask(err)
+	}
+
+	serviceAccountTrust, err := key.GetServiceAccountTrust(eksCluster)
+	if err != nil {
+		logger.Error(err, "invalid service account trust")
+		return ctrl.Result{}, microerror.Mask(err)
+	}
+
+	oidcProviderThumbprints, err := key.GetOIDCProviderThumbprints(eksCluster)
+	if err != nil {
+		logger.Error(err, "invalid OIDC provider thumbprints")
+		return ctrl.Result{}, microerror.Mask(err)
+	}
+
+	eksClusterName := eksCluster.Spec.EKSClusterName
+	if eksClusterName == "" {
+		eksClusterName = eksCluster.Name
+	}
+
+	policyTemplates, err := r.PolicyTemplates.load(ctx, r.Client, eksCluster.Namespace)
+	if err != nil {
+		logger.Error(err, "failed to load policy templates")
+		return ctrl.Result{}, microerror.Mask(err)
+	}
+
+	additionalStatements, err := loadAdditionalPolicyStatements(ctx, r.Client, eksCluster)
+	if err != nil {
+		logger.Error(err, "failed to load additional policy statements")
 		return ctrl.Result{}, microerror.Mask(err)
 	}
 
 	var iamService *iam.IAMService
 	{
 		c := iam.IAMServiceConfig{
-			AWSConfig:             &awsClientConfig,
-			ClusterIsBeingDeleted: cluster.DeletionTimestamp != nil,
-			ClusterName:           clusterName,
-			ClusterRelease:        cluster.Labels[GiantSwarmReleaseLabel],
-			MainRoleName:          *eksCluster.Spec.RoleName,
-			Log:                   logger,
-			RoleType:              iam.IRSARole,
-			Region:                eksCluster.Spec.Region,
-			IAMClientFactory:      r.IAMClientFactory,
-			CustomTags:            eksCluster.Spec.AdditionalTags,
+			AWSConfig:               &awsClientConfig,
+			ClusterIsBeingDeleted:   cluster.DeletionTimestamp != nil,
+			ClusterName:             clusterName,
+			ClusterRelease:          cluster.Labels[GiantSwarmReleaseLabel],
+			MainRoleName:            *eksCluster.Spec.RoleName,
+			Log:                     logger,
+			RoleType:                iam.IRSARole,
+			Region:                  eksCluster.Spec.Region,
+			IAMClientFactory:        r.IAMClientFactory,
+			CustomTags:              eksCluster.Spec.AdditionalTags,
+			ManagedPolicyRoleTypes:  r.ManagedPolicyRoleTypes,
+			PermissionsBoundary:     key.GetPermissionsBoundary(eksCluster, r.PermissionsBoundary),
+			RoleSettings:            roleSettings,
+			PolicyTemplates:         policyTemplates,
+			AdditionalStatements:    additionalStatements,
+			IRSARoleTypes:           irsaRoleTypes,
+			ServiceAccounts:         serviceAccounts,
+			ServiceAccountTrust:     serviceAccountTrust,
+			EKSClusterName:          eksClusterName,
+			EKSClientFactory:        r.EKSClientFactory,
+			ManageOIDCProviders:     r.ManageOIDCProviders,
+			OIDCProviderThumbprints: oidcProviderThumbprints,
 		}
 		iamService, err = iam.New(c)
 		if err != nil {
@@ -111,6 +185,11 @@ func (r *AWSManagedControlPlaneReconciler) Reconcile(ctx context.Context, req ct
 			return ctrl.Result{}, microerror.Mask(err)
 		}
 
+		err = iamService.DeleteOIDCProviders()
+		if err != nil {
+			return ctrl.Result{}, microerror.Mask(err)
+		}
+
 		err = removeFinalizer(ctx, r.Client, eksCluster, iam.IRSARole)
 		if err != nil {
 			logger.Error(err, "failed to remove finalizer on AWSManagedControlPlane")
@@ -133,16 +212,30 @@ func (r *AWSManagedControlPlaneReconciler) Reconcile(ctx context.Context, req ct
 			logger.Info("successfully added finalizer to AWSManagedControlPlane", "finalizer_name", key.FinalizerName(iam.IRSARole))
 		}
 
-		accountID, err := key.GetAWSAccountID(awsClusterRoleIdentity)
+		accountID, err := r.AWSClient.GetAWSAccountID(ctx, eksCluster.Spec.IdentityRef, awsClientConfig)
 		if err != nil {
 			logger.Error(err, "Could not get account ID")
 			return ctrl.Result{}, microerror.Mask(err)
 		}
 
-		eksOpenIdDomain, err := iamService.GetIRSAOpenIDForEKS(eksCluster.Name)
-		if err != nil {
-			logger.Error(err, "failed to fetch EKS OpenConnectID URL")
-			return ctrl.Result{}, microerror.Mask(err)
+		// roles trusted through Pod Identity only do not need the OIDC provider of the cluster
+		var irsaTrustDomains []string
+		if serviceAccountTrust == nil || slices.Contains(serviceAccountTrust, iam.TrustKindIRSA) {
+			eksOpenIdDomain, err := iamService.GetIRSAOpenIDForEKS(eksCluster.Name)
+			if err != nil {
+				logger.Error(err, "failed to fetch EKS OpenConnectID URL")
+				return ctrl.Result{}, microerror.Mask(err)
+			}
+			irsaTrustDomains = []string{eksOpenIdDomain}
+
+			missingTrustDomains, err := iamService.ReconcileOIDCProviders(accountID, irsaTrustDomains)
+			if err != nil {
+				return ctrl.Result{}, microerror.Mask(err)
+			}
+			err = reportOIDCProviders(ctx, r.Client, r.Recorder, eksCluster, missingTrustDomains)
+			if err != nil {
+				return ctrl.Result{}, microerror.Mask(err)
+			}
 		}
 
 		eksRoleARN, err := iamService.GetRoleARN(*eksCluster.Spec.RoleName)
@@ -153,7 +246,7 @@ func (r *AWSManagedControlPlaneReconciler) Reconcile(ctx context.Context, req ct
 		}
 
 		iamService.SetPrincipalRoleARN(eksRoleARN)
-		err = iamService.ReconcileRolesForIRSA(accountID, []string{eksOpenIdDomain})
+		err = iamService.ReconcileRolesForIRSA(accountID, irsaTrustDomains)
 		if err != nil {
 			return ctrl.Result{}, microerror.Mask(err)
 		}
@@ -169,5 +262,6 @@ func (r *AWSManagedControlPlaneReconciler) Reconcile(ctx context.Context, req ct
 func (r *AWSManagedControlPlaneReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&eks.AWSManagedControlPlane{}).
+		WatchesRawSource(r.ConfigMaps.Source(func() client.ObjectList { return &eks.AWSManagedControlPlaneList{} })).
 		Complete(r)
 }
@@ -2,6 +2,7 @@ package controllers_test
 
 type RoleInfo struct {
 	ExpectedName                     string
+	ExpectedDescription              string
 	ExpectedAssumeRolePolicyDocument string
 	ExpectedPolicyName               string
 	ExpectedPolicyDocument           string
@@ -9,7 +10,8 @@ type RoleInfo struct {
 }
 
 var certManagerRoleInfo = RoleInfo{
-	ExpectedName: "test-cluster-CertManager-Role",
+	ExpectedName:        "test-cluster-CertManager-Role",
+	ExpectedDescription: "cert-manager of cluster test-cluster, managed by capa-iam-operator",
 
 	ExpectedAssumeRolePolicyDocument: `{
   "Version": "2012-10-17",
@@ -22,7 +24,9 @@ var certManagerRoleInfo = RoleInfo{
       "Action": "sts:AssumeRoleWithWebIdentity",
       "Condition": {
         "StringEquals": {
-          "irsa.test.gaws.gigantic.io:sub": "system:serviceaccount:kube-system:cert-manager-app"
+          "irsa.test.gaws.gigantic.io:sub": [
+            "system:serviceaccount:kube-system:cert-manager-app"
+          ]
         }
       }
     }
@@ -30,14 +34,14 @@ var certManagerRoleInfo = RoleInfo{
 }
 `,
 
-	ExpectedPolicyName: "control-plane-test-cluster-policy",
+	ExpectedPolicyName: "cert-manager-role-test-cluster-policy",
 	ExpectedPolicyDocument: `{
   "Version": "2012-10-17",
   "Statement": [
     {
       "Effect": "Allow",
       "Action": "route53:GetChange",
-      "Resource": "arn:*:route53:::change/*"
+      "Resource": "arn:aws:route53:::change/*"
     },
     {
       "Effect": "Allow",
@@ -45,7 +49,7 @@ var certManagerRoleInfo = RoleInfo{
         "route53:ChangeResourceRecordSets",
         "route53:ListResourceRecordSets"
       ],
-      "Resource": "arn:*:route53:::hostedzone/*"
+      "Resource": "arn:aws:route53:::hostedzone/*"
     },
     {
       "Effect": "Allow",
@@ -60,7 +64,8 @@ var certManagerRoleInfo = RoleInfo{
 }
 
 var externalDnsRoleInfo = RoleInfo{
-	ExpectedName: "test-cluster-Route53Manager-Role",
+	ExpectedName:        "test-cluster-Route53Manager-Role",
+	ExpectedDescription: "external-dns of cluster test-cluster, managed by capa-iam-operator",
 
 	ExpectedAssumeRolePolicyDocument: `{
   "Version": "2012-10-17",
@@ -73,7 +78,9 @@ var externalDnsRoleInfo = RoleInfo{
       "Action": "sts:AssumeRoleWithWebIdentity",
       "Condition": {
         "StringLike": {
-          "irsa.test.gaws.gigantic.io:sub": "system:serviceaccount:*:*external-dns*"
+          "irsa.test.gaws.gigantic.io:sub": [
+            "system:serviceaccount:*:*external-dns*"
+          ]
         }
       }
     }
@@ -81,14 +88,14 @@ var externalDnsRoleInfo = RoleInfo{
 }
 `,
 
-	ExpectedPolicyName: "control-plane-test-cluster-policy",
+	ExpectedPolicyName: "route53-role-test-cluster-policy",
 	ExpectedPolicyDocument: `{
   "Version": "2012-10-17",
   "Statement": [
     {
       "Action": "route53:ChangeResourceRecordSets",
       "Resource": [
-        "arn:*:route53:::hostedzone/*"
+        "arn:aws:route53:::hostedzone/*"
       ],
       "Effect": "Allow"
     },
@@ -108,7 +115,8 @@ var externalDnsRoleInfo = RoleInfo{
 }
 
 var ALBControllerRoleInfo = RoleInfo{
-	ExpectedName: "test-cluster-ALBController-Role",
+	ExpectedName:        "test-cluster-ALBController-Role",
+	ExpectedDescription: "AWS Load Balancer Controller of cluster test-cluster, managed by capa-iam-operator",
 
 	ExpectedAssumeRolePolicyDocument: `{
   "Version": "2012-10-17",
@@ -121,7 +129,9 @@ var ALBControllerRoleInfo = RoleInfo{
       "Action": "sts:AssumeRoleWithWebIdentity",
       "Condition": {
         "StringLike": {
-          "irsa.test.gaws.gigantic.io:sub": "system:serviceaccount:*:aws-load-balancer-controller"
+          "irsa.test.gaws.gigantic.io:sub": [
+            "system:serviceaccount:*:aws-load-balancer-controller"
+          ]
         }
       }
     }
@@ -129,7 +139,7 @@ var ALBControllerRoleInfo = RoleInfo{
 }
 `,
 
-	ExpectedPolicyName: "control-plane-test-cluster-policy",
+	ExpectedPolicyName: "ALBController-Role-test-cluster-policy",
 	ExpectedPolicyDocument: `{
     "Version": "2012-10-17",
     "Statement": [
@@ -217,7 +227,7 @@ var ALBControllerRoleInfo = RoleInfo{
             "Action": [
                 "ec2:CreateTags"
             ],
-            "Resource": "arn:*:ec2:*:*:security-group/*",
+            "Resource": "arn:aws:ec2:*:*:security-group/*",
             "Condition": {
                 "StringEquals": {
                     "ec2:CreateAction": "CreateSecurityGroup"
@@ -233,7 +243,7 @@ var ALBControllerRoleInfo = RoleInfo{
                 "ec2:CreateTags",
                 "ec2:DeleteTags"
             ],
-            "Resource": "arn:*:ec2:*:*:security-group/*",
+            "Resource": "arn:aws:ec2:*:*:security-group/*",
             "Condition": {
                 "Null": {
                     "aws:RequestTag/elbv2.k8s.aws/cluster": "true",
@@ -285,9 +295,9 @@ var ALBControllerRoleInfo = RoleInfo{
                 "elasticloadbalancing:RemoveTags"
             ],
             "Resource": [
-                "arn:*:elasticloadbalancing:*:*:targetgroup/*/*",
-                "arn:*:elasticloadbalancing:*:*:loadbalancer/net/*/*",
-                "arn:*:elasticloadbalancing:*:*:loadbalancer/app/*/*"
+                "arn:aws:elasticloadbalancing:*:*:targetgroup/*/*",
+                "arn:aws:elasticloadbalancing:*:*:loadbalancer/net/*/*",
+                "arn:aws:elasticloadbalancing:*:*:loadbalancer/app/*/*"
             ],
             "Condition": {
                 "Null": {
@@ -303,10 +313,10 @@ var ALBControllerRoleInfo = RoleInfo{
                 "elasticloadbalancing:RemoveTags"
             ],
             "Resource": [
-                "arn:*:elasticloadbalancing:*:*:listener/net/*/*/*",
-                "arn:*:elasticloadbalancing:*:*:listener/app/*/*/*",
-                "arn:*:elasticloadbalancing:*:*:listener-rule/net/*/*/*",
-                "arn:*:elasticloadbalancing:*:*:listener-rule/app/*/*/*"
+                "arn:aws:elasticloadbalancing:*:*:listener/net/*/*/*",
+                "arn:aws:elasticloadbalancing:*:*:listener/app/*/*/*",
+                "arn:aws:elasticloadbalancing:*:*:listener-rule/net/*/*/*",
+                "arn:aws:elasticloadbalancing:*:*:listener-rule/app/*/*/*"
             ]
         },
         {
@@ -334,9 +344,9 @@ var ALBControllerRoleInfo = RoleInfo{
                 "elasticloadbalancing:AddTags"
             ],
             "Resource": [
-                "arn:*:elasticloadbalancing:*:*:targetgroup/*/*",
-                "arn:*:elasticloadbalancing:*:*:loadbalancer/net/*/*",
-                "arn:*:elasticloadbalancing:*:*:loadbalancer/app/*/*"
+                "arn:aws:elasticloadbalancing:*:*:targetgroup/*/*",
+                "arn:aws:elasticloadbalancing:*:*:loadbalancer/net/*/*",
+                "arn:aws:elasticloadbalancing:*:*:loadbalancer/app/*/*"
             ],
             "Condition": {
                 "StringEquals": {
@@ -356,7 +366,7 @@ var ALBControllerRoleInfo = RoleInfo{
                 "elasticloadbalancing:RegisterTargets",
                 "elasticloadbalancing:DeregisterTargets"
             ],
-            "Resource": "arn:*:elasticloadbalancing:*:*:targetgroup/*/*"
+            "Resource": "arn:aws:elasticloadbalancing:*:*:targetgroup/*/*"
         },
         {
             "Effect": "Allow",
@@ -376,7 +386,8 @@ var ALBControllerRoleInfo = RoleInfo{
 }
 
 var ebsCsiDriverRoleInfo = RoleInfo{
-	ExpectedName: "test-cluster-ebs-csi-driver-role",
+	ExpectedName:        "test-cluster-ebs-csi-driver-role",
+	ExpectedDescription: "EBS CSI driver of cluster test-cluster, managed by capa-iam-operator",
 
 	ExpectedAssumeRolePolicyDocument: `{
   "Version": "2012-10-17",
@@ -389,7 +400,9 @@ var ebsCsiDriverRoleInfo = RoleInfo{
       "Action": "sts:AssumeRoleWithWebIdentity",
       "Condition": {
         "StringEquals": {
-          "irsa.test.gaws.gigantic.io:sub": "system:serviceaccount:kube-system:ebs-csi-controller-sa"
+          "irsa.test.gaws.gigantic.io:sub": [
+            "system:serviceaccount:kube-system:ebs-csi-controller-sa"
+          ]
         }
       }
     }
@@ -397,7 +410,7 @@ var ebsCsiDriverRoleInfo = RoleInfo{
 }
 `,
 
-	ExpectedPolicyName: "control-plane-test-cluster-policy",
+	ExpectedPolicyName: "ebs-csi-driver-role-test-cluster-policy",
 	ExpectedPolicyDocument: `{
   "Version": "2012-10-17",
   "Statement": [
@@ -583,7 +596,8 @@ var ebsCsiDriverRoleInfo = RoleInfo{
 }
 
 var efsCsiDriverRoleInfo = RoleInfo{
-	ExpectedName: "test-cluster-efs-csi-driver-role",
+	ExpectedName:        "test-cluster-efs-csi-driver-role",
+	ExpectedDescription: "EFS CSI driver of cluster test-cluster, managed by capa-iam-operator",
 
 	ExpectedAssumeRolePolicyDocument: `{
   "Version": "2012-10-17",
@@ -596,7 +610,9 @@ var efsCsiDriverRoleInfo = RoleInfo{
       "Action": "sts:AssumeRoleWithWebIdentity",
       "Condition": {
         "StringEquals": {
-          "irsa.test.gaws.gigantic.io:sub": "system:serviceaccount:kube-system:efs-csi-sa"
+          "irsa.test.gaws.gigantic.io:sub": [
+            "system:serviceaccount:kube-system:efs-csi-sa"
+          ]
         }
       }
     }
@@ -604,7 +620,7 @@ var efsCsiDriverRoleInfo = RoleInfo{
 }
 `,
 
-	ExpectedPolicyName: "control-plane-test-cluster-policy",
+	ExpectedPolicyName: "efs-csi-driver-role-test-cluster-policy",
 	ExpectedPolicyDocument: `{
   "Version": "2012-10-17",
   "Statement": [
@@ -659,7 +675,8 @@ var efsCsiDriverRoleInfo = RoleInfo{
 }
 
 var clusterAutoscalerRoleInfo = RoleInfo{
-	ExpectedName: "test-cluster-cluster-autoscaler-role",
+	ExpectedName:        "test-cluster-cluster-autoscaler-role",
+	ExpectedDescription: "Cluster Autoscaler of cluster test-cluster, managed by capa-iam-operator",
 
 	ExpectedAssumeRolePolicyDocument: `{
   "Version": "2012-10-17",
@@ -672,7 +689,9 @@ var clusterAutoscalerRoleInfo = RoleInfo{
       "Action": "sts:AssumeRoleWithWebIdentity",
       "Condition": {
         "StringEquals": {
-          "irsa.test.gaws.gigantic.io:sub": "system:serviceaccount:kube-system:cluster-autoscaler"
+          "irsa.test.gaws.gigantic.io:sub": [
+            "system:serviceaccount:kube-system:cluster-autoscaler"
+          ]
         }
       }
     }
@@ -680,7 +699,7 @@ var clusterAutoscalerRoleInfo = RoleInfo{
 }
 `,
 
-	ExpectedPolicyName: "control-plane-test-cluster-policy",
+	ExpectedPolicyName: "cluster-autoscaler-role-test-cluster-policy",
 	ExpectedPolicyDocument: `{
   "Version": "2012-10-17",
   "Statement": [
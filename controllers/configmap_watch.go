@@ -0,0 +1,150 @@
+package controllers
+
+import (
+	"context"
+	"sync"
+
+	"github.com/giantswarm/microerror"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	toolscache "k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/workqueue"
+	capa "sigs.k8s.io/cluster-api-provider-aws/v2/api/v1beta2"
+	eks "sigs.k8s.io/cluster-api-provider-aws/v2/controlplane/eks/api/v1beta2"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	"github.com/giantswarm/capa-iam-operator/v3/pkg/key"
+)
+
+// ConfigMapWatch watches the ConfigMaps read by the controllers: the policy templates ConfigMaps and the ConfigMaps
+// referenced by the key.AdditionalStatementsAnnotation annotation of a cluster. ConfigMaps are not cached by the
+// manager, so a single metadata-only informer is shared by all controllers, and changes to other ConfigMaps are
+// dropped.
+type ConfigMapWatch struct {
+	Client          client.Client
+	PolicyTemplates PolicyTemplatesConfig
+
+	ctx         context.Context
+	mu          sync.Mutex
+	subscribers []configMapSubscriber
+}
+
+type configMapSubscriber struct {
+	newList func() client.ObjectList
+	queue   workqueue.TypedRateLimitingInterface[reconcile.Request]
+}
+
+// SetupWithManager registers the ConfigMap informer and the index of the clusters by the ConfigMap of their additional
+// policy statements. It must be called once before the manager is started.
+func (w *ConfigMapWatch) SetupWithManager(ctx context.Context, mgr ctrl.Manager) error {
+	w.ctx = ctx
+
+	for _, o := range []client.Object{&capa.AWSCluster{}, &eks.AWSManagedControlPlane{}} {
+		err := mgr.GetFieldIndexer().IndexField(ctx, o, additionalStatementsIndex, indexAdditionalStatements)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+	}
+
+	informer, err := mgr.GetCache().GetInformer(ctx, configMapMetadata())
+	if err != nil {
+		return microerror.Mask(err)
+	}
+	_, err = informer.AddEventHandler(toolscache.ResourceEventHandlerFuncs{
+		AddFunc: w.notify,
+		UpdateFunc: func(_, newObj interface{}) {
+			w.notify(newObj)
+		},
+		DeleteFunc: w.notify,
+	})
+	return microerror.Mask(err)
+}
+
+// Source returns a source for a controller that enqueues the objects of the given list type affected by a change to a
+// watched ConfigMap.
+func (w *ConfigMapWatch) Source(newList func() client.ObjectList) source.Source {
+	return source.Func(func(_ context.Context, queue workqueue.TypedRateLimitingInterface[reconcile.Request]) error {
+		w.mu.Lock()
+		defer w.mu.Unlock()
+		w.subscribers = append(w.subscribers, configMapSubscriber{newList: newList, queue: queue})
+		return nil
+	})
+}
+
+// affectedBy returns the list options selecting the objects affected by a change to a ConfigMap, one per set of
+// objects. It returns none if the ConfigMap is not read by the controllers. The policy templates are matched by name
+// and the additional statements through the cache index, so changes to unrelated ConfigMaps do not list the clusters.
+func (w *ConfigMapWatch) affectedBy(o client.Object) ([][]client.ListOption, error) {
+	var selectors [][]client.ListOption
+	if opts, ok := w.PolicyTemplates.affectedBy(o); ok {
+		selectors = append(selectors, opts)
+	}
+
+	clusterNames, err := referencingClusters(w.ctx, w.Client, o)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+	for _, clusterName := range clusterNames {
+		selectors = append(selectors, []client.ListOption{
+			client.InNamespace(o.GetNamespace()),
+			client.MatchingLabels{key.ClusterNameLabel: clusterName},
+		})
+	}
+
+	return selectors, nil
+}
+
+func (w *ConfigMapWatch) notify(obj interface{}) {
+	if tombstone, ok := obj.(toolscache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+	o, ok := obj.(client.Object)
+	if !ok {
+		return
+	}
+
+	logger := log.FromContext(w.ctx).WithValues("configmap", client.ObjectKeyFromObject(o))
+
+	selectors, err := w.affectedBy(o)
+	if err != nil {
+		logger.Error(err, "failed to find objects affected by ConfigMap")
+		return
+	}
+	if len(selectors) == 0 {
+		return
+	}
+
+	w.mu.Lock()
+	subscribers := append([]configMapSubscriber(nil), w.subscribers...)
+	w.mu.Unlock()
+
+	for _, s := range subscribers {
+		for _, opts := range selectors {
+			list := s.newList()
+			err := w.Client.List(w.ctx, list, opts...)
+			if err != nil {
+				logger.Error(err, "failed to list objects affected by ConfigMap")
+				continue
+			}
+			err = meta.EachListItem(list, func(item runtime.Object) error {
+				s.queue.Add(reconcile.Request{NamespacedName: client.ObjectKeyFromObject(item.(client.Object))})
+				return nil
+			})
+			if err != nil {
+				logger.Error(err, "failed to enqueue objects affected by ConfigMap")
+			}
+		}
+	}
+}
+
+func configMapMetadata() *metav1.PartialObjectMetadata {
+	cm := &metav1.PartialObjectMetadata{}
+	cm.SetGroupVersionKind(corev1.SchemeGroupVersion.WithKind("ConfigMap"))
+	return cm
+}
@@ -0,0 +1,75 @@
+package controllers
+
+import (
+	"context"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/giantswarm/microerror"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/tools/record"
+	capa "sigs.k8s.io/cluster-api-provider-aws/v2/api/v1beta2"
+	capi "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/util/conditions"
+	"sigs.k8s.io/cluster-api/util/patch"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/giantswarm/capa-iam-operator/v3/pkg/awsclient"
+)
+
+const (
+	// IdentityUsageAllowedCondition reports whether the namespace of a cluster is allowed to use the AWS identity the
+	// cluster references for managing its IAM roles.
+	IdentityUsageAllowedCondition capi.ConditionType = "IAMIdentityUsageAllowed"
+	// IdentityUsageNotAllowedReason is the reason of events and conditions about identities the namespace of a
+	// cluster is not allowed to use.
+	IdentityUsageNotAllowedReason = "IdentityUsageNotAllowed"
+
+	// AWSAccountAvailableCondition reports whether the operator calls the AWS account of a cluster, or pauses the
+	// calls after repeated throttling errors of the account or AccessDenied errors of the cluster's identity.
+	AWSAccountAvailableCondition capi.ConditionType = "AWSAccountAvailable"
+	// AWSAccountCircuitOpenReason is the reason of conditions about AWS accounts whose calls are paused.
+	AWSAccountCircuitOpenReason = "CircuitBreakerOpen"
+)
+
+// identityReferrer is an object referencing an AWS identity, e.g. an AWSCluster.
+type identityReferrer interface {
+	client.Object
+	conditions.Setter
+}
+
+// getAWSClientConfig returns the configuration of AWS clients acting as the identity referenced by obj. Whether the
+// namespace of obj is allowed to use the identity is recorded in the IdentityUsageAllowedCondition of obj, and a
+// denied identity is also reported in an event. Whether calls to the account of the identity are paused is recorded
+// in the AWSAccountAvailableCondition; callers requeue with CircuitRetryAfter for errors asserted by
+// awsclient.IsCircuitOpen.
+func getAWSClientConfig(ctx context.Context, ctrlClient client.Client, awsClient awsclient.AwsClientInterface, recorder record.EventRecorder, obj identityReferrer, identityRef *capa.AWSIdentityReference, region string) (aws.Config, error) {
+	patchHelper, err := patch.NewHelper(obj, ctrlClient)
+	if err != nil {
+		return aws.Config{}, microerror.Mask(err)
+	}
+
+	cfg, err := awsClient.GetAWSClientConfig(ctx, identityRef, obj.GetNamespace(), region)
+	if awsclient.IsIdentityNotAllowed(err) {
+		recorder.Event(obj, corev1.EventTypeWarning, IdentityUsageNotAllowedReason, err.Error())
+		conditions.MarkFalse(obj, IdentityUsageAllowedCondition, IdentityUsageNotAllowedReason, capi.ConditionSeverityError, "%s", err.Error())
+	} else if awsclient.IsCircuitOpen(err) {
+		conditions.MarkTrue(obj, IdentityUsageAllowedCondition)
+		conditions.MarkFalse(obj, AWSAccountAvailableCondition, AWSAccountCircuitOpenReason, capi.ConditionSeverityWarning, "%s", err.Error())
+	} else if err == nil {
+		conditions.MarkTrue(obj, IdentityUsageAllowedCondition)
+		conditions.MarkTrue(obj, AWSAccountAvailableCondition)
+	} else {
+		// whether the identity may be used is unknown, so the condition is left as it is
+		return aws.Config{}, microerror.Mask(err)
+	}
+
+	patchErr := patchHelper.Patch(ctx, obj, patch.WithOwnedConditions{Conditions: []capi.ConditionType{IdentityUsageAllowedCondition, AWSAccountAvailableCondition}})
+	if patchErr != nil {
+		return aws.Config{}, microerror.Mask(patchErr)
+	}
+	if err != nil {
+		return aws.Config{}, microerror.Mask(err)
+	}
+
+	return cfg, nil
+}
@@ -0,0 +1,84 @@
+package controllers
+
+import (
+	"context"
+	"slices"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	capa "sigs.k8s.io/cluster-api-provider-aws/v2/api/v1beta2"
+	"sigs.k8s.io/cluster-api/controllers/external"
+	expcapi "sigs.k8s.io/cluster-api/exp/api/v1beta1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"github.com/giantswarm/capa-iam-operator/v3/pkg/iam"
+	"github.com/giantswarm/capa-iam-operator/v3/pkg/key"
+)
+
+const karpenterMachinePoolKind = "KarpenterMachinePool"
+
+// getKarpenterConfig returns the configuration of the Karpenter controller role of a cluster. The node roles are those
+// of the cluster's KarpenterMachinePools; there are none if the cluster does not use Karpenter.
+func getKarpenterConfig(ctx context.Context, ctrlClient client.Client, awsCluster *capa.AWSCluster, clusterName string) (iam.KarpenterConfig, error) {
+	machinePools := &expcapi.MachinePoolList{}
+	err := ctrlClient.List(ctx, machinePools, client.InNamespace(awsCluster.Namespace), client.MatchingLabels{key.ClusterNameLabel: clusterName})
+	if err != nil {
+		return iam.KarpenterConfig{}, err
+	}
+
+	var nodeRoleNames []string
+	for i := range machinePools.Items {
+		ref := machinePools.Items[i].Spec.Template.Spec.InfrastructureRef
+		if ref.Kind != karpenterMachinePoolKind || machinePools.Items[i].DeletionTimestamp != nil {
+			continue
+		}
+
+		infraMachinePool, err := external.Get(ctx, ctrlClient, &ref)
+		if err != nil {
+			return iam.KarpenterConfig{}, err
+		}
+		// role and instance profile of nodes share their name
+		nodeRoleName, _, err := unstructured.NestedString(infraMachinePool.Object, "spec", "ec2NodeClass", "instanceProfile")
+		if err != nil {
+			return iam.KarpenterConfig{}, err
+		}
+		if nodeRoleName != "" && !slices.Contains(nodeRoleNames, nodeRoleName) {
+			nodeRoleNames = append(nodeRoleNames, nodeRoleName)
+		}
+	}
+	slices.Sort(nodeRoleNames)
+
+	return iam.KarpenterConfig{
+		NodeRoleNames:        nodeRoleNames,
+		InterruptionQueueARN: key.GetAnnotation(awsCluster, key.KarpenterInterruptionQueueARNAnnotation),
+	}, nil
+}
+
+// enqueueControlPlaneTemplatesForKarpenterMachinePool enqueues the control plane AWSMachineTemplates of the cluster of
+// a MachinePool backed by a KarpenterMachinePool, whose IRSA roles include the Karpenter controller role.
+func enqueueControlPlaneTemplatesForKarpenterMachinePool(ctrlClient client.Client) handler.EventHandler {
+	return handler.EnqueueRequestsFromMapFunc(func(ctx context.Context, o client.Object) []reconcile.Request {
+		machinePool, ok := o.(*expcapi.MachinePool)
+		if !ok || machinePool.Spec.Template.Spec.InfrastructureRef.Kind != karpenterMachinePoolKind {
+			return nil
+		}
+
+		templates := &capa.AWSMachineTemplateList{}
+		err := ctrlClient.List(ctx, templates, client.InNamespace(machinePool.Namespace), client.MatchingLabels{
+			key.ClusterNameLabel: machinePool.Spec.ClusterName,
+			key.ClusterRole:      iam.ControlPlaneRole,
+		})
+		if err != nil {
+			log.FromContext(ctx).Error(err, "failed to list control plane AWSMachineTemplates of KarpenterMachinePool", "machinepool", client.ObjectKeyFromObject(o))
+			return nil
+		}
+
+		var requests []reconcile.Request
+		for i := range templates.Items {
+			requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&templates.Items[i])})
+		}
+		return requests
+	})
+}
@@ -25,6 +25,7 @@ import (
 	"github.com/pkg/errors"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/tools/record"
 	"sigs.k8s.io/cluster-api/controllers/external"
 	expcapi "sigs.k8s.io/cluster-api/exp/api/v1beta1"
 	"sigs.k8s.io/cluster-api/util"
@@ -45,9 +46,17 @@ type MachinePoolReconciler struct {
 	client.Client
 	IAMClientFactory func(aws.Config, string) iam.IAMClient
 	AWSClient        awsclient.AwsClientInterface
+	// Recorder records events on the objects referencing AWS identities.
+	Recorder record.EventRecorder
+
+	ManagedPolicyRoleTypes []string
+	PermissionsBoundary    string
+	RoleSettings           iam.RoleSettings
+	PolicyTemplates        PolicyTemplatesConfig
+	ConfigMaps             *ConfigMapWatch
 }
 
-func (r *MachinePoolReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+func (r *MachinePoolReconciler) Reconcile(ctx context.Context, req ctrl.Request) (result ctrl.Result, reterr error) {
 	logger := log.FromContext(ctx)
 
 	machinePool := &expcapi.MachinePool{}
@@ -112,32 +121,54 @@ func (r *MachinePoolReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 		return ctrl.Result{}, nil
 	}
 
-	awsClusterRoleIdentity, err := key.GetAWSClusterRoleIdentity(ctx, r.Client, awsCluster.Spec.IdentityRef.Name)
+	// errors of AWS calls decide how the reconciliation is retried from here on
+	defer func() {
+		result, reterr = handleReconcileResult(ctx, r.Client, r.Recorder, awsCluster, "MachinePool "+machinePool.Name, machinePool.DeletionTimestamp != nil, result, reterr)
+	}()
+
+	awsClientConfig, err := getAWSClientConfig(ctx, r.Client, r.AWSClient, r.Recorder, awsCluster, awsCluster.Spec.IdentityRef, awsCluster.Spec.Region)
 	if err != nil {
-		logger.Error(err, "could not get AWSClusterRoleIdentity")
-		return ctrl.Result{}, microerror.Mask(err)
+		logger.Error(err, "Failed to get aws client session")
+		return ctrl.Result{}, errors.WithStack(err)
 	}
 
-	awsClientConfig, err := r.AWSClient.GetAWSClientConfig(awsClusterRoleIdentity.Spec.RoleArn, awsCluster.Spec.Region)
+	roleSettings, err := key.GetRoleSettings(awsCluster, r.RoleSettings)
 	if err != nil {
-		logger.Error(err, "Failed to get aws client session")
+		logger.Error(err, "invalid role settings")
+		return ctrl.Result{}, errors.WithStack(err)
+	}
+
+	policyTemplates, err := r.PolicyTemplates.load(ctx, r.Client, machinePool.Namespace)
+	if err != nil {
+		logger.Error(err, "failed to load policy templates")
+		return ctrl.Result{}, errors.WithStack(err)
+	}
+
+	additionalStatements, err := loadAdditionalPolicyStatements(ctx, r.Client, awsCluster)
+	if err != nil {
+		logger.Error(err, "failed to load additional policy statements")
 		return ctrl.Result{}, errors.WithStack(err)
 	}
 
 	var iamService *iam.IAMService
 	{
 		c := iam.IAMServiceConfig{
-			ObjectLabels:          maps.Clone(infraMachinePool.GetLabels()),
-			AWSConfig:             &awsClientConfig,
-			ClusterIsBeingDeleted: cluster.DeletionTimestamp != nil,
-			ClusterName:           cluster.Name,
-			ClusterRelease:        cluster.Labels[GiantSwarmReleaseLabel],
-			MainRoleName:          iamInstanceProfile,
-			Log:                   logger,
-			RoleType:              iam.NodesRole,
-			Region:                awsCluster.Spec.Region,
-			IAMClientFactory:      r.IAMClientFactory,
-			CustomTags:            awsCluster.Spec.AdditionalTags,
+			ObjectLabels:           maps.Clone(infraMachinePool.GetLabels()),
+			AWSConfig:              &awsClientConfig,
+			ClusterIsBeingDeleted:  cluster.DeletionTimestamp != nil,
+			ClusterName:            cluster.Name,
+			ClusterRelease:         cluster.Labels[GiantSwarmReleaseLabel],
+			MainRoleName:           iamInstanceProfile,
+			Log:                    logger,
+			RoleType:               iam.NodesRole,
+			Region:                 awsCluster.Spec.Region,
+			IAMClientFactory:       r.IAMClientFactory,
+			CustomTags:             awsCluster.Spec.AdditionalTags,
+			ManagedPolicyRoleTypes: r.ManagedPolicyRoleTypes,
+			PermissionsBoundary:    key.GetPermissionsBoundary(awsCluster, r.PermissionsBoundary),
+			RoleSettings:           roleSettings,
+			PolicyTemplates:        policyTemplates,
+			AdditionalStatements:   additionalStatements,
 		}
 		iamService, err = iam.New(c)
 		if err != nil {
@@ -205,5 +236,6 @@ func (r *MachinePoolReconciler) reconcileNormal(ctx context.Context, infraMachin
 func (r *MachinePoolReconciler) SetupWithManager(ctx context.Context, mgr ctrl.Manager) error {
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&expcapi.MachinePool{}).
+		WatchesRawSource(r.ConfigMaps.Source(func() client.ObjectList { return &expcapi.MachinePoolList{} })).
 		Complete(r)
 }
@@ -175,7 +175,8 @@ var _ = Describe("MachinePoolReconciler", func() {
 	expectedRoleStatusesOnSuccess := []RoleInfo{
 		// Worker node
 		{
-			ExpectedName: "the-profile",
+			ExpectedName:        "the-profile",
+			ExpectedDescription: "Worker nodes of cluster test-cluster, managed by capa-iam-operator",
 
 			ExpectedAssumeRolePolicyDocument: `{
   "Version": "2012-10-17",
@@ -329,7 +330,7 @@ var _ = Describe("MachinePoolReconciler", func() {
         "secretsmanager:GetSecretValue",
         "secretsmanager:DeleteSecret"
       ],
-      "Resource": "arn:*:secretsmanager:*:*:secret:aws.cluster.x-k8s.io/*",
+      "Resource": "arn:aws:secretsmanager:*:*:secret:aws.cluster.x-k8s.io/*",
       "Effect": "Allow"
     }
   ]
@@ -353,7 +354,7 @@ var _ = Describe("MachinePoolReconciler", func() {
 
 	When("a role does not exist", func() {
 		BeforeEach(func() {
-			mockAwsClient.EXPECT().GetAWSClientConfig("arn:aws:iam::012345678901:role/giantswarm-test-capa-controller", "eu-west-1").Return(*cfg, nil)
+			mockAwsClient.EXPECT().GetAWSClientConfig(gomock.Any(), &capa.AWSIdentityReference{Name: "test-3", Kind: "AWSClusterRoleIdentity"}, namespace, "eu-west-1").Return(*cfg, nil)
 			for _, info := range expectedRoleStatusesOnSuccess {
 				mockIAMClient.EXPECT().GetRole(context.TODO(), &awsiam.GetRoleInput{
 					RoleName: aws.String(info.ExpectedName),
@@ -367,10 +368,18 @@ var _ = Describe("MachinePoolReconciler", func() {
 					AssumeRolePolicyDocument: aws.String(info.ExpectedAssumeRolePolicyDocument),
 					RoleName:                 aws.String(info.ExpectedName),
 					Tags:                     expectedIAMTags,
+					Path:                     aws.String("/"),
+					Description:              aws.String(info.ExpectedDescription),
+					MaxSessionDuration:       aws.Int32(3600),
 				}).Return(&awsiam.CreateRoleOutput{}, nil)
 
+				mockIAMClient.EXPECT().GetInstanceProfile(context.TODO(), &awsiam.GetInstanceProfileInput{
+					InstanceProfileName: aws.String(info.ExpectedName),
+				}).Return(nil, &awsiamtypes.NoSuchEntityException{})
+
 				mockIAMClient.EXPECT().CreateInstanceProfile(context.TODO(), &awsiam.CreateInstanceProfileInput{
 					InstanceProfileName: aws.String(info.ExpectedName),
+					Path:                aws.String("/"),
 					Tags:                expectedIAMTags,
 				}).Return(&awsiam.CreateInstanceProfileOutput{}, nil)
 
@@ -418,6 +427,7 @@ var _ = Describe("MachinePoolReconciler", func() {
 			for _, info := range expectedRoleStatusesOnSuccess {
 				mockIAMClient.EXPECT().CreateInstanceProfile(context.TODO(), &awsiam.CreateInstanceProfileInput{
 					InstanceProfileName: aws.String(info.ExpectedName),
+					Path:                aws.String("/"),
 					Tags:                expectedIAMTags,
 				}).Return(&awsiam.CreateInstanceProfileOutput{}, nil)
 
@@ -0,0 +1,47 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/giantswarm/microerror"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/tools/record"
+	capi "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/util/conditions"
+	"sigs.k8s.io/cluster-api/util/patch"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// OIDCProvidersAvailableCondition reports whether the IAM OIDC providers of the IRSA trust domains of a cluster
+	// exist. Missing providers are only reported if the operator does not create them, see --manage-oidc-providers.
+	OIDCProvidersAvailableCondition capi.ConditionType = "OIDCProvidersAvailable"
+	// OIDCProviderMissingReason is the reason of events and conditions about missing OIDC providers.
+	OIDCProviderMissingReason = "OIDCProviderMissing"
+)
+
+// reportOIDCProviders records whether the OIDC providers of the IRSA trust domains of obj exist in the
+// OIDCProvidersAvailableCondition of obj. missingTrustDomains are the trust domains without a provider, which are
+// also reported in an event, as IRSA roles cannot be assumed through them until users create the providers.
+func reportOIDCProviders(ctx context.Context, ctrlClient client.Client, recorder record.EventRecorder, obj identityReferrer, missingTrustDomains []string) error {
+	patchHelper, err := patch.NewHelper(obj, ctrlClient)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	if len(missingTrustDomains) > 0 {
+		message := fmt.Sprintf("IAM OIDC providers of IRSA trust domains %s do not exist, IRSA roles cannot be assumed through them", strings.Join(missingTrustDomains, ", "))
+		recorder.Event(obj, corev1.EventTypeWarning, OIDCProviderMissingReason, message)
+		conditions.MarkFalse(obj, OIDCProvidersAvailableCondition, OIDCProviderMissingReason, capi.ConditionSeverityWarning, "%s", message)
+	} else {
+		conditions.MarkTrue(obj, OIDCProvidersAvailableCondition)
+	}
+
+	err = patchHelper.Patch(ctx, obj, patch.WithOwnedConditions{Conditions: []capi.ConditionType{OIDCProvidersAvailableCondition}})
+	if err != nil {
+		return microerror.Mask(err)
+	}
+	return nil
+}
@@ -0,0 +1,66 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/giantswarm/microerror"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/giantswarm/capa-iam-operator/v3/pkg/iam"
+)
+
+// PolicyTemplatesConfig locates the ConfigMaps that override the built-in policy templates. The operator-wide ConfigMap
+// lives in OperatorNamespace, and a ConfigMap with the same name in the namespace of a cluster takes precedence over it.
+type PolicyTemplatesConfig struct {
+	ConfigMapName     string
+	OperatorNamespace string
+}
+
+// load returns the policy template overrides for a cluster in the given namespace.
+func (c PolicyTemplatesConfig) load(ctx context.Context, ctrlClient client.Client, clusterNamespace string) (iam.PolicyTemplates, error) {
+	var templates iam.PolicyTemplates
+	if c.ConfigMapName == "" {
+		return templates, nil
+	}
+
+	namespaces := []string{clusterNamespace}
+	if c.OperatorNamespace != "" && c.OperatorNamespace != clusterNamespace {
+		namespaces = []string{c.OperatorNamespace, clusterNamespace}
+	}
+
+	for _, namespace := range namespaces {
+		cm := &corev1.ConfigMap{}
+		err := ctrlClient.Get(ctx, types.NamespacedName{Name: c.ConfigMapName, Namespace: namespace}, cm)
+		if k8serrors.IsNotFound(err) {
+			continue
+		}
+		if err != nil {
+			return iam.PolicyTemplates{}, microerror.Mask(err)
+		}
+
+		overrides, err := iam.ParsePolicyTemplates(cm.Data)
+		if err != nil {
+			return iam.PolicyTemplates{}, fmt.Errorf("invalid policy templates in ConfigMap %s/%s: %w", namespace, c.ConfigMapName, err)
+		}
+		templates = templates.Merge(overrides)
+	}
+
+	return templates, nil
+}
+
+// affectedBy returns the list options selecting the objects affected by a change to a ConfigMap: all of them for the
+// operator-wide policy templates ConfigMap, otherwise those in the ConfigMap's namespace. It returns false if the
+// ConfigMap does not hold policy templates.
+func (c PolicyTemplatesConfig) affectedBy(o client.Object) ([]client.ListOption, bool) {
+	if c.ConfigMapName == "" || o.GetName() != c.ConfigMapName {
+		return nil, false
+	}
+	if o.GetNamespace() == c.OperatorNamespace {
+		return nil, true
+	}
+	return []client.ListOption{client.InNamespace(o.GetNamespace())}, true
+}
@@ -0,0 +1,95 @@
+package controllers
+
+import (
+	"context"
+	"strings"
+
+	"github.com/giantswarm/microerror"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/tools/record"
+	capi "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/util/conditions"
+	"sigs.k8s.io/cluster-api/util/patch"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/giantswarm/capa-iam-operator/v3/pkg/awsclient"
+	"github.com/giantswarm/capa-iam-operator/v3/pkg/iam"
+)
+
+// IAMRolesReconciledCondition reports whether the IAM roles of a cluster were reconciled, or failed with an error
+// users have to act on, e.g. a malformed policy template or an exceeded quota. The reason is the class of the error.
+const IAMRolesReconciledCondition capi.ConditionType = "IAMRolesReconciled"
+
+// handleReconcileResult decides how a reconciliation of the IAM roles of a cluster is retried, based on the class of
+// its error. source names the object that was reconciled, e.g. "AWSMachineTemplate foo", deleting whether it is being
+// deleted, and obj is the cluster object it belongs to. Errors users have to act on are recorded in the
+// IAMRolesReconciledCondition of obj and in an event. The condition is cleared again by the next successful
+// reconciliation of the same source, so that the sources of a cluster do not overwrite each other's errors.
+func handleReconcileResult(ctx context.Context, ctrlClient client.Client, recorder record.EventRecorder, obj identityReferrer, source string, deleting bool, result ctrl.Result, err error) (ctrl.Result, error) {
+	logger := log.FromContext(ctx)
+	prefix := source + ": "
+
+	if awsclient.IsCircuitOpen(err) {
+		logger.Info("Calls to the AWS account are paused, requeueing", "reason", err.Error())
+		return ctrl.Result{RequeueAfter: awsclient.CircuitRetryAfter(err)}, nil
+	}
+
+	if err == nil {
+		if obj.GetDeletionTimestamp() != nil {
+			return result, nil
+		}
+		c := conditions.Get(obj, IAMRolesReconciledCondition)
+		if c != nil && (c.Status == corev1.ConditionTrue || !strings.HasPrefix(c.Message, prefix)) {
+			return result, nil
+		}
+		patchErr := patchIAMRolesReconciledCondition(ctx, ctrlClient, obj, func() {
+			conditions.MarkTrue(obj, IAMRolesReconciledCondition)
+		})
+		if patchErr != nil {
+			return ctrl.Result{}, microerror.Mask(patchErr)
+		}
+		return result, nil
+	}
+
+	class := iam.ClassifyError(err)
+	behaviour := class.Behaviour()
+	if behaviour.Report {
+		message := prefix + err.Error()
+		recorder.Event(obj, corev1.EventTypeWarning, string(class), message)
+		patchErr := patchIAMRolesReconciledCondition(ctx, ctrlClient, obj, func() {
+			conditions.MarkFalse(obj, IAMRolesReconciledCondition, string(class), capi.ConditionSeverityError, "%s", message)
+		})
+		if patchErr != nil {
+			logger.Error(patchErr, "Failed to report reconciliation error on cluster", "error_class", class)
+		}
+	}
+
+	switch {
+	case (deleting || obj.GetDeletionTimestamp() != nil) && !behaviour.Retry:
+		// the finalizer is only removed once the roles are deleted, so deletions are retried whatever the error
+		return result, err
+	case !behaviour.Retry:
+		logger.Error(err, "Reconciliation failed with an error that persists until the cluster changes, requeueing", "error_class", class, "requeue_after", behaviour.RequeueAfter)
+		return ctrl.Result{RequeueAfter: behaviour.RequeueAfter}, nil
+	case behaviour.RequeueAfter > 0:
+		logger.Error(err, "Reconciliation failed, requeueing", "error_class", class, "requeue_after", behaviour.RequeueAfter)
+		return ctrl.Result{RequeueAfter: behaviour.RequeueAfter}, nil
+	default:
+		return result, err
+	}
+}
+
+func patchIAMRolesReconciledCondition(ctx context.Context, ctrlClient client.Client, obj identityReferrer, mark func()) error {
+	patchHelper, err := patch.NewHelper(obj, ctrlClient)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+	mark()
+	err = patchHelper.Patch(ctx, obj, patch.WithOwnedConditions{Conditions: []capi.ConditionType{IAMRolesReconciledCondition}})
+	if err != nil {
+		return microerror.Mask(err)
+	}
+	return nil
+}
@@ -20,11 +20,13 @@ import (
 	"context"
 	"flag"
 	"os"
+	"strings"
 
 	// Import all Kubernetes client auth plugins (e.g. Azure, GCP, OIDC, etc.)
 	// to ensure that exec-entrypoint and run can make use of them.
 
 	"github.com/aws/aws-sdk-go-v2/aws"
+	awseks "github.com/aws/aws-sdk-go-v2/service/eks"
 	awsiam "github.com/aws/aws-sdk-go-v2/service/iam"
 	corev1 "k8s.io/api/core/v1"
 	_ "k8s.io/client-go/plugin/pkg/client/auth"
@@ -46,6 +48,7 @@ import (
 	"github.com/giantswarm/capa-iam-operator/v3/controllers"
 	"github.com/giantswarm/capa-iam-operator/v3/pkg/awsclient"
 	"github.com/giantswarm/capa-iam-operator/v3/pkg/iam"
+	"github.com/giantswarm/capa-iam-operator/v3/pkg/key"
 	// +kubebuilder:scaffold:imports
 )
 
@@ -66,10 +69,23 @@ func init() {
 }
 
 func main() {
+	accountLimits := awsclient.DefaultAccountLimits()
 	var metricsAddr string
 	var enableLeaderElection bool
+	var eksEndpoint string
 	var enableRoute53Role bool
+	var iamEndpoint string
+	var irsaRoleTypes string
+	var manageOIDCProviders bool
+	var managedPolicyRoleTypes string
+	var permissionsBoundary string
+	var policyTemplatesConfigMapName string
+	var policyTemplatesConfigMapNamespace string
 	var probeAddr string
+	var roleMaxSessionDuration string
+	var rolePath string
+	var staticIdentitySecretNamespace string
+	var stsEndpoint string
 	flag.StringVar(&metricsAddr, "metrics-bind-address", ":8080", "The address the metric endpoint binds to.")
 	flag.StringVar(&probeAddr, "health-probe-bind-address", ":8081", "The address the probe endpoint binds to.")
 	flag.BoolVar(&enableLeaderElection, "leader-elect", false,
@@ -77,6 +93,40 @@ func main() {
 			"Enabling this will ensure there is only one active controller manager.")
 	flag.BoolVar(&enableRoute53Role, "enable-route53-role", true,
 		"Enable creation and management of Route53 role for external-dns app.")
+	flag.StringVar(&irsaRoleTypes, "irsa-role-types", strings.Join(iam.IRSARoleTypes(), ","),
+		"Comma-separated list of IRSA role types created for clusters. Can be changed per cluster with the "+key.EnabledIRSARolesAnnotation+" and "+key.DisabledIRSARolesAnnotation+" annotations.")
+	flag.BoolVar(&manageOIDCProviders, "manage-oidc-providers", false,
+		"Create the IAM OIDC providers of the IRSA trust domains of clusters if they are missing, keep them up to date and delete them with the cluster. Otherwise missing providers are only reported.")
+	flag.StringVar(&managedPolicyRoleTypes, "managed-policy-role-types", "",
+		"Comma-separated list of role types whose permissions are kept in customer-managed policies instead of inline policies.")
+	flag.StringVar(&permissionsBoundary, "permissions-boundary", "",
+		"ARN of the policy to set as permissions boundary on all roles. Can be overridden per cluster with the "+key.PermissionsBoundaryAnnotation+" annotation.")
+	flag.StringVar(&policyTemplatesConfigMapName, "policy-templates-configmap-name", "capa-iam-operator-policy-templates",
+		"Name of the ConfigMaps overriding the built-in policy templates, looked up in the operator-wide namespace and in the namespace of each cluster. Empty disables overrides.")
+	flag.StringVar(&policyTemplatesConfigMapNamespace, "policy-templates-configmap-namespace", "",
+		"Namespace of the operator-wide ConfigMap overriding the built-in policy templates.")
+	flag.StringVar(&rolePath, "role-path", "",
+		"Path of all roles and instance profiles. Empty means \"/\". Can be overridden per cluster with the "+key.RolePathAnnotation+" annotation and per role type with the "+key.RolePathAnnotationPrefix+"<role type> annotations.")
+	flag.StringVar(&roleMaxSessionDuration, "role-max-session-duration", "",
+		"Maximum session duration of all roles, in seconds (e.g. \"7200\") or as duration (e.g. \"2h\"). Empty means one hour. Can be overridden per cluster with the "+key.RoleMaxSessionDurationAnnotation+" annotation and per role type with the "+key.RoleMaxSessionDurationAnnotationPrefix+"<role type> annotations.")
+	flag.StringVar(&staticIdentitySecretNamespace, "static-identity-secret-namespace", "capa-system",
+		"Namespace of the Secrets referenced by AWSClusterStaticIdentities, which is the namespace of the CAPA controller.")
+	flag.StringVar(&iamEndpoint, "iam-endpoint", "",
+		"URL of the IAM endpoint, e.g. of LocalStack. "+awsclient.EndpointRegionPlaceholder+" is replaced with the region of the cluster. Empty means the endpoint of AWS.")
+	flag.StringVar(&stsEndpoint, "sts-endpoint", "",
+		"URL of the STS endpoint, e.g. of LocalStack. "+awsclient.EndpointRegionPlaceholder+" is replaced with the region of the cluster. Empty means the regional endpoint of AWS.")
+	flag.StringVar(&eksEndpoint, "eks-endpoint", "",
+		"URL of the EKS endpoint, e.g. of LocalStack. "+awsclient.EndpointRegionPlaceholder+" is replaced with the region of the cluster. Empty means the endpoint of AWS.")
+	flag.Float64Var(&accountLimits.RequestsPerSecond, "aws-account-requests-per-second", accountLimits.RequestsPerSecond,
+		"Maximum rate of requests to an AWS account, shared by all clusters and controllers.")
+	flag.IntVar(&accountLimits.Burst, "aws-account-burst", accountLimits.Burst,
+		"Maximum burst of requests to an AWS account.")
+	flag.IntVar(&accountLimits.MaxAttempts, "aws-max-attempts", accountLimits.MaxAttempts,
+		"Maximum number of attempts of an AWS request, with adaptive retries of throttled requests.")
+	flag.IntVar(&accountLimits.CircuitBreakerThreshold, "aws-circuit-breaker-threshold", accountLimits.CircuitBreakerThreshold,
+		"Number of consecutive throttling errors after which calls to an AWS account are paused, or AccessDenied errors after which the calls of an identity are paused.")
+	flag.DurationVar(&accountLimits.CircuitBreakerPause, "aws-circuit-breaker-pause", accountLimits.CircuitBreakerPause,
+		"How long calls to an AWS account are paused at first. The pause doubles while the account keeps failing.")
 	opts := zap.Options{
 		Development: false,
 	}
@@ -85,6 +135,43 @@ func main() {
 
 	ctrl.SetLogger(zap.New(zap.UseFlagOptions(&opts)))
 
+	if err := iam.ValidateIRSARoleTypes(splitList(irsaRoleTypes)); err != nil {
+		setupLog.Error(err, "invalid --irsa-role-types flag")
+		os.Exit(1)
+	}
+	if err := iam.ValidateRoleTypes(splitList(managedPolicyRoleTypes)); err != nil {
+		setupLog.Error(err, "invalid --managed-policy-role-types flag")
+		os.Exit(1)
+	}
+	roleSettings := iam.RoleSettings{
+		Path: rolePath,
+	}
+	if roleMaxSessionDuration != "" {
+		d, err := key.ParseMaxSessionDuration(roleMaxSessionDuration)
+		if err != nil {
+			setupLog.Error(err, "invalid --role-max-session-duration flag")
+			os.Exit(1)
+		}
+		roleSettings.MaxSessionDuration = d
+	}
+	if err := roleSettings.Validate(); err != nil {
+		setupLog.Error(err, "invalid role settings flags")
+		os.Exit(1)
+	}
+	endpoints := awsclient.Endpoints{
+		IAM: iamEndpoint,
+		STS: stsEndpoint,
+		EKS: eksEndpoint,
+	}
+	if err := endpoints.Validate(); err != nil {
+		setupLog.Error(err, "invalid endpoint flags")
+		os.Exit(1)
+	}
+	if err := accountLimits.Validate(); err != nil {
+		setupLog.Error(err, "invalid AWS account limit flags")
+		os.Exit(1)
+	}
+
 	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), ctrl.Options{
 		Scheme: scheme,
 		Metrics: metricsserver.Options{
@@ -112,32 +199,76 @@ func main() {
 		os.Exit(1)
 	}
 
+	// role sessions are shared by all controllers and dropped once their identity changes
+	credentialsCache := awsclient.NewCredentialsCache()
+	if err := credentialsCache.SetupWithManager(context.Background(), mgr); err != nil {
+		setupLog.Error(err, "unable to set up credentials cache")
+		os.Exit(1)
+	}
+
+	// the limits of an AWS account apply to the calls of all controllers
+	accountLimiter := awsclient.NewAccountLimiter(accountLimits)
+
 	awsClientAwsMachineTemplate, err := awsclient.New(awsclient.AWSClientConfig{
-		CtrlClient: mgr.GetClient(),
-		Log:        ctrl.Log.WithName("controllers").WithName("AWSMachineTemplate"),
+		CtrlClient:                    mgr.GetClient(),
+		Log:                           ctrl.Log.WithName("controllers").WithName("AWSMachineTemplate"),
+		StaticIdentitySecretNamespace: staticIdentitySecretNamespace,
+		CredentialsCache:              credentialsCache,
+		Endpoints:                     endpoints,
+		AccountLimiter:                accountLimiter,
 	})
 	if err != nil {
 		setupLog.Error(err, "unable to create aws client for controller", "controller", "AWSMachineTemplate")
 		os.Exit(1)
 	}
 
+	policyTemplatesConfig := controllers.PolicyTemplatesConfig{
+		ConfigMapName:     policyTemplatesConfigMapName,
+		OperatorNamespace: policyTemplatesConfigMapNamespace,
+	}
+
+	// a single informer watches the ConfigMaps read by all controllers
+	configMapWatch := &controllers.ConfigMapWatch{
+		Client:          mgr.GetClient(),
+		PolicyTemplates: policyTemplatesConfig,
+	}
+	if err := configMapWatch.SetupWithManager(context.Background(), mgr); err != nil {
+		setupLog.Error(err, "unable to set up ConfigMap watch")
+		os.Exit(1)
+	}
+
 	iamClientFactory := func(cfg aws.Config, region string) iam.IAMClient {
-		return awsiam.NewFromConfig(cfg)
+		return awsiam.NewFromConfig(cfg, endpoints.IAMOptions(region))
+	}
+	eksClientFactory := func(cfg aws.Config, region string) iam.EKSClient {
+		return awseks.NewFromConfig(cfg, endpoints.EKSOptions(region))
 	}
 
 	if err = (&controllers.AWSMachineTemplateReconciler{
-		Client:            mgr.GetClient(),
-		EnableRoute53Role: enableRoute53Role,
-		AWSClient:         awsClientAwsMachineTemplate,
-		IAMClientFactory:  iamClientFactory,
+		Client:                 mgr.GetClient(),
+		EnableRoute53Role:      enableRoute53Role,
+		AWSClient:              awsClientAwsMachineTemplate,
+		IAMClientFactory:       iamClientFactory,
+		Recorder:               mgr.GetEventRecorderFor("capa-iam-operator"),
+		ManagedPolicyRoleTypes: splitList(managedPolicyRoleTypes),
+		PermissionsBoundary:    permissionsBoundary,
+		RoleSettings:           roleSettings,
+		PolicyTemplates:        policyTemplatesConfig,
+		ConfigMaps:             configMapWatch,
+		IRSARoleTypes:          splitList(irsaRoleTypes),
+		ManageOIDCProviders:    manageOIDCProviders,
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "AWSMachineTemplate")
 		os.Exit(1)
 	}
 
 	awsClientAwsMachine, err := awsclient.New(awsclient.AWSClientConfig{
-		CtrlClient: mgr.GetClient(),
-		Log:        ctrl.Log.WithName("controllers").WithName("AWSMachinePool"),
+		CtrlClient:                    mgr.GetClient(),
+		Log:                           ctrl.Log.WithName("controllers").WithName("AWSMachinePool"),
+		StaticIdentitySecretNamespace: staticIdentitySecretNamespace,
+		CredentialsCache:              credentialsCache,
+		Endpoints:                     endpoints,
+		AccountLimiter:                accountLimiter,
 	})
 	if err != nil {
 		setupLog.Error(err, "unable to create aws client for controller", "controller", "AWSMachinePool")
@@ -145,18 +276,33 @@ func main() {
 	}
 
 	if err = (&controllers.MachinePoolReconciler{
-		Client:           mgr.GetClient(),
-		AWSClient:        awsClientAwsMachine,
-		IAMClientFactory: iamClientFactory,
+		Client:                 mgr.GetClient(),
+		AWSClient:              awsClientAwsMachine,
+		IAMClientFactory:       iamClientFactory,
+		Recorder:               mgr.GetEventRecorderFor("capa-iam-operator"),
+		ManagedPolicyRoleTypes: splitList(managedPolicyRoleTypes),
+		PermissionsBoundary:    permissionsBoundary,
+		RoleSettings:           roleSettings,
+		PolicyTemplates:        policyTemplatesConfig,
+		ConfigMaps:             configMapWatch,
 	}).SetupWithManager(context.Background(), mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "AWSMachinePool")
 		os.Exit(1)
 	}
 
 	if err = (&controllers.AWSManagedControlPlaneReconciler{
-		Client:           mgr.GetClient(),
-		AWSClient:        awsClientAwsMachine,
-		IAMClientFactory: iamClientFactory,
+		Client:                 mgr.GetClient(),
+		AWSClient:              awsClientAwsMachine,
+		IAMClientFactory:       iamClientFactory,
+		EKSClientFactory:       eksClientFactory,
+		Recorder:               mgr.GetEventRecorderFor("capa-iam-operator"),
+		ManagedPolicyRoleTypes: splitList(managedPolicyRoleTypes),
+		PermissionsBoundary:    permissionsBoundary,
+		RoleSettings:           roleSettings,
+		PolicyTemplates:        policyTemplatesConfig,
+		ConfigMaps:             configMapWatch,
+		IRSARoleTypes:          splitList(irsaRoleTypes),
+		ManageOIDCProviders:    manageOIDCProviders,
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "AWSManagedControlPlane")
 		os.Exit(1)
@@ -179,3 +325,15 @@ func main() {
 		os.Exit(1)
 	}
 }
+
+// splitList splits a comma-separated flag value, ignoring empty items.
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		if item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -0,0 +1,367 @@
+package awsclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/retry"
+	"github.com/aws/smithy-go"
+	"github.com/aws/smithy-go/middleware"
+	"golang.org/x/time/rate"
+)
+
+// Default limits of the calls to an AWS account. IAM allows only a few requests per second per account, shared by all
+// clusters of the account.
+const (
+	defaultAccountRequestsPerSecond = 10
+	defaultAccountBurst             = 20
+	defaultMaxAttempts              = 8
+	defaultMaxBackoff               = 20 * time.Second
+	defaultCircuitBreakerThreshold  = 5
+	defaultCircuitBreakerPause      = 30 * time.Second
+	defaultCircuitBreakerMaxPause   = 10 * time.Minute
+)
+
+// circuitBreakerProbeRetryAfter is when calls are retried that were refused while the first call after a pause finds
+// out whether the account is available again.
+const circuitBreakerProbeRetryAfter = 5 * time.Second
+
+// States of the circuit breakers of an AWS account, as exposed in the
+// capa_iam_operator_aws_account_circuit_breaker_state metric.
+const (
+	circuitClosed   = 0
+	circuitHalfOpen = 1
+	circuitOpen     = 2
+)
+
+// AccountLimits configures the limits of the calls to an AWS account.
+type AccountLimits struct {
+	// RequestsPerSecond and Burst limit the rate of requests to the account, including retries.
+	RequestsPerSecond float64
+	Burst             int
+	// MaxAttempts and MaxBackoff configure the adaptive retries of throttled and failed requests.
+	MaxAttempts int
+	MaxBackoff  time.Duration
+	// CircuitBreakerThreshold is the number of consecutive calls failing with a throttling error after which calls to
+	// the account are paused for CircuitBreakerPause, or failing with an AccessDenied error after which the calls of
+	// the identity are paused. The pause doubles every time the first call after a pause fails as well, up to
+	// CircuitBreakerMaxPause.
+	CircuitBreakerThreshold int
+	CircuitBreakerPause     time.Duration
+	CircuitBreakerMaxPause  time.Duration
+}
+
+// DefaultAccountLimits returns the default limits of the calls to an AWS account.
+func DefaultAccountLimits() AccountLimits {
+	return AccountLimits{
+		RequestsPerSecond:       defaultAccountRequestsPerSecond,
+		Burst:                   defaultAccountBurst,
+		MaxAttempts:             defaultMaxAttempts,
+		MaxBackoff:              defaultMaxBackoff,
+		CircuitBreakerThreshold: defaultCircuitBreakerThreshold,
+		CircuitBreakerPause:     defaultCircuitBreakerPause,
+		CircuitBreakerMaxPause:  defaultCircuitBreakerMaxPause,
+	}
+}
+
+// Validate returns an error if the limits would keep the operator from calling AWS at all.
+func (l AccountLimits) Validate() error {
+	if l.RequestsPerSecond <= 0 || l.Burst < 1 {
+		return fmt.Errorf("invalid rate limit of %v requests per second with a burst of %d, both must be positive", l.RequestsPerSecond, l.Burst)
+	}
+	if l.MaxAttempts < 1 {
+		return fmt.Errorf("invalid maximum of %d attempts, expected at least 1", l.MaxAttempts)
+	}
+	if l.CircuitBreakerThreshold < 1 {
+		return fmt.Errorf("invalid circuit breaker threshold %d, expected at least 1", l.CircuitBreakerThreshold)
+	}
+	if l.CircuitBreakerPause <= 0 || l.CircuitBreakerMaxPause < l.CircuitBreakerPause {
+		return fmt.Errorf("invalid circuit breaker pause %s, expected a positive duration of at most %s", l.CircuitBreakerPause, l.CircuitBreakerMaxPause)
+	}
+	return nil
+}
+
+// AccountLimiter applies the limits to the calls of all AWS clients to an account, regardless of the controller and
+// the identity making them. Only AccessDenied errors are counted per identity, as they concern the permissions of
+// one identity rather than the account. It can be shared by several AwsClients. The circuit breakers of identity
+// chains that have not been used for an hour are dropped.
+type AccountLimiter struct {
+	limits AccountLimits
+	now    func() time.Time
+
+	mu           sync.Mutex
+	accounts     map[string]*accountState
+	identities   map[string]*identityState
+	lastEviction time.Time
+}
+
+type identityState struct {
+	breaker  *circuitBreaker
+	lastUsed time.Time
+}
+
+type accountState struct {
+	accountID string
+	limiter   *rate.Limiter
+	retryer   aws.Retryer
+	breaker   *circuitBreaker
+}
+
+func NewAccountLimiter(limits AccountLimits) *AccountLimiter {
+	return &AccountLimiter{
+		limits:     limits,
+		now:        time.Now,
+		accounts:   map[string]*accountState{},
+		identities: map[string]*identityState{},
+	}
+}
+
+// apply makes the clients created from the configuration call AWS within the limits of the account. identity is the
+// key of the identity chain the configuration's credentials belong to.
+func (l *AccountLimiter) apply(cfg *aws.Config, accountID string, identity string) {
+	state := l.account(accountID)
+	identityBreaker := l.identity(accountID, identity)
+	cfg.Retryer = func() aws.Retryer { return state.retryer }
+	// the slice may be shared with the configuration the given one was copied from
+	cfg.APIOptions = append(slices.Clone(cfg.APIOptions), func(stack *middleware.Stack) error {
+		return state.addMiddleware(stack, identityBreaker)
+	})
+}
+
+// check returns an error asserted by IsCircuitOpen while calls to the account or calls of the identity are paused.
+func (l *AccountLimiter) check(accountID string, identity string) error {
+	err := l.account(accountID).breaker.check()
+	if err != nil {
+		return err
+	}
+	return l.identity(accountID, identity).check()
+}
+
+func (l *AccountLimiter) account(accountID string) *accountState {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	state, ok := l.accounts[accountID]
+	if !ok {
+		state = &accountState{
+			accountID: accountID,
+			limiter:   rate.NewLimiter(rate.Limit(l.limits.RequestsPerSecond), l.limits.Burst),
+			retryer: retry.NewAdaptiveMode(func(o *retry.AdaptiveModeOptions) {
+				o.StandardOptions = append(o.StandardOptions, func(o *retry.StandardOptions) {
+					o.MaxAttempts = l.limits.MaxAttempts
+					o.MaxBackoff = l.limits.MaxBackoff
+				})
+			}),
+			breaker: l.newCircuitBreaker(accountID, "", isThrottleError),
+		}
+		l.accounts[accountID] = state
+	}
+	return state
+}
+
+// identity returns the circuit breaker counting the AccessDenied errors of an identity chain.
+func (l *AccountLimiter) identity(accountID string, identity string) *circuitBreaker {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.evictIdleIdentities(now)
+	state, ok := l.identities[identity]
+	if !ok {
+		state = &identityState{breaker: l.newCircuitBreaker(accountID, identity, isAccessDeniedError)}
+		l.identities[identity] = state
+	}
+	state.lastUsed = now
+	return state.breaker
+}
+
+// evictIdleIdentities drops the circuit breakers of identity chains that have not been used within idleTimeout,
+// together with their metrics, looking for them at most once per idleEvictionInterval. Clients created before still
+// hold on to their breaker. The caller must hold the lock.
+func (l *AccountLimiter) evictIdleIdentities(now time.Time) {
+	if now.Sub(l.lastEviction) < idleEvictionInterval {
+		return
+	}
+	l.lastEviction = now
+
+	for identity, state := range l.identities {
+		if now.Sub(state.lastUsed) >= idleTimeout {
+			delete(l.identities, identity)
+			circuitBreakerState.DeleteLabelValues(state.breaker.accountID, identity)
+			circuitBreakerTripsTotal.DeleteLabelValues(state.breaker.accountID, identity)
+		}
+	}
+}
+
+func (l *AccountLimiter) newCircuitBreaker(accountID string, identity string, trips func(error) bool) *circuitBreaker {
+	circuitBreakerState.WithLabelValues(accountID, identity).Set(circuitClosed)
+	return &circuitBreaker{
+		accountID: accountID,
+		identity:  identity,
+		trips:     trips,
+		threshold: l.limits.CircuitBreakerThreshold,
+		pause:     l.limits.CircuitBreakerPause,
+		maxPause:  l.limits.CircuitBreakerMaxPause,
+		now:       l.now,
+	}
+}
+
+// addMiddleware adds the circuit breakers of the account and the identity around the whole operation, including its
+// retries, and the rate limiter in front of every attempt.
+func (s *accountState) addMiddleware(stack *middleware.Stack, identityBreaker *circuitBreaker) error {
+	err := stack.Initialize.Add(middleware.InitializeMiddlewareFunc("AccountCircuitBreaker", func(ctx context.Context, in middleware.InitializeInput, next middleware.InitializeHandler) (middleware.InitializeOutput, middleware.Metadata, error) {
+		err := identityBreaker.allow()
+		if err != nil {
+			return middleware.InitializeOutput{}, middleware.Metadata{}, err
+		}
+		err = s.breaker.allow()
+		if err != nil {
+			identityBreaker.release()
+			return middleware.InitializeOutput{}, middleware.Metadata{}, err
+		}
+		out, metadata, err := next.HandleInitialize(ctx, in)
+		s.breaker.record(err)
+		identityBreaker.record(err)
+		return out, metadata, err
+	}), middleware.Before)
+	if err != nil {
+		return err
+	}
+
+	return stack.Finalize.Insert(middleware.FinalizeMiddlewareFunc("AccountRateLimiter", func(ctx context.Context, in middleware.FinalizeInput, next middleware.FinalizeHandler) (middleware.FinalizeOutput, middleware.Metadata, error) {
+		err := s.limiter.Wait(ctx)
+		if err != nil {
+			return middleware.FinalizeOutput{}, middleware.Metadata{}, err
+		}
+		return next.HandleFinalize(ctx, in)
+	}), (&retry.Attempt{}).ID(), middleware.After)
+}
+
+// circuitBreaker pauses the calls to an account after consecutive throttling errors, or the calls of an identity after
+// consecutive AccessDenied errors. After the pause a single call is let through; if it fails as well, the calls are
+// paused again for twice as long.
+type circuitBreaker struct {
+	accountID string
+	// identity is the key of the identity chain whose calls are paused, empty for all calls to the account.
+	identity  string
+	trips     func(error) bool
+	threshold int
+	pause     time.Duration
+	maxPause  time.Duration
+	now       func() time.Time
+
+	mu        sync.Mutex
+	failures  int
+	tripCount int
+	openUntil time.Time
+	probing   bool
+}
+
+func (b *circuitBreaker) check() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.tripCount > 0 && b.now().Before(b.openUntil) {
+		return b.openError(b.openUntil)
+	}
+	return nil
+}
+
+func (b *circuitBreaker) allow() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.tripCount == 0 {
+		return nil
+	}
+	now := b.now()
+	if now.Before(b.openUntil) {
+		return b.openError(b.openUntil)
+	}
+	if b.probing {
+		// only the first call after the pause finds out whether the calls are accepted again
+		return b.openError(now.Add(circuitBreakerProbeRetryAfter))
+	}
+	b.probing = true
+	circuitBreakerState.WithLabelValues(b.accountID, b.identity).Set(circuitHalfOpen)
+	return nil
+}
+
+// release gives up the call let through by allow without making it, so that the next one is let through instead.
+func (b *circuitBreaker) release() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.probing {
+		b.probing = false
+		circuitBreakerState.WithLabelValues(b.accountID, b.identity).Set(circuitOpen)
+	}
+}
+
+func (b *circuitBreaker) record(err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if !b.trips(err) {
+		if err != nil && (isCanceled(err) || isThrottleError(err)) {
+			// the call did not tell whether the calls are accepted, so the next one is let through instead
+			b.probing = false
+			return
+		}
+		if b.tripCount > 0 {
+			circuitBreakerState.WithLabelValues(b.accountID, b.identity).Set(circuitClosed)
+		}
+		b.failures = 0
+		b.tripCount = 0
+		b.probing = false
+		return
+	}
+
+	b.failures++
+	if !b.probing && (b.tripCount > 0 || b.failures < b.threshold) {
+		// calls that were already running when the breaker tripped do not extend the pause
+		return
+	}
+	b.probing = false
+	b.tripCount++
+	pause := b.pause << (b.tripCount - 1)
+	if pause > b.maxPause || pause <= 0 {
+		pause = b.maxPause
+	}
+	b.openUntil = b.now().Add(pause)
+	circuitBreakerState.WithLabelValues(b.accountID, b.identity).Set(circuitOpen)
+	circuitBreakerTripsTotal.WithLabelValues(b.accountID, b.identity).Inc()
+}
+
+func (b *circuitBreaker) openError(retryAt time.Time) error {
+	return &CircuitOpenError{AccountID: b.accountID, Identity: b.identity, RetryAt: retryAt}
+}
+
+// isThrottleError returns true for errors telling that the account refuses calls because of their rate.
+func isThrottleError(err error) bool {
+	var apiErr smithy.APIError
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+	_, ok := retry.DefaultThrottleErrorCodes[apiErr.ErrorCode()]
+	return ok
+}
+
+// isAccessDeniedError returns true for errors telling that the identity making a call lacks permissions.
+func isAccessDeniedError(err error) bool {
+	var apiErr smithy.APIError
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+	code := apiErr.ErrorCode()
+	return code == "AccessDenied" || code == "AccessDeniedException"
+}
+
+func isCanceled(err error) bool {
+	return errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)
+}
@@ -0,0 +1,316 @@
+package awsclient_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	dto "github.com/prometheus/client_model/go"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	capa "sigs.k8s.io/cluster-api-provider-aws/v2/api/v1beta2"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	"github.com/giantswarm/capa-iam-operator/v3/pkg/awsclient"
+)
+
+const getCallerIdentityResponse = `<GetCallerIdentityResponse xmlns="https://sts.amazonaws.com/doc/2011-06-15/">
+  <GetCallerIdentityResult>
+    <Arn>arn:aws:iam::123456789012:user/emulator</Arn>
+    <UserId>AIDAEXAMPLE</UserId>
+    <Account>123456789012</Account>
+  </GetCallerIdentityResult>
+  <ResponseMetadata><RequestId>1</RequestId></ResponseMetadata>
+</GetCallerIdentityResponse>`
+
+const accessDeniedResponse = `<ErrorResponse xmlns="https://sts.amazonaws.com/doc/2011-06-15/">
+  <Error><Type>Sender</Type><Code>AccessDenied</Code><Message>not allowed</Message></Error>
+  <RequestId>1</RequestId>
+</ErrorResponse>`
+
+const throttlingResponse = `<ErrorResponse xmlns="https://sts.amazonaws.com/doc/2011-06-15/">
+  <Error><Type>Sender</Type><Code>Throttling</Code><Message>rate exceeded</Message></Error>
+  <RequestId>1</RequestId>
+</ErrorResponse>`
+
+var _ = Describe("AccountLimiter", func() {
+	var (
+		ctx       context.Context
+		denied    atomic.Bool
+		throttled atomic.Bool
+		// deniedAccessKey limits the denied requests to those of one access key if set
+		deniedAccessKey atomic.Value
+		requests        atomic.Int32
+		server          *httptest.Server
+		limits          awsclient.AccountLimits
+		accountLimiter  *awsclient.AccountLimiter
+		awsClient       *awsclient.AwsClient
+		ref             *capa.AWSIdentityReference
+	)
+
+	BeforeEach(func() {
+		ctx = context.TODO()
+		denied.Store(false)
+		throttled.Store(false)
+		deniedAccessKey.Store("")
+		requests.Store(0)
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requests.Add(1)
+			w.Header().Set("Content-Type", "text/xml")
+			accessKey := deniedAccessKey.Load().(string)
+			if denied.Load() && (accessKey == "" || strings.Contains(r.Header.Get("Authorization"), "Credential="+accessKey+"/")) {
+				w.WriteHeader(http.StatusForbidden)
+				_, _ = w.Write([]byte(accessDeniedResponse))
+				return
+			}
+			if throttled.Load() {
+				w.WriteHeader(http.StatusBadRequest)
+				_, _ = w.Write([]byte(throttlingResponse))
+				return
+			}
+			_, _ = w.Write([]byte(getCallerIdentityResponse))
+		}))
+		DeferCleanup(server.Close)
+
+		limits = awsclient.DefaultAccountLimits()
+		limits.MaxAttempts = 1
+		limits.CircuitBreakerThreshold = 2
+		limits.CircuitBreakerPause = 200 * time.Millisecond
+		ref = &capa.AWSIdentityReference{Kind: capa.ClusterStaticIdentityKind, Name: "static"}
+		accountLimiter = nil
+	})
+
+	JustBeforeEach(func() {
+		if accountLimiter == nil {
+			accountLimiter = awsclient.NewAccountLimiter(limits)
+		}
+		var err error
+		awsClient, err = awsclient.New(awsclient.AWSClientConfig{
+			CtrlClient: fake.NewClientBuilder().WithScheme(newScheme()).WithObjects(
+				&capa.AWSClusterStaticIdentity{
+					ObjectMeta: metav1.ObjectMeta{Name: "static"},
+					Spec: capa.AWSClusterStaticIdentitySpec{
+						AWSClusterIdentitySpec: capa.AWSClusterIdentitySpec{AllowedNamespaces: &capa.AllowedNamespaces{}},
+						SecretRef:              "static-credentials",
+					},
+				},
+				&corev1.Secret{
+					ObjectMeta: metav1.ObjectMeta{Name: "static-credentials", Namespace: "capa-system"},
+					Data: map[string][]byte{
+						"AccessKeyID":     []byte("test"),
+						"SecretAccessKey": []byte("test"),
+					},
+				},
+				&capa.AWSClusterStaticIdentity{
+					ObjectMeta: metav1.ObjectMeta{Name: "other"},
+					Spec: capa.AWSClusterStaticIdentitySpec{
+						AWSClusterIdentitySpec: capa.AWSClusterIdentitySpec{AllowedNamespaces: &capa.AllowedNamespaces{}},
+						SecretRef:              "other-credentials",
+					},
+				},
+				&corev1.Secret{
+					ObjectMeta: metav1.ObjectMeta{Name: "other-credentials", Namespace: "capa-system"},
+					Data: map[string][]byte{
+						"AccessKeyID":     []byte("other"),
+						"SecretAccessKey": []byte("other"),
+					},
+				},
+			).Build(),
+			Log:                           ctrl.Log,
+			StaticIdentitySecretNamespace: "capa-system",
+			Endpoints:                     awsclient.Endpoints{STS: server.URL},
+			AccountLimiter:                accountLimiter,
+		})
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	callSTS := func(ctx context.Context, cfg aws.Config) error {
+		_, err := sts.NewFromConfig(cfg, awsclient.Endpoints{STS: server.URL}.STSOptions(cfg.Region)).GetCallerIdentity(ctx, &sts.GetCallerIdentityInput{})
+		return err
+	}
+
+	It("pauses an identity after repeated AccessDenied errors", func() {
+		cfg, err := awsClient.GetAWSClientConfig(ctx, ref, "test", "eu-west-1")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(requests.Load()).To(Equal(int32(1)))
+
+		denied.Store(true)
+		Expect(callSTS(ctx, cfg)).To(HaveOccurred())
+		Expect(callSTS(ctx, cfg)).To(HaveOccurred())
+		Expect(requests.Load()).To(Equal(int32(3)))
+
+		err = callSTS(ctx, cfg)
+		Expect(awsclient.IsCircuitOpen(err)).To(BeTrue())
+		Expect(awsclient.CircuitRetryAfter(err)).To(BeNumerically(">", 0))
+		Expect(requests.Load()).To(Equal(int32(3)))
+
+		var circuitOpenErr *awsclient.CircuitOpenError
+		Expect(errors.As(err, &circuitOpenErr)).To(BeTrue())
+		Expect(circuitOpenErr.Identity).To(ContainSubstring("AWSClusterStaticIdentity/static"))
+		breakerLabels := map[string]string{"account_id": "123456789012", "identity": circuitOpenErr.Identity}
+		Expect(gaugeValue("capa_iam_operator_aws_account_circuit_breaker_state", breakerLabels)).To(Equal(2.0))
+		Expect(gaugeValue("capa_iam_operator_aws_account_circuit_breaker_state", map[string]string{"account_id": "123456789012", "identity": ""})).To(Equal(0.0))
+
+		_, err = awsClient.GetAWSClientConfig(ctx, ref, "test", "eu-west-1")
+		Expect(awsclient.IsCircuitOpen(err)).To(BeTrue())
+
+		// after the pause a single call finds out that the identity is allowed again
+		time.Sleep(limits.CircuitBreakerPause)
+		denied.Store(false)
+		Expect(callSTS(ctx, cfg)).To(Succeed())
+		Expect(gaugeValue("capa_iam_operator_aws_account_circuit_breaker_state", breakerLabels)).To(Equal(0.0))
+		_, err = awsClient.GetAWSClientConfig(ctx, ref, "test", "eu-west-1")
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("does not pause other identities of the account after AccessDenied errors", func() {
+		cfg, err := awsClient.GetAWSClientConfig(ctx, ref, "test", "eu-west-1")
+		Expect(err).NotTo(HaveOccurred())
+		otherRef := &capa.AWSIdentityReference{Kind: capa.ClusterStaticIdentityKind, Name: "other"}
+		otherCfg, err := awsClient.GetAWSClientConfig(ctx, otherRef, "test", "eu-west-1")
+		Expect(err).NotTo(HaveOccurred())
+
+		denied.Store(true)
+		deniedAccessKey.Store("test")
+		for range limits.CircuitBreakerThreshold {
+			Expect(callSTS(ctx, cfg)).To(HaveOccurred())
+		}
+		Expect(awsclient.IsCircuitOpen(callSTS(ctx, cfg))).To(BeTrue())
+
+		Expect(callSTS(ctx, otherCfg)).To(Succeed())
+		_, err = awsClient.GetAWSClientConfig(ctx, otherRef, "test", "eu-west-1")
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("pauses all identities of an account after repeated throttling errors", func() {
+		cfg, err := awsClient.GetAWSClientConfig(ctx, ref, "test", "eu-west-1")
+		Expect(err).NotTo(HaveOccurred())
+		otherRef := &capa.AWSIdentityReference{Kind: capa.ClusterStaticIdentityKind, Name: "other"}
+		otherCfg, err := awsClient.GetAWSClientConfig(ctx, otherRef, "test", "eu-west-1")
+		Expect(err).NotTo(HaveOccurred())
+
+		throttled.Store(true)
+		for range limits.CircuitBreakerThreshold {
+			Expect(callSTS(ctx, cfg)).To(HaveOccurred())
+		}
+
+		err = callSTS(ctx, otherCfg)
+		Expect(awsclient.IsCircuitOpen(err)).To(BeTrue())
+		Expect(err.Error()).To(ContainSubstring("throttling"))
+		_, err = awsClient.GetAWSClientConfig(ctx, otherRef, "test", "eu-west-1")
+		Expect(awsclient.IsCircuitOpen(err)).To(BeTrue())
+	})
+
+	It("does not pause an account after other errors", func() {
+		cfg, err := awsClient.GetAWSClientConfig(ctx, ref, "test", "eu-west-1")
+		Expect(err).NotTo(HaveOccurred())
+
+		canceled, cancel := context.WithCancel(ctx)
+		cancel()
+		for range limits.CircuitBreakerThreshold + 1 {
+			err = callSTS(canceled, cfg)
+			Expect(err).To(HaveOccurred())
+			Expect(awsclient.IsCircuitOpen(err)).To(BeFalse())
+		}
+	})
+
+	When("an identity is no longer used", func() {
+		var now time.Time
+
+		BeforeEach(func() {
+			now = time.Now()
+			accountLimiter = awsclient.NewAccountLimiter(limits)
+			accountLimiter.SetClock(func() time.Time { return now })
+		})
+
+		It("drops its circuit breaker", func() {
+			cfg, err := awsClient.GetAWSClientConfig(ctx, ref, "test", "eu-west-1")
+			Expect(err).NotTo(HaveOccurred())
+			denied.Store(true)
+			for range limits.CircuitBreakerThreshold {
+				Expect(callSTS(ctx, cfg)).To(HaveOccurred())
+			}
+			err = callSTS(ctx, cfg)
+			Expect(awsclient.IsCircuitOpen(err)).To(BeTrue())
+			var circuitOpenErr *awsclient.CircuitOpenError
+			Expect(errors.As(err, &circuitOpenErr)).To(BeTrue())
+			breakerLabels := map[string]string{"account_id": "123456789012", "identity": circuitOpenErr.Identity}
+			Expect(gaugeRegistered("capa_iam_operator_aws_account_circuit_breaker_state", breakerLabels)).To(BeTrue())
+
+			now = now.Add(2 * time.Hour)
+			denied.Store(false)
+			_, err = awsClient.GetAWSClientConfig(ctx, &capa.AWSIdentityReference{Kind: capa.ClusterStaticIdentityKind, Name: "other"}, "test", "eu-west-1")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(gaugeRegistered("capa_iam_operator_aws_account_circuit_breaker_state", breakerLabels)).To(BeFalse())
+		})
+	})
+
+	When("the rate limit is reached", func() {
+		BeforeEach(func() {
+			limits.RequestsPerSecond = 0.001
+			limits.Burst = 1
+		})
+
+		It("makes requests wait", func() {
+			// the first call after the lookup of the account uses up the burst
+			cfg, err := awsClient.GetAWSClientConfig(ctx, ref, "test", "eu-west-1")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(callSTS(ctx, cfg)).To(Succeed())
+
+			timeout, cancel := context.WithTimeout(ctx, 100*time.Millisecond)
+			defer cancel()
+			Expect(callSTS(timeout, cfg)).To(HaveOccurred())
+			Expect(requests.Load()).To(Equal(int32(2)))
+		})
+	})
+})
+
+// gaugeValue returns the value of a gauge with the given labels registered with the controller-runtime metrics
+// registry.
+func gaugeValue(name string, labels map[string]string) float64 {
+	gauge, ok := findGauge(name, labels)
+	if !ok {
+		Fail(fmt.Sprintf("gauge %s with labels %v is not registered", name, labels))
+	}
+	return gauge.GetGauge().GetValue()
+}
+
+// gaugeRegistered returns true if a gauge with the given labels is registered with the controller-runtime metrics
+// registry.
+func gaugeRegistered(name string, labels map[string]string) bool {
+	_, ok := findGauge(name, labels)
+	return ok
+}
+
+func findGauge(name string, labels map[string]string) (*dto.Metric, bool) {
+	families, err := metrics.Registry.Gather()
+	Expect(err).NotTo(HaveOccurred())
+	for _, family := range families {
+		if family.GetName() != name {
+			continue
+		}
+		for _, metric := range family.GetMetric() {
+			matching := 0
+			for _, label := range metric.GetLabel() {
+				if value, ok := labels[label.GetName()]; ok && value == label.GetValue() {
+					matching++
+				}
+			}
+			if matching == len(labels) {
+				return metric, true
+			}
+		}
+	}
+	return nil, false
+}
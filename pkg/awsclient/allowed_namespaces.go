@@ -0,0 +1,58 @@
+package awsclient
+
+import (
+	"context"
+	"slices"
+
+	"github.com/giantswarm/microerror"
+	"github.com/google/go-cmp/cmp"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	capa "sigs.k8s.io/cluster-api-provider-aws/v2/api/v1beta2"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// ensureNamespaceAllowed returns an error asserted by IsIdentityNotAllowed if clusters in the namespace may not use
+// the identity.
+func (a *AwsClient) ensureNamespaceAllowed(ctx context.Context, identityRef *capa.AWSIdentityReference, allowed *capa.AllowedNamespaces, namespace string) error {
+	ok, err := a.isNamespaceAllowed(ctx, allowed, namespace)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+	if !ok {
+		return microerror.Maskf(identityNotAllowedError, "namespace %q is not allowed to use %s %q", namespace, identityRef.Kind, identityRef.Name)
+	}
+	return nil
+}
+
+// isNamespaceAllowed checks the allowed namespaces of an identity the way CAPA does: nil allows no namespace, an empty
+// value allows all namespaces, and otherwise the namespace has to be listed or match the selector. An empty selector
+// matches no namespace.
+func (a *AwsClient) isNamespaceAllowed(ctx context.Context, allowed *capa.AllowedNamespaces, namespace string) (bool, error) {
+	if allowed == nil {
+		return false, nil
+	}
+	if cmp.Equal(*allowed, capa.AllowedNamespaces{}) {
+		return true, nil
+	}
+	if slices.Contains(allowed.NamespaceList, namespace) {
+		return true, nil
+	}
+
+	selector, err := metav1.LabelSelectorAsSelector(&allowed.Selector)
+	if err != nil {
+		return false, microerror.Mask(err)
+	}
+	if selector.Empty() {
+		return false, nil
+	}
+
+	ns := &corev1.Namespace{}
+	err = a.ctrlClient.Get(ctx, client.ObjectKey{Name: namespace}, ns)
+	if err != nil {
+		return false, microerror.Mask(err)
+	}
+
+	return selector.Matches(labels.Set(ns.Labels)), nil
+}
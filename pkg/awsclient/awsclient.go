@@ -5,26 +5,59 @@ import (
 	"errors"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
-	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/credentials/stscreds"
 	"github.com/aws/aws-sdk-go-v2/service/sts"
 	"github.com/giantswarm/microerror"
 	"github.com/go-logr/logr"
+	capa "sigs.k8s.io/cluster-api-provider-aws/v2/api/v1beta2"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
 type AwsClientInterface interface {
-	GetAWSClientConfig(awsRoleARN string, region string) (aws.Config, error)
+	// GetAWSClientConfig returns the configuration of AWS clients that act as the given CAPA identity in the region.
+	// A nil identity reference means the controller identity, like in CAPA. Clusters in the namespace must be allowed
+	// to use the identity, otherwise an error asserted by IsIdentityNotAllowed is returned before any AWS call. The
+	// clients call AWS within the limits of the identity's account; while calls to the account are paused, an error
+	// asserted by IsCircuitOpen is returned.
+	GetAWSClientConfig(ctx context.Context, identityRef *capa.AWSIdentityReference, namespace string, region string) (aws.Config, error)
+	// GetAWSAccountID returns the ID of the AWS account of the identity. cfg is the configuration returned by
+	// GetAWSClientConfig for the identity.
+	GetAWSAccountID(ctx context.Context, identityRef *capa.AWSIdentityReference, cfg aws.Config) (string, error)
+}
+
+// STSClient is the part of the STS API used to assume roles and to look up the account of credentials.
+type STSClient interface {
+	stscreds.AssumeRoleAPIClient
+	GetCallerIdentity(ctx context.Context, params *sts.GetCallerIdentityInput, optFns ...func(*sts.Options)) (*sts.GetCallerIdentityOutput, error)
 }
 
 type AWSClientConfig struct {
 	CtrlClient client.Client
 	Log        logr.Logger
+
+	// StaticIdentitySecretNamespace is the namespace of the Secrets referenced by AWSClusterStaticIdentities, which
+	// is the namespace of the CAPA controller.
+	StaticIdentitySecretNamespace string
+	// STSClientFactory creates the STS clients. Nil means sts.NewFromConfig with the STS endpoint of Endpoints.
+	STSClientFactory func(cfg aws.Config) STSClient
+	// Endpoints overrides the endpoints of the STS clients created by default.
+	Endpoints Endpoints
+	// CredentialsCache keeps the credentials of assumed roles between calls and can be shared between clients. Nil
+	// means a cache of the client's own.
+	CredentialsCache *CredentialsCache
+	// AccountLimiter limits the calls to AWS accounts and can be shared between clients. Nil means a limiter of the
+	// client's own with the default limits.
+	AccountLimiter *AccountLimiter
 }
 
 type AwsClient struct {
 	ctrlClient client.Client
 	log        logr.Logger
+
+	staticIdentitySecretNamespace string
+	stsClientFactory              func(cfg aws.Config) STSClient
+	credentialsCache              *CredentialsCache
+	accountLimiter                *AccountLimiter
 }
 
 func New(config AWSClientConfig) (*AwsClient, error) {
@@ -35,29 +68,83 @@ func New(config AWSClientConfig) (*AwsClient, error) {
 	a := &AwsClient{
 		ctrlClient: config.CtrlClient,
 		log:        config.Log,
+
+		staticIdentitySecretNamespace: config.StaticIdentitySecretNamespace,
+		stsClientFactory:              config.STSClientFactory,
+		credentialsCache:              config.CredentialsCache,
+		accountLimiter:                config.AccountLimiter,
+	}
+	if a.stsClientFactory == nil {
+		a.stsClientFactory = func(cfg aws.Config) STSClient {
+			return sts.NewFromConfig(cfg, config.Endpoints.STSOptions(cfg.Region))
+		}
+	}
+	if a.credentialsCache == nil {
+		a.credentialsCache = NewCredentialsCache()
+	}
+	if a.accountLimiter == nil {
+		a.accountLimiter = NewAccountLimiter(DefaultAccountLimits())
 	}
 
 	return a, nil
 }
 
-// GetAWSClientConfig doesn't use the receiver argument, so I don't know why this is a method.
-func (a *AwsClient) GetAWSClientConfig(awsRoleARN string, region string) (aws.Config, error) {
-	// Initial credentials loaded from SDK's default credential chain. Such as
-	// the environment, shared credentials (~/.aws/credentials), or EC2 Instance
-	// Role. These credentials will be used to to make the STS Assume Role API.
-	cfg, err := config.LoadDefaultConfig(context.Background(),
-		config.WithRegion(region),
-	)
+func (a *AwsClient) GetAWSClientConfig(ctx context.Context, identityRef *capa.AWSIdentityReference, namespace string, region string) (aws.Config, error) {
+	cfg, err := a.credentialsCache.baseConfig(ctx, region)
 	if err != nil {
 		return aws.Config{}, microerror.Mask(err)
 	}
 
-	// Create the credentials from AssumeRoleProvider to assume the role
-	// referenced by the "awsRoleARN" ARN.
-	stsSvc := sts.NewFromConfig(cfg)
-	creds := stscreds.NewAssumeRoleProvider(stsSvc, awsRoleARN)
+	identityRef = identityRefOrDefault(identityRef)
+	creds, chain, err := a.credentialsProvider(ctx, identityRef, namespace, cfg, nil)
+	if err != nil {
+		return aws.Config{}, microerror.Mask(err)
+	}
+	cfg.Credentials = creds
 
-	cfg.Credentials = aws.NewCredentialsCache(creds)
+	accountID, err := a.accountID(ctx, identityRef, cfg)
+	if err != nil {
+		return aws.Config{}, microerror.Mask(err)
+	}
+	identity := credentialsCacheKey(chain, region)
+	err = a.accountLimiter.check(accountID, identity)
+	if err != nil {
+		return aws.Config{}, microerror.Mask(err)
+	}
+	a.accountLimiter.apply(&cfg, accountID, identity)
 
 	return cfg, nil
 }
+
+func (a *AwsClient) GetAWSAccountID(ctx context.Context, identityRef *capa.AWSIdentityReference, cfg aws.Config) (string, error) {
+	return a.accountID(ctx, identityRefOrDefault(identityRef), cfg)
+}
+
+// accountID returns the ID of the AWS account of the identity. Accounts looked up with STS are cached per access key.
+func (a *AwsClient) accountID(ctx context.Context, identityRef *capa.AWSIdentityReference, cfg aws.Config) (string, error) {
+	// the account of a role identity is part of its ARN, which saves an API call
+	if identityRef.Kind == capa.ClusterRoleIdentityKind {
+		identity, err := a.getRoleIdentity(ctx, identityRef.Name)
+		if err != nil {
+			return "", microerror.Mask(err)
+		}
+		return accountIDFromRoleARN(identity.Spec.RoleArn)
+	}
+
+	creds, err := cfg.Credentials.Retrieve(ctx)
+	if err != nil {
+		return "", microerror.Mask(err)
+	}
+	if accountID, ok := a.credentialsCache.accountID(creds.AccessKeyID); ok {
+		return accountID, nil
+	}
+
+	o, err := a.stsClientFactory(cfg).GetCallerIdentity(ctx, &sts.GetCallerIdentityInput{})
+	if err != nil {
+		a.log.Error(err, "failed to get caller identity", "identity_kind", identityRef.Kind, "identity_name", identityRef.Name)
+		return "", microerror.Mask(err)
+	}
+	a.credentialsCache.addAccountID(creds.AccessKeyID, aws.ToString(o.Account))
+
+	return aws.ToString(o.Account), nil
+}
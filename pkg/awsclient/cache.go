@@ -0,0 +1,243 @@
+package awsclient
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/giantswarm/microerror"
+	toolscache "k8s.io/client-go/tools/cache"
+	capa "sigs.k8s.io/cluster-api-provider-aws/v2/api/v1beta2"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+const (
+	// credentialsExpiryWindow is how long before their expiry the credentials of assumed roles are refreshed, so
+	// that reconciliations never run with credentials that expire halfway through.
+	credentialsExpiryWindow = 5 * time.Minute
+	// credentialsExpiryWindowJitterFrac spreads the refreshes of roles assumed at the same time, e.g. after a
+	// restart of the operator.
+	credentialsExpiryWindowJitterFrac = 0.2
+	// idleTimeout is how long cache entries and the circuit breakers of identity chains are kept without being used.
+	// Chains stop being used once one of their identities changes without the cache being told, e.g. when the
+	// Secret of a static identity is rotated.
+	idleTimeout = time.Hour
+	// idleEvictionInterval is how often at most idle entries are looked for.
+	idleEvictionInterval = 10 * time.Minute
+)
+
+// identityLink is an identity in a chain of identities, from the identity of a cluster to the identity whose
+// credentials are used to assume the first role. version changes whenever the credentials of the identity may
+// change, which keeps a cache entry from outliving the identity it was created for.
+type identityLink struct {
+	kind    capa.AWSIdentityKind
+	name    string
+	version string
+}
+
+func (l identityLink) String() string {
+	return string(l.kind) + "/" + l.name + "@" + l.version
+}
+
+type credentialsCacheEntry struct {
+	chain       []identityLink
+	credentials aws.CredentialsProvider
+	lastUsed    time.Time
+}
+
+type accountIDCacheEntry struct {
+	accountID string
+	lastUsed  time.Time
+}
+
+// CredentialsCache keeps the credentials of assumed roles per identity chain and region, so that reconciliations
+// reuse role sessions instead of assuming the role again, the default configuration of the operator per region and
+// the accounts of access keys. It can be shared by several AwsClients. Entries that have not been used for an hour are
+// dropped.
+//
+// The IAM and EKS clients themselves are not cached: creating them from a cached configuration is cheap, and the
+// clients of an account share its rate limiter and retryer anyway.
+type CredentialsCache struct {
+	now func() time.Time
+
+	mu           sync.Mutex
+	entries      map[string]credentialsCacheEntry
+	baseConfigs  map[string]aws.Config
+	accountIDs   map[string]accountIDCacheEntry
+	lastEviction time.Time
+}
+
+func NewCredentialsCache() *CredentialsCache {
+	return &CredentialsCache{
+		now:         time.Now,
+		entries:     map[string]credentialsCacheEntry{},
+		baseConfigs: map[string]aws.Config{},
+		accountIDs:  map[string]accountIDCacheEntry{},
+	}
+}
+
+// SetupWithManager drops the cached credentials of AWSClusterRoleIdentities once they are changed or deleted.
+func (c *CredentialsCache) SetupWithManager(ctx context.Context, mgr ctrl.Manager) error {
+	informer, err := mgr.GetCache().GetInformer(ctx, &capa.AWSClusterRoleIdentity{})
+	if err != nil {
+		return microerror.Mask(err)
+	}
+	_, err = informer.AddEventHandler(toolscache.ResourceEventHandlerFuncs{
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			oldIdentity, ok := oldObj.(*capa.AWSClusterRoleIdentity)
+			if !ok {
+				return
+			}
+			newIdentity, ok := newObj.(*capa.AWSClusterRoleIdentity)
+			if !ok || oldIdentity.ResourceVersion == newIdentity.ResourceVersion {
+				return
+			}
+			c.Invalidate(capa.ClusterRoleIdentityKind, oldIdentity.Name)
+		},
+		DeleteFunc: func(obj interface{}) {
+			if tombstone, ok := obj.(toolscache.DeletedFinalStateUnknown); ok {
+				obj = tombstone.Obj
+			}
+			identity, ok := obj.(*capa.AWSClusterRoleIdentity)
+			if !ok {
+				return
+			}
+			c.Invalidate(capa.ClusterRoleIdentityKind, identity.Name)
+		},
+	})
+	return microerror.Mask(err)
+}
+
+// Invalidate drops the cached credentials of all identity chains containing the identity.
+func (c *CredentialsCache) Invalidate(kind capa.AWSIdentityKind, name string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for key, entry := range c.entries {
+		for _, link := range entry.chain {
+			if link.kind == kind && link.name == name {
+				delete(c.entries, key)
+				break
+			}
+		}
+	}
+}
+
+func (c *CredentialsCache) get(chain []identityLink, region string) (aws.CredentialsProvider, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.now()
+	c.evictIdle(now)
+	key := credentialsCacheKey(chain, region)
+	entry, ok := c.entries[key]
+	if !ok {
+		credentialsCacheMissesTotal.Inc()
+		return nil, false
+	}
+	credentialsCacheHitsTotal.Inc()
+	entry.lastUsed = now
+	c.entries[key] = entry
+	return entry.credentials, true
+}
+
+func (c *CredentialsCache) add(chain []identityLink, region string, credentials aws.CredentialsProvider) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.entries[credentialsCacheKey(chain, region)] = credentialsCacheEntry{
+		chain:       chain,
+		credentials: credentials,
+		lastUsed:    c.now(),
+	}
+}
+
+// evictIdle drops the entries that have not been used within idleTimeout, looking for them at most once per
+// idleEvictionInterval. The caller must hold the lock.
+func (c *CredentialsCache) evictIdle(now time.Time) {
+	if now.Sub(c.lastEviction) < idleEvictionInterval {
+		return
+	}
+	c.lastEviction = now
+
+	for key, entry := range c.entries {
+		if now.Sub(entry.lastUsed) >= idleTimeout {
+			delete(c.entries, key)
+		}
+	}
+	for accessKeyID, entry := range c.accountIDs {
+		if now.Sub(entry.lastUsed) >= idleTimeout {
+			delete(c.accountIDs, accessKeyID)
+		}
+	}
+}
+
+// baseConfig returns the default configuration of the operator in the region, loading it only once. Its
+// credentials refresh themselves.
+func (c *CredentialsCache) baseConfig(ctx context.Context, region string) (aws.Config, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	cfg, ok := c.baseConfigs[region]
+	if !ok {
+		var err error
+		// Initial credentials loaded from SDK's default credential chain. Such as
+		// the environment, shared credentials (~/.aws/credentials), or EC2 Instance
+		// Role. These are the credentials of the controller identity and are used
+		// to make the STS Assume Role API calls of role identities.
+		cfg, err = config.LoadDefaultConfig(ctx,
+			config.WithRegion(region),
+		)
+		if err != nil {
+			return aws.Config{}, microerror.Mask(err)
+		}
+		c.baseConfigs[region] = cfg
+	}
+
+	return cfg.Copy(), nil
+}
+
+// accountID returns the account of an access key. Unlike roles, the account of an access key never changes.
+func (c *CredentialsCache) accountID(accessKeyID string) (string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.now()
+	c.evictIdle(now)
+	entry, ok := c.accountIDs[accessKeyID]
+	if !ok {
+		return "", false
+	}
+	entry.lastUsed = now
+	c.accountIDs[accessKeyID] = entry
+	return entry.accountID, true
+}
+
+func (c *CredentialsCache) addAccountID(accessKeyID string, accountID string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.accountIDs[accessKeyID] = accountIDCacheEntry{
+		accountID: accountID,
+		lastUsed:  c.now(),
+	}
+}
+
+func credentialsCacheKey(chain []identityLink, region string) string {
+	links := make([]string, 0, len(chain))
+	for _, link := range chain {
+		links = append(links, link.String())
+	}
+	return region + ":" + strings.Join(links, ",")
+}
+
+// newCachedCredentials wraps credentials that expire, refreshing them ahead of their expiry.
+func newCachedCredentials(provider aws.CredentialsProvider) *aws.CredentialsCache {
+	return aws.NewCredentialsCache(provider, func(o *aws.CredentialsCacheOptions) {
+		o.ExpiryWindow = credentialsExpiryWindow
+		o.ExpiryWindowJitterFrac = credentialsExpiryWindowJitterFrac
+	})
+}
@@ -0,0 +1,75 @@
+package awsclient
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/eks"
+	"github.com/aws/aws-sdk-go-v2/service/iam"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+)
+
+// EndpointRegionPlaceholder is replaced with the region of the client in endpoint overrides, which allows regional
+// endpoints like https://sts.{region}.example.com.
+const EndpointRegionPlaceholder = "{region}"
+
+// Endpoints overrides the endpoints of the AWS services called by the operator, e.g. to run it against LocalStack or
+// moto. Empty endpoints are resolved by the AWS SDK; STS then uses the regional endpoint of the client's region.
+type Endpoints struct {
+	IAM string
+	STS string
+	EKS string
+}
+
+// Validate returns an error if an endpoint is not an absolute HTTP or HTTPS URL.
+func (e Endpoints) Validate() error {
+	for service, endpoint := range map[string]string{"IAM": e.IAM, "STS": e.STS, "EKS": e.EKS} {
+		if endpoint == "" {
+			continue
+		}
+		u, err := url.Parse(resolveEndpoint(endpoint, "eu-west-1"))
+		if err != nil {
+			return fmt.Errorf("invalid %s endpoint %q: %w", service, endpoint, err)
+		}
+		if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("invalid %s endpoint %q, expected an absolute http or https URL", service, endpoint)
+		}
+	}
+	return nil
+}
+
+// IAMOptions configures IAM clients of the region.
+func (e Endpoints) IAMOptions(region string) func(*iam.Options) {
+	return func(o *iam.Options) {
+		o.Region = region
+		if e.IAM != "" {
+			o.BaseEndpoint = aws.String(resolveEndpoint(e.IAM, region))
+		}
+	}
+}
+
+// STSOptions configures STS clients of the region.
+func (e Endpoints) STSOptions(region string) func(*sts.Options) {
+	return func(o *sts.Options) {
+		o.Region = region
+		if e.STS != "" {
+			o.BaseEndpoint = aws.String(resolveEndpoint(e.STS, region))
+		}
+	}
+}
+
+// EKSOptions configures EKS clients of the region.
+func (e Endpoints) EKSOptions(region string) func(*eks.Options) {
+	return func(o *eks.Options) {
+		o.Region = region
+		if e.EKS != "" {
+			o.BaseEndpoint = aws.String(resolveEndpoint(e.EKS, region))
+		}
+	}
+}
+
+func resolveEndpoint(endpoint string, region string) string {
+	return strings.ReplaceAll(endpoint, EndpointRegionPlaceholder, region)
+}
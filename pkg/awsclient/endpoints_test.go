@@ -0,0 +1,99 @@
+package awsclient_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/iam"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	capa "sigs.k8s.io/cluster-api-provider-aws/v2/api/v1beta2"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/giantswarm/capa-iam-operator/v3/pkg/awsclient"
+)
+
+var _ = Describe("Endpoints", func() {
+	DescribeTable("Validate",
+		func(endpoints awsclient.Endpoints, valid bool) {
+			err := endpoints.Validate()
+			if valid {
+				Expect(err).NotTo(HaveOccurred())
+			} else {
+				Expect(err).To(HaveOccurred())
+			}
+		},
+		Entry("no overrides", awsclient.Endpoints{}, true),
+		Entry("local emulator", awsclient.Endpoints{IAM: "http://localhost:4566", STS: "http://localhost:4566", EKS: "http://localhost:4566"}, true),
+		Entry("regional endpoint", awsclient.Endpoints{STS: "https://sts.{region}.example.com"}, true),
+		Entry("missing scheme", awsclient.Endpoints{IAM: "localhost:4566"}, false),
+		Entry("unsupported scheme", awsclient.Endpoints{EKS: "ftp://localhost"}, false),
+		Entry("relative URL", awsclient.Endpoints{STS: "/sts"}, false),
+	)
+
+	It("sets the region and the endpoint of the region on clients", func() {
+		endpoints := awsclient.Endpoints{IAM: "https://iam.{region}.example.com"}
+		o := iam.Options{}
+		endpoints.IAMOptions("cn-north-1")(&o)
+		Expect(o.Region).To(Equal("cn-north-1"))
+		Expect(aws.ToString(o.BaseEndpoint)).To(Equal("https://iam.cn-north-1.example.com"))
+
+		o = iam.Options{}
+		awsclient.Endpoints{}.IAMOptions("eu-west-1")(&o)
+		Expect(o.BaseEndpoint).To(BeNil())
+	})
+
+	It("calls STS through the overridden endpoint", func() {
+		var requests int
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requests++
+			w.Header().Set("Content-Type", "text/xml")
+			_, _ = w.Write([]byte(`<GetCallerIdentityResponse xmlns="https://sts.amazonaws.com/doc/2011-06-15/">
+  <GetCallerIdentityResult>
+    <Arn>arn:aws:iam::123456789012:user/emulator</Arn>
+    <UserId>AIDAEXAMPLE</UserId>
+    <Account>123456789012</Account>
+  </GetCallerIdentityResult>
+  <ResponseMetadata><RequestId>1</RequestId></ResponseMetadata>
+</GetCallerIdentityResponse>`))
+		}))
+		defer server.Close()
+
+		ctrlClient := fake.NewClientBuilder().WithScheme(newScheme()).WithObjects(
+			&capa.AWSClusterStaticIdentity{
+				ObjectMeta: metav1.ObjectMeta{Name: "static"},
+				Spec: capa.AWSClusterStaticIdentitySpec{
+					AWSClusterIdentitySpec: capa.AWSClusterIdentitySpec{AllowedNamespaces: &capa.AllowedNamespaces{}},
+					SecretRef:              "static-credentials",
+				},
+			},
+			&corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: "static-credentials", Namespace: "capa-system"},
+				Data: map[string][]byte{
+					"AccessKeyID":     []byte("test"),
+					"SecretAccessKey": []byte("test"),
+				},
+			},
+		).Build()
+		awsClient, err := awsclient.New(awsclient.AWSClientConfig{
+			CtrlClient:                    ctrlClient,
+			Log:                           ctrl.Log,
+			StaticIdentitySecretNamespace: "capa-system",
+			Endpoints:                     awsclient.Endpoints{STS: server.URL},
+		})
+		Expect(err).NotTo(HaveOccurred())
+
+		ref := &capa.AWSIdentityReference{Kind: capa.ClusterStaticIdentityKind, Name: "static"}
+		cfg, err := awsClient.GetAWSClientConfig(context.TODO(), ref, "test", "eu-west-1")
+		Expect(err).NotTo(HaveOccurred())
+		accountID, err := awsClient.GetAWSAccountID(context.TODO(), ref, cfg)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(accountID).To(Equal("123456789012"))
+		Expect(requests).To(Equal(1))
+	})
+})
@@ -0,0 +1,60 @@
+package awsclient
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/giantswarm/microerror"
+)
+
+var invalidIdentityError = &microerror.Error{
+	Kind: "invalidIdentityError",
+}
+
+// IsInvalidIdentity asserts invalidIdentityError.
+func IsInvalidIdentity(err error) bool {
+	return errors.Is(err, invalidIdentityError)
+}
+
+var identityNotAllowedError = &microerror.Error{
+	Kind: "identityNotAllowedError",
+}
+
+// IsIdentityNotAllowed asserts identityNotAllowedError.
+func IsIdentityNotAllowed(err error) bool {
+	return errors.Is(err, identityNotAllowedError)
+}
+
+// CircuitOpenError is returned instead of calling an AWS account whose circuit breaker, or the circuit breaker of the
+// calling identity, is open.
+type CircuitOpenError struct {
+	AccountID string
+	// Identity is the identity chain whose calls are paused. Empty means all calls to the account are paused.
+	Identity string
+	// RetryAt is when the account may be called again.
+	RetryAt time.Time
+}
+
+func (e *CircuitOpenError) Error() string {
+	if e.Identity != "" {
+		return fmt.Sprintf("calls of identity %s to AWS account %s are paused after repeated AccessDenied errors until %s", e.Identity, e.AccountID, e.RetryAt.Format(time.RFC3339))
+	}
+	return fmt.Sprintf("calls to AWS account %s are paused after repeated throttling errors until %s", e.AccountID, e.RetryAt.Format(time.RFC3339))
+}
+
+// IsCircuitOpen asserts CircuitOpenError.
+func IsCircuitOpen(err error) bool {
+	var circuitOpenErr *CircuitOpenError
+	return errors.As(err, &circuitOpenErr)
+}
+
+// CircuitRetryAfter returns how long to wait before calling the account of a CircuitOpenError again, or zero if err
+// is not one.
+func CircuitRetryAfter(err error) time.Duration {
+	var circuitOpenErr *CircuitOpenError
+	if !errors.As(err, &circuitOpenErr) {
+		return 0
+	}
+	return max(time.Until(circuitOpenErr.RetryAt), time.Second)
+}
@@ -0,0 +1,13 @@
+package awsclient
+
+import "time"
+
+// SetClock makes the cache tell the age of its entries by the given clock.
+func (c *CredentialsCache) SetClock(now func() time.Time) {
+	c.now = now
+}
+
+// SetClock makes the limiter and its circuit breakers tell the time by the given clock.
+func (l *AccountLimiter) SetClock(now func() time.Time) {
+	l.now = now
+}
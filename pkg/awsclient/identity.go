@@ -0,0 +1,164 @@
+package awsclient
+
+import (
+	"context"
+	"slices"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsarn "github.com/aws/aws-sdk-go-v2/aws/arn"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/credentials/stscreds"
+	ststypes "github.com/aws/aws-sdk-go-v2/service/sts/types"
+	"github.com/giantswarm/microerror"
+	corev1 "k8s.io/api/core/v1"
+	capa "sigs.k8s.io/cluster-api-provider-aws/v2/api/v1beta2"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// DefaultSessionName is the name of the role sessions of AWSClusterRoleIdentities without session name, which makes
+// the calls of the operator recognisable in CloudTrail.
+const DefaultSessionName = "capa-iam-operator"
+
+// Keys of the Secrets referenced by AWSClusterStaticIdentities, as defined by CAPA.
+const (
+	staticIdentityAccessKeyIDKey     = "AccessKeyID"
+	staticIdentitySecretAccessKeyKey = "SecretAccessKey"
+	staticIdentitySessionTokenKey    = "SessionToken"
+)
+
+// identityRefOrDefault returns the identity reference, or the controller identity CAPA defaults to if there is none.
+func identityRefOrDefault(identityRef *capa.AWSIdentityReference) *capa.AWSIdentityReference {
+	if identityRef == nil {
+		return &capa.AWSIdentityReference{
+			Kind: capa.ControllerIdentityKind,
+			Name: capa.AWSClusterControllerIdentityName,
+		}
+	}
+	return identityRef
+}
+
+// credentialsProvider returns the credentials of the identity for clusters in the namespace, and the chain of
+// identities they depend on. baseCfg holds the credentials of the operator itself, which stand in for the CAPA
+// controller identity. Like in CAPA, the role of an AWSClusterRoleIdentity is assumed with the credentials of its
+// source identity, which may be another role identity; visited holds the names of the role identities that are
+// already part of the chain. Credentials of assumed roles are cached per chain and region.
+func (a *AwsClient) credentialsProvider(ctx context.Context, identityRef *capa.AWSIdentityReference, namespace string, baseCfg aws.Config, visited []string) (aws.CredentialsProvider, []identityLink, error) {
+	switch identityRef.Kind {
+	case capa.ControllerIdentityKind:
+		// like CAPA, only the singleton controller identity is accepted
+		if identityRef.Name != capa.AWSClusterControllerIdentityName {
+			return nil, nil, microerror.Maskf(invalidIdentityError, "AWSClusterControllerIdentity must be named %q, got %q", capa.AWSClusterControllerIdentityName, identityRef.Name)
+		}
+		identity := &capa.AWSClusterControllerIdentity{}
+		err := a.ctrlClient.Get(ctx, client.ObjectKey{Name: identityRef.Name}, identity)
+		if err != nil {
+			return nil, nil, microerror.Mask(err)
+		}
+		err = a.ensureNamespaceAllowed(ctx, identityRef, identity.Spec.AllowedNamespaces, namespace)
+		if err != nil {
+			return nil, nil, microerror.Mask(err)
+		}
+		// the credentials of the operator are the same for every version of the identity
+		return baseCfg.Credentials, []identityLink{{kind: identityRef.Kind, name: identityRef.Name}}, nil
+
+	case capa.ClusterStaticIdentityKind:
+		identity := &capa.AWSClusterStaticIdentity{}
+		err := a.ctrlClient.Get(ctx, client.ObjectKey{Name: identityRef.Name}, identity)
+		if err != nil {
+			return nil, nil, microerror.Mask(err)
+		}
+		err = a.ensureNamespaceAllowed(ctx, identityRef, identity.Spec.AllowedNamespaces, namespace)
+		if err != nil {
+			return nil, nil, microerror.Mask(err)
+		}
+		secret := &corev1.Secret{}
+		err = a.ctrlClient.Get(ctx, client.ObjectKey{Namespace: a.staticIdentitySecretNamespace, Name: identity.Spec.SecretRef}, secret)
+		if err != nil {
+			return nil, nil, microerror.Mask(err)
+		}
+		accessKeyID := string(secret.Data[staticIdentityAccessKeyIDKey])
+		secretAccessKey := string(secret.Data[staticIdentitySecretAccessKeyKey])
+		if accessKeyID == "" || secretAccessKey == "" {
+			return nil, nil, microerror.Maskf(invalidIdentityError, "Secret %s/%s of AWSClusterStaticIdentity %q lacks %s or %s", secret.Namespace, secret.Name, identity.Name, staticIdentityAccessKeyIDKey, staticIdentitySecretAccessKeyKey)
+		}
+		// roles assumed with the credentials must be assumed again once the Secret is rotated
+		chain := []identityLink{{kind: identityRef.Kind, name: identityRef.Name, version: secret.ResourceVersion}}
+		return credentials.NewStaticCredentialsProvider(accessKeyID, secretAccessKey, string(secret.Data[staticIdentitySessionTokenKey])), chain, nil
+
+	case capa.ClusterRoleIdentityKind:
+		if slices.Contains(visited, identityRef.Name) {
+			return nil, nil, microerror.Maskf(invalidIdentityError, "AWSClusterRoleIdentity %q is its own source identity", identityRef.Name)
+		}
+		identity, err := a.getRoleIdentity(ctx, identityRef.Name)
+		if err != nil {
+			return nil, nil, microerror.Mask(err)
+		}
+		err = a.ensureNamespaceAllowed(ctx, identityRef, identity.Spec.AllowedNamespaces, namespace)
+		if err != nil {
+			return nil, nil, microerror.Mask(err)
+		}
+
+		sourceCfg := baseCfg.Copy()
+		sourceChain := []identityLink{{kind: capa.ControllerIdentityKind, name: capa.AWSClusterControllerIdentityName}}
+		if identity.Spec.SourceIdentityRef != nil {
+			sourceCfg.Credentials, sourceChain, err = a.credentialsProvider(ctx, identity.Spec.SourceIdentityRef, namespace, baseCfg, append(visited, identityRef.Name))
+			if err != nil {
+				return nil, nil, microerror.Mask(err)
+			}
+		}
+		chain := append([]identityLink{{kind: identityRef.Kind, name: identityRef.Name, version: identity.ResourceVersion}}, sourceChain...)
+
+		if creds, ok := a.credentialsCache.get(chain, baseCfg.Region); ok {
+			return creds, chain, nil
+		}
+
+		// Create the credentials from AssumeRoleProvider to assume the role
+		// referenced by the identity.
+		creds := newCachedCredentials(stscreds.NewAssumeRoleProvider(a.stsClientFactory(sourceCfg), identity.Spec.RoleArn, assumeRoleOptions(identity.Spec)))
+		a.credentialsCache.add(chain, baseCfg.Region, creds)
+		return creds, chain, nil
+
+	default:
+		return nil, nil, microerror.Maskf(invalidIdentityError, "unknown identity kind %q", identityRef.Kind)
+	}
+}
+
+// assumeRoleOptions sets the session parameters of a role identity on the AssumeRole calls.
+func assumeRoleOptions(spec capa.AWSClusterRoleIdentitySpec) func(*stscreds.AssumeRoleOptions) {
+	return func(o *stscreds.AssumeRoleOptions) {
+		o.RoleSessionName = spec.SessionName
+		if o.RoleSessionName == "" {
+			o.RoleSessionName = DefaultSessionName
+		}
+		if spec.ExternalID != "" {
+			o.ExternalID = aws.String(spec.ExternalID)
+		}
+		if spec.DurationSeconds > 0 {
+			o.Duration = time.Duration(spec.DurationSeconds) * time.Second
+		}
+		if spec.InlinePolicy != "" {
+			o.Policy = aws.String(spec.InlinePolicy)
+		}
+		for _, arn := range spec.PolicyARNs {
+			o.PolicyARNs = append(o.PolicyARNs, ststypes.PolicyDescriptorType{Arn: aws.String(arn)})
+		}
+	}
+}
+
+func (a *AwsClient) getRoleIdentity(ctx context.Context, name string) (*capa.AWSClusterRoleIdentity, error) {
+	identity := &capa.AWSClusterRoleIdentity{}
+	err := a.ctrlClient.Get(ctx, client.ObjectKey{Name: name}, identity)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+	return identity, nil
+}
+
+func accountIDFromRoleARN(roleARN string) (string, error) {
+	a, err := awsarn.Parse(roleARN)
+	if err != nil {
+		return "", microerror.Mask(err)
+	}
+	return a.AccountID, nil
+}
@@ -0,0 +1,49 @@
+package awsclient
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+const metricsNamespace = "capa_iam_operator"
+
+var credentialsCacheHitsTotal = prometheus.NewCounter(
+	prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "credentials_cache_hits_total",
+		Help:      "Number of times the credentials of an assumed role were taken from the credentials cache.",
+	},
+)
+
+var credentialsCacheMissesTotal = prometheus.NewCounter(
+	prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "credentials_cache_misses_total",
+		Help:      "Number of times the credentials of an assumed role were not found in the credentials cache and a new role session was set up.",
+	},
+)
+
+var circuitBreakerState = prometheus.NewGaugeVec(
+	prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "aws_account_circuit_breaker_state",
+		Help:      "State of the circuit breakers of an AWS account, counting throttling errors of all calls to the account (empty identity) or AccessDenied errors of the calls of an identity chain: closed (0), half-open (1) or open (2). Calls covered by an open circuit breaker are paused.",
+	},
+	[]string{"account_id", "identity"},
+)
+
+var circuitBreakerTripsTotal = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "aws_account_circuit_breaker_trips_total",
+		Help:      "Number of times calls to an AWS account were paused after repeated throttling errors (empty identity), or calls of an identity chain after repeated AccessDenied errors.",
+	},
+	[]string{"account_id", "identity"},
+)
+
+func init() {
+	metrics.Registry.MustRegister(credentialsCacheHitsTotal)
+	metrics.Registry.MustRegister(credentialsCacheMissesTotal)
+	metrics.Registry.MustRegister(circuitBreakerState)
+	metrics.Registry.MustRegister(circuitBreakerTripsTotal)
+}
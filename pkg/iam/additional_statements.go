@@ -0,0 +1,157 @@
+package iam
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/giantswarm/microerror"
+)
+
+const (
+	// maxInlinePolicySize and maxManagedPolicySize are the IAM quotas for policy documents, counted without whitespace.
+	maxInlinePolicySize  = 10240
+	maxManagedPolicySize = 6144
+	// maxTrustPolicySize is the highest quota trust policies can be raised to.
+	maxTrustPolicySize = 4096
+)
+
+// AdditionalPolicyStatements holds extra policy statements by role type that are added to the rendered policy of the
+// role.
+type AdditionalPolicyStatements map[string][]map[string]any
+
+// ParseAdditionalPolicyStatements reads additional policy statements from the data of a ConfigMap. Every key must be a
+// known role type and every value a JSON statement or list of statements.
+func ParseAdditionalPolicyStatements(data map[string]string) (AdditionalPolicyStatements, error) {
+	statements := AdditionalPolicyStatements{}
+
+	keys := make([]string, 0, len(data))
+	for k := range data {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, roleType := range keys {
+		if getTrustPolicyTemplate(roleType) == "" {
+			return nil, microerror.Maskf(invalidAdditionalStatementsError, "key %q is not a known role type", roleType)
+		}
+
+		parsed, err := parseStatements([]byte(data[roleType]))
+		if err != nil {
+			return nil, microerror.Maskf(invalidAdditionalStatementsError, "role type %q: %s", roleType, err)
+		}
+		for i, statement := range parsed {
+			err = validateStatement(statement)
+			if err != nil {
+				return nil, microerror.Maskf(invalidAdditionalStatementsError, "role type %q, statement %d: %s", roleType, i, err)
+			}
+		}
+
+		statements[roleType] = parsed
+	}
+
+	return statements, nil
+}
+
+// parseStatements accepts a single statement or a list of statements, like the Statement element of a policy.
+func parseStatements(data []byte) ([]map[string]any, error) {
+	var statements []map[string]any
+	if err := json.Unmarshal(data, &statements); err == nil {
+		return statements, nil
+	}
+
+	var statement map[string]any
+	if err := json.Unmarshal(data, &statement); err != nil {
+		return nil, fmt.Errorf("must be a JSON statement or list of statements")
+	}
+	return []map[string]any{statement}, nil
+}
+
+func validateStatement(statement map[string]any) error {
+	switch statement["Effect"] {
+	case "Allow", "Deny":
+	default:
+		return fmt.Errorf("Effect must be \"Allow\" or \"Deny\"")
+	}
+	if _, ok := statement["Action"]; !ok {
+		if _, ok := statement["NotAction"]; !ok {
+			return fmt.Errorf("either Action or NotAction must be set")
+		}
+	}
+	if _, ok := statement["Resource"]; !ok {
+		if _, ok := statement["NotResource"]; !ok {
+			return fmt.Errorf("either Resource or NotResource must be set")
+		}
+	}
+	// collisions with other statements are detected by statement ID, so it is required
+	if sid, _ := statement["Sid"].(string); sid == "" {
+		return fmt.Errorf("Sid must be a non-empty string")
+	}
+	return nil
+}
+
+// mergeStatements adds the statements to the policy document. It fails if a statement ID is used twice, if a statement
+// repeats one of the policy apart from its ID, or if the resulting document exceeds maxSize.
+func mergeStatements(policyDocument string, statements []map[string]any, maxSize int) (string, error) {
+	var policy map[string]any
+	err := json.Unmarshal([]byte(policyDocument), &policy)
+	if err != nil {
+		return "", err
+	}
+
+	var existing []any
+	switch s := policy["Statement"].(type) {
+	case []any:
+		existing = s
+	case map[string]any:
+		existing = []any{s}
+	}
+
+	sids := map[string]bool{}
+	contents := map[string]bool{}
+	for _, statement := range existing {
+		if m, ok := statement.(map[string]any); ok {
+			if sid, ok := m["Sid"].(string); ok && sid != "" {
+				sids[sid] = true
+			}
+			contents[statementContent(m)] = true
+		}
+	}
+
+	merged := existing
+	for _, statement := range statements {
+		sid, _ := statement["Sid"].(string)
+		if sids[sid] {
+			return "", microerror.Maskf(invalidAdditionalStatementsError, "statement ID %q is already used in the policy", sid)
+		}
+		if contents[statementContent(statement)] {
+			return "", microerror.Maskf(invalidAdditionalStatementsError, "statement %q repeats a statement of the policy", sid)
+		}
+		sids[sid] = true
+		contents[statementContent(statement)] = true
+		merged = append(merged, statement)
+	}
+	policy["Statement"] = merged
+
+	out, err := json.Marshal(policy)
+	if err != nil {
+		return "", err
+	}
+	if len(out) > maxSize {
+		return "", microerror.Maskf(invalidAdditionalStatementsError, "policy with additional statements has %d characters, exceeding the limit of %d", len(out), maxSize)
+	}
+
+	return string(out), nil
+}
+
+// statementContent returns a statement without its ID in canonical form, for comparison with other statements.
+func statementContent(statement map[string]any) string {
+	content := make(map[string]any, len(statement))
+	for k, v := range statement {
+		if k != "Sid" {
+			content[k] = v
+		}
+	}
+	out, _ := json.Marshal(content)
+	return string(out)
+}
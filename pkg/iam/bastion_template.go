@@ -13,7 +13,7 @@ const bastionPolicyTemplate = `{
         "s3:GetObjectVersion"
       ],
       "Resource": [
-        "arn:*:s3:::*-capa-*"
+        "arn:{{ .AWSPartition }}:s3:::*-capa-*"
       ],
       "Effect": "Allow"
     }
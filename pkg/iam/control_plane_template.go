@@ -148,7 +148,7 @@ const controlPlanePolicyTemplate = `{
         "secretsmanager:GetSecretValue",
         "secretsmanager:DeleteSecret"
       ],
-      "Resource": "arn:*:secretsmanager:*:*:secret:aws.cluster.x-k8s.io/*",
+      "Resource": "arn:{{ .AWSPartition }}:secretsmanager:*:*:secret:aws.cluster.x-k8s.io/*",
       "Effect": "Allow"
     }
   ]
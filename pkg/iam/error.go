@@ -2,8 +2,12 @@ package iam
 
 import (
 	"errors"
+	"time"
 
+	"github.com/aws/aws-sdk-go-v2/aws/retry"
+	ekstypes "github.com/aws/aws-sdk-go-v2/service/eks/types"
 	awsiamtypes "github.com/aws/aws-sdk-go-v2/service/iam/types"
+	"github.com/aws/smithy-go"
 	"github.com/giantswarm/microerror"
 )
 
@@ -11,6 +15,60 @@ var invalidClusterError = &microerror.Error{
 	Kind: "invalidClusterError",
 }
 
+var instanceProfileConflictError = &microerror.Error{
+	Kind: "instanceProfileConflictError",
+}
+
+// IsInstanceProfileConflict asserts instanceProfileConflictError.
+func IsInstanceProfileConflict(err error) bool {
+	return errors.Is(err, instanceProfileConflictError)
+}
+
+var managedPolicyConflictError = &microerror.Error{
+	Kind: "managedPolicyConflictError",
+}
+
+// IsManagedPolicyConflict asserts managedPolicyConflictError.
+func IsManagedPolicyConflict(err error) bool {
+	return errors.Is(err, managedPolicyConflictError)
+}
+
+var podIdentityAssociationConflictError = &microerror.Error{
+	Kind: "podIdentityAssociationConflictError",
+}
+
+// IsPodIdentityAssociationConflict asserts podIdentityAssociationConflictError.
+func IsPodIdentityAssociationConflict(err error) bool {
+	return errors.Is(err, podIdentityAssociationConflictError)
+}
+
+var invalidPolicyTemplateError = &microerror.Error{
+	Kind: "invalidPolicyTemplateError",
+}
+
+// IsInvalidPolicyTemplate asserts invalidPolicyTemplateError.
+func IsInvalidPolicyTemplate(err error) bool {
+	return errors.Is(err, invalidPolicyTemplateError)
+}
+
+var invalidAdditionalStatementsError = &microerror.Error{
+	Kind: "invalidAdditionalStatementsError",
+}
+
+// IsInvalidAdditionalStatements asserts invalidAdditionalStatementsError.
+func IsInvalidAdditionalStatements(err error) bool {
+	return errors.Is(err, invalidAdditionalStatementsError)
+}
+
+var invalidServiceAccountsError = &microerror.Error{
+	Kind: "invalidServiceAccountsError",
+}
+
+// IsInvalidServiceAccounts asserts invalidServiceAccountsError.
+func IsInvalidServiceAccounts(err error) bool {
+	return errors.Is(err, invalidServiceAccountsError)
+}
+
 func IsNotFound(err error) bool {
 	var nsee *awsiamtypes.NoSuchEntityException
 	return errors.As(err, &nsee)
@@ -20,3 +78,107 @@ func IsAlreadyExists(err error) bool {
 	var eaee *awsiamtypes.EntityAlreadyExistsException
 	return errors.As(err, &eaee)
 }
+
+func isEKSNotFound(err error) bool {
+	var rnfe *ekstypes.ResourceNotFoundException
+	return errors.As(err, &rnfe)
+}
+
+// ErrorClass classifies the errors of reconciliations by how they are retried.
+type ErrorClass string
+
+const (
+	// ErrorClassUnknown is the class of errors without special handling, which are retried with the backoff of
+	// controller-runtime.
+	ErrorClassUnknown                ErrorClass = "Unknown"
+	ErrorClassAccessDenied           ErrorClass = "AccessDenied"
+	ErrorClassLimitExceeded          ErrorClass = "LimitExceeded"
+	ErrorClassMalformedPolicy        ErrorClass = "MalformedPolicyDocument"
+	ErrorClassThrottling             ErrorClass = "Throttling"
+	ErrorClassConcurrentModification ErrorClass = "ConcurrentModification"
+	ErrorClassDeleteConflict         ErrorClass = "DeleteConflict"
+	ErrorClassInvalidInput           ErrorClass = "InvalidInput"
+	ErrorClassExpiredCredentials     ErrorClass = "ExpiredCredentials"
+)
+
+// ErrorBehaviour is how a reconciliation reacts to a class of errors.
+type ErrorBehaviour struct {
+	// Retry is false for errors that persist until the cluster, its annotations or its templates change, which
+	// trigger a reconciliation anyway. They are only requeued after RequeueAfter, to keep correcting drift.
+	Retry bool
+	// RequeueAfter is when the reconciliation is retried. Zero means the backoff of controller-runtime.
+	RequeueAfter time.Duration
+	// Report is true for errors users have to act on, which are reported on the cluster.
+	Report bool
+}
+
+var errorBehaviours = map[ErrorClass]ErrorBehaviour{
+	ErrorClassUnknown:                {Retry: true},
+	ErrorClassAccessDenied:           {Retry: true, RequeueAfter: 10 * time.Minute, Report: true},
+	ErrorClassLimitExceeded:          {Retry: true, RequeueAfter: 30 * time.Minute, Report: true},
+	ErrorClassMalformedPolicy:        {Retry: false, RequeueAfter: 30 * time.Minute, Report: true},
+	ErrorClassThrottling:             {Retry: true, RequeueAfter: time.Minute},
+	ErrorClassConcurrentModification: {Retry: true, RequeueAfter: 5 * time.Second},
+	ErrorClassDeleteConflict:         {Retry: true, RequeueAfter: 2 * time.Minute, Report: true},
+	ErrorClassInvalidInput:           {Retry: false, RequeueAfter: 30 * time.Minute, Report: true},
+	ErrorClassExpiredCredentials:     {Retry: true, RequeueAfter: 15 * time.Second},
+}
+
+// Behaviour returns how a reconciliation reacts to errors of the class.
+func (c ErrorClass) Behaviour() ErrorBehaviour {
+	if b, ok := errorBehaviours[c]; ok {
+		return b
+	}
+	return errorBehaviours[ErrorClassUnknown]
+}
+
+// ClassifyError returns the class of an error returned by the IAM service or by the AWS SDK.
+func ClassifyError(err error) ErrorClass {
+	if err == nil {
+		return ErrorClassUnknown
+	}
+	if IsInvalidPolicyTemplate(err) || IsInvalidAdditionalStatements(err) {
+		return ErrorClassMalformedPolicy
+	}
+	if IsInvalidServiceAccounts(err) {
+		return ErrorClassInvalidInput
+	}
+
+	var malformedPolicyErr *awsiamtypes.MalformedPolicyDocumentException
+	var limitExceededErr *awsiamtypes.LimitExceededException
+	var eksLimitExceededErr *ekstypes.ResourceLimitExceededException
+	var concurrentModificationErr *awsiamtypes.ConcurrentModificationException
+	var deleteConflictErr *awsiamtypes.DeleteConflictException
+	var invalidInputErr *awsiamtypes.InvalidInputException
+	var eksInvalidParameterErr *ekstypes.InvalidParameterException
+	var eksInvalidRequestErr *ekstypes.InvalidRequestException
+	switch {
+	case errors.As(err, &malformedPolicyErr):
+		return ErrorClassMalformedPolicy
+	case errors.As(err, &limitExceededErr), errors.As(err, &eksLimitExceededErr):
+		return ErrorClassLimitExceeded
+	case errors.As(err, &concurrentModificationErr):
+		return ErrorClassConcurrentModification
+	case errors.As(err, &deleteConflictErr):
+		return ErrorClassDeleteConflict
+	case errors.As(err, &invalidInputErr), errors.As(err, &eksInvalidParameterErr), errors.As(err, &eksInvalidRequestErr):
+		return ErrorClassInvalidInput
+	}
+
+	// errors without a type of their own in the SDK, e.g. of STS or of the authentication of requests
+	var apiErr smithy.APIError
+	if !errors.As(err, &apiErr) {
+		return ErrorClassUnknown
+	}
+	code := apiErr.ErrorCode()
+	if _, ok := retry.DefaultThrottleErrorCodes[code]; ok {
+		return ErrorClassThrottling
+	}
+	switch code {
+	case "AccessDenied", "AccessDeniedException":
+		return ErrorClassAccessDenied
+	case "ExpiredToken", "ExpiredTokenException", "RequestExpired", "TokenRefreshRequired":
+		return ErrorClassExpiredCredentials
+	}
+	return ErrorClassUnknown
+}
@@ -2,7 +2,6 @@ package iam
 
 import (
 	"context"
-	"encoding/json"
 	"errors"
 	"fmt"
 	"net/url"
@@ -12,26 +11,31 @@ import (
 
 	"github.com/Masterminds/semver/v3"
 	"github.com/aws/aws-sdk-go-v2/aws"
+	awsarn "github.com/aws/aws-sdk-go-v2/aws/arn"
 	"github.com/aws/aws-sdk-go-v2/service/eks"
 	"github.com/aws/aws-sdk-go-v2/service/iam"
 	iamtypes "github.com/aws/aws-sdk-go-v2/service/iam/types"
 	"github.com/giantswarm/microerror"
 	"github.com/go-logr/logr"
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/giantswarm/capa-iam-operator/v3/pkg/partition"
 )
 
 const (
-	BastionRole           = "bastion"
-	ControlPlaneRole      = "control-plane" // also used as part of finalizer name
-	NodesRole             = "nodes"         // also used as part of finalizer name
-	Route53Role           = "route53-role"
-	IRSARole              = "irsa-role"
-	CertManagerRole       = "cert-manager-role"
-	ALBConrollerRole      = "ALBController-Role"
-	EBSCSIDriverRole      = "ebs-csi-driver-role"
-	EFSCSIDriverRole      = "efs-csi-driver-role"
-	ClusterAutoscalerRole = "cluster-autoscaler-role"
-	IAMControllerOwnedTag = "capi-iam-controller/owned"
-	ClusterIDTag          = "sigs.k8s.io/cluster-api-provider-aws/cluster/%s"
+	BastionRole             = "bastion"
+	ControlPlaneRole        = "control-plane" // also used as part of finalizer name
+	NodesRole               = "nodes"         // also used as part of finalizer name
+	Route53Role             = "route53-role"
+	IRSARole                = "irsa-role"
+	CertManagerRole         = "cert-manager-role"
+	ALBConrollerRole        = "ALBController-Role"
+	EBSCSIDriverRole        = "ebs-csi-driver-role"
+	EFSCSIDriverRole        = "efs-csi-driver-role"
+	ClusterAutoscalerRole   = "cluster-autoscaler-role"
+	KarpenterControllerRole = "karpenter-controller-role"
+	IAMControllerOwnedTag   = "capi-iam-controller/owned"
+	ClusterIDTag            = "sigs.k8s.io/cluster-api-provider-aws/cluster/%s"
 )
 
 // GiantSwarmReleaseCrossplaneNodesIAMRoles The GiantSwarm CAPA release that introduced Crossplane CRs to manage IAM Roles / Policies / Instance profiles in `cluster-aws`.
@@ -46,24 +50,55 @@ type IAMClient interface {
 	iam.GetRoleAPIClient
 	iam.ListRolePoliciesAPIClient
 	iam.ListAttachedRolePoliciesAPIClient
+	iam.ListPolicyVersionsAPIClient
 
+	AddClientIDToOpenIDConnectProvider(ctx context.Context, params *iam.AddClientIDToOpenIDConnectProviderInput, optFns ...func(*iam.Options)) (*iam.AddClientIDToOpenIDConnectProviderOutput, error)
 	AddRoleToInstanceProfile(ctx context.Context, params *iam.AddRoleToInstanceProfileInput, optFns ...func(*iam.Options)) (*iam.AddRoleToInstanceProfileOutput, error)
+	AttachRolePolicy(ctx context.Context, params *iam.AttachRolePolicyInput, optFns ...func(*iam.Options)) (*iam.AttachRolePolicyOutput, error)
 	CreateInstanceProfile(ctx context.Context, params *iam.CreateInstanceProfileInput, optFns ...func(*iam.Options)) (*iam.CreateInstanceProfileOutput, error)
+	CreateOpenIDConnectProvider(ctx context.Context, params *iam.CreateOpenIDConnectProviderInput, optFns ...func(*iam.Options)) (*iam.CreateOpenIDConnectProviderOutput, error)
+	CreatePolicy(ctx context.Context, params *iam.CreatePolicyInput, optFns ...func(*iam.Options)) (*iam.CreatePolicyOutput, error)
+	CreatePolicyVersion(ctx context.Context, params *iam.CreatePolicyVersionInput, optFns ...func(*iam.Options)) (*iam.CreatePolicyVersionOutput, error)
 	CreateRole(ctx context.Context, params *iam.CreateRoleInput, optFns ...func(*iam.Options)) (*iam.CreateRoleOutput, error)
 	DeleteInstanceProfile(ctx context.Context, params *iam.DeleteInstanceProfileInput, optFns ...func(*iam.Options)) (*iam.DeleteInstanceProfileOutput, error)
+	DeleteOpenIDConnectProvider(ctx context.Context, params *iam.DeleteOpenIDConnectProviderInput, optFns ...func(*iam.Options)) (*iam.DeleteOpenIDConnectProviderOutput, error)
+	DeletePolicy(ctx context.Context, params *iam.DeletePolicyInput, optFns ...func(*iam.Options)) (*iam.DeletePolicyOutput, error)
+	DeletePolicyVersion(ctx context.Context, params *iam.DeletePolicyVersionInput, optFns ...func(*iam.Options)) (*iam.DeletePolicyVersionOutput, error)
 	DeleteRole(ctx context.Context, params *iam.DeleteRoleInput, optFns ...func(*iam.Options)) (*iam.DeleteRoleOutput, error)
+	DeleteRolePermissionsBoundary(ctx context.Context, params *iam.DeleteRolePermissionsBoundaryInput, optFns ...func(*iam.Options)) (*iam.DeleteRolePermissionsBoundaryOutput, error)
 	DeleteRolePolicy(ctx context.Context, params *iam.DeleteRolePolicyInput, optFns ...func(*iam.Options)) (*iam.DeleteRolePolicyOutput, error)
 	DetachRolePolicy(ctx context.Context, params *iam.DetachRolePolicyInput, optFns ...func(*iam.Options)) (*iam.DetachRolePolicyOutput, error)
+	GetInstanceProfile(ctx context.Context, params *iam.GetInstanceProfileInput, optFns ...func(*iam.Options)) (*iam.GetInstanceProfileOutput, error)
+	GetOpenIDConnectProvider(ctx context.Context, params *iam.GetOpenIDConnectProviderInput, optFns ...func(*iam.Options)) (*iam.GetOpenIDConnectProviderOutput, error)
+	GetPolicy(ctx context.Context, params *iam.GetPolicyInput, optFns ...func(*iam.Options)) (*iam.GetPolicyOutput, error)
+	GetPolicyVersion(ctx context.Context, params *iam.GetPolicyVersionInput, optFns ...func(*iam.Options)) (*iam.GetPolicyVersionOutput, error)
 	GetRolePolicy(ctx context.Context, params *iam.GetRolePolicyInput, optFns ...func(*iam.Options)) (*iam.GetRolePolicyOutput, error)
+	ListOpenIDConnectProviders(ctx context.Context, params *iam.ListOpenIDConnectProvidersInput, optFns ...func(*iam.Options)) (*iam.ListOpenIDConnectProvidersOutput, error)
+	PutRolePermissionsBoundary(ctx context.Context, params *iam.PutRolePermissionsBoundaryInput, optFns ...func(*iam.Options)) (*iam.PutRolePermissionsBoundaryOutput, error)
 	PutRolePolicy(ctx context.Context, params *iam.PutRolePolicyInput, optFns ...func(*iam.Options)) (*iam.PutRolePolicyOutput, error)
+	RemoveClientIDFromOpenIDConnectProvider(ctx context.Context, params *iam.RemoveClientIDFromOpenIDConnectProviderInput, optFns ...func(*iam.Options)) (*iam.RemoveClientIDFromOpenIDConnectProviderOutput, error)
 	RemoveRoleFromInstanceProfile(ctx context.Context, params *iam.RemoveRoleFromInstanceProfileInput, optFns ...func(*iam.Options)) (*iam.RemoveRoleFromInstanceProfileOutput, error)
+	TagInstanceProfile(ctx context.Context, params *iam.TagInstanceProfileInput, optFns ...func(*iam.Options)) (*iam.TagInstanceProfileOutput, error)
+	TagOpenIDConnectProvider(ctx context.Context, params *iam.TagOpenIDConnectProviderInput, optFns ...func(*iam.Options)) (*iam.TagOpenIDConnectProviderOutput, error)
+	TagRole(ctx context.Context, params *iam.TagRoleInput, optFns ...func(*iam.Options)) (*iam.TagRoleOutput, error)
+	UntagInstanceProfile(ctx context.Context, params *iam.UntagInstanceProfileInput, optFns ...func(*iam.Options)) (*iam.UntagInstanceProfileOutput, error)
+	UntagOpenIDConnectProvider(ctx context.Context, params *iam.UntagOpenIDConnectProviderInput, optFns ...func(*iam.Options)) (*iam.UntagOpenIDConnectProviderOutput, error)
+	UntagRole(ctx context.Context, params *iam.UntagRoleInput, optFns ...func(*iam.Options)) (*iam.UntagRoleOutput, error)
 	UpdateAssumeRolePolicy(ctx context.Context, params *iam.UpdateAssumeRolePolicyInput, optFns ...func(*iam.Options)) (*iam.UpdateAssumeRolePolicyOutput, error)
+	UpdateOpenIDConnectProviderThumbprint(ctx context.Context, params *iam.UpdateOpenIDConnectProviderThumbprintInput, optFns ...func(*iam.Options)) (*iam.UpdateOpenIDConnectProviderThumbprintOutput, error)
+	UpdateRole(ctx context.Context, params *iam.UpdateRoleInput, optFns ...func(*iam.Options)) (*iam.UpdateRoleOutput, error)
 }
 
 // EKSClient defines all the methods that we use of the EKS service.
 // I hate this less.
 type EKSClient interface {
 	eks.DescribeClusterAPIClient
+	eks.ListPodIdentityAssociationsAPIClient
+
+	CreatePodIdentityAssociation(ctx context.Context, params *eks.CreatePodIdentityAssociationInput, optFns ...func(*eks.Options)) (*eks.CreatePodIdentityAssociationOutput, error)
+	DeletePodIdentityAssociation(ctx context.Context, params *eks.DeletePodIdentityAssociationInput, optFns ...func(*eks.Options)) (*eks.DeletePodIdentityAssociationOutput, error)
+	DescribePodIdentityAssociation(ctx context.Context, params *eks.DescribePodIdentityAssociationInput, optFns ...func(*eks.Options)) (*eks.DescribePodIdentityAssociationOutput, error)
+	UpdatePodIdentityAssociation(ctx context.Context, params *eks.UpdatePodIdentityAssociationInput, optFns ...func(*eks.Options)) (*eks.UpdatePodIdentityAssociationOutput, error)
 }
 
 type IAMServiceConfig struct {
@@ -78,8 +113,42 @@ type IAMServiceConfig struct {
 	Region                string
 	PrincipalRoleARN      string
 	CustomTags            map[string]string
+	// ManagedPolicyRoleTypes lists the role types whose permissions are kept in a customer-managed policy
+	// instead of an inline policy.
+	ManagedPolicyRoleTypes []string
+	// PermissionsBoundary is the ARN of the policy set as permissions boundary on all roles. Empty means no boundary.
+	PermissionsBoundary string
+	// RoleSettings overrides the path, description and maximum session duration of the roles of the cluster.
+	RoleSettings RoleSettingsOverrides
+	// PolicyTemplates replace the built-in policy templates of the given role types.
+	PolicyTemplates PolicyTemplates
+	// AdditionalStatements are added to the policies of the given role types.
+	AdditionalStatements AdditionalPolicyStatements
+	// FeatureGates enables the role types whose definition names a feature gate.
+	FeatureGates map[string]bool
+	// IRSARoleTypes lists the IRSA roles of the cluster. Nil means all IRSA roles. Operator-owned roles that are not
+	// listed are deleted.
+	IRSARoleTypes []string
+	// ServiceAccounts overrides the service accounts that may assume the roles of the given role types.
+	ServiceAccounts map[string][]ServiceAccount
+	// Karpenter configures the Karpenter controller role, which is enabled by FeatureGateKarpenter.
+	Karpenter KarpenterConfig
+	// ServiceAccountTrust lists how service accounts assume the IRSA roles of the cluster, through the OIDC provider
+	// (TrustKindIRSA), EKS Pod Identity (TrustKindPodIdentity) or both. Nil means IRSA only.
+	ServiceAccountTrust []TrustKind
+	// EKSClusterName is the name of the EKS cluster the Pod Identity associations are created in. It is required for
+	// TrustKindPodIdentity.
+	EKSClusterName string
+	// ManageOIDCProviders enables creating, updating and deleting the IAM OIDC providers of the IRSA trust domains.
+	// Otherwise missing providers are only reported.
+	ManageOIDCProviders bool
+	// OIDCProviderThumbprints are the server certificate thumbprints of the OIDC providers of the cluster. Empty means
+	// IAM determines them when creating a provider and they are not reconciled.
+	OIDCProviderThumbprints []string
 
 	IAMClientFactory func(aws.Config, string) IAMClient
+	// EKSClientFactory creates the EKS client. Nil means the client of the AWS SDK.
+	EKSClientFactory func(aws.Config, string) EKSClient
 }
 
 type IAMService struct {
@@ -95,6 +164,21 @@ type IAMService struct {
 	roleType              string
 	principalRoleARN      string
 	customTags            map[string]string
+
+	managedPolicyRoleTypes []string
+	permissionsBoundary    string
+	roleSettingsOverrides  RoleSettingsOverrides
+	policyTemplates        PolicyTemplates
+	additionalStatements   AdditionalPolicyStatements
+	featureGates           map[string]bool
+	irsaRoleTypes          []string
+	serviceAccounts        map[string][]ServiceAccount
+	karpenter              KarpenterConfig
+	serviceAccountTrust    []TrustKind
+	eksClusterName         string
+
+	manageOIDCProviders     bool
+	oidcProviderThumbprints []string
 }
 
 type Route53RoleParams struct {
@@ -102,9 +186,21 @@ type Route53RoleParams struct {
 	EC2ServiceDomain string
 	AccountID        string
 	IRSATrustDomains []string
+	ServiceAccounts  []ServiceAccount
+	// Namespace and ServiceAccount are those of the first service account, for templates written before roles
+	// could have several.
 	Namespace        string
 	ServiceAccount   string
 	PrincipalRoleARN string
+
+	// ClusterName, Region, NodeRoleARNs and InterruptionQueueARN are used by the Karpenter controller role.
+	ClusterName          string
+	Region               string
+	NodeRoleARNs         []string
+	InterruptionQueueARN string
+
+	// EKSClusterName is used by the Pod Identity trust policy.
+	EKSClusterName string
 }
 
 func New(config IAMServiceConfig) (*IAMService, error) {
@@ -123,14 +219,48 @@ func New(config IAMServiceConfig) (*IAMService, error) {
 	if config.MainRoleName == "" {
 		return nil, errors.New("cannot create IAMService with empty MainRoleName")
 	}
-	if config.RoleType != ControlPlaneRole && config.RoleType != NodesRole && config.RoleType != BastionRole && config.RoleType != IRSARole {
+	if d, ok := GetRoleDefinition(config.RoleType); !ok || !d.Main {
 		return nil, fmt.Errorf("cannot create IAMService with invalid RoleType '%s'", config.RoleType)
 	}
+	if config.PermissionsBoundary != "" {
+		if _, err := awsarn.Parse(config.PermissionsBoundary); err != nil {
+			return nil, fmt.Errorf("cannot create IAMService with invalid PermissionsBoundary '%s': %w", config.PermissionsBoundary, err)
+		}
+	}
+	if err := ValidateIRSARoleTypes(config.IRSARoleTypes); err != nil {
+		return nil, fmt.Errorf("cannot create IAMService with invalid IRSARoleTypes: %w", err)
+	}
+	if err := ValidateServiceAccounts(config.ServiceAccounts); err != nil {
+		return nil, fmt.Errorf("cannot create IAMService with invalid ServiceAccounts: %w", err)
+	}
+	if err := config.Karpenter.Validate(); err != nil {
+		return nil, fmt.Errorf("cannot create IAMService with invalid Karpenter config: %w", err)
+	}
+	if err := ValidateServiceAccountTrust(config.ServiceAccountTrust); err != nil {
+		return nil, fmt.Errorf("cannot create IAMService with invalid ServiceAccountTrust: %w", err)
+	}
+	if slices.Contains(config.ServiceAccountTrust, TrustKindPodIdentity) && config.EKSClusterName == "" {
+		return nil, errors.New("cannot create IAMService with Pod Identity trust and empty EKSClusterName")
+	}
+	if err := ValidateOIDCProviderThumbprints(config.OIDCProviderThumbprints); err != nil {
+		return nil, fmt.Errorf("cannot create IAMService with invalid OIDCProviderThumbprints: %w", err)
+	}
+	if err := ValidateRoleTypes(config.ManagedPolicyRoleTypes); err != nil {
+		return nil, fmt.Errorf("cannot create IAMService with invalid ManagedPolicyRoleTypes: %w", err)
+	}
+	if err := config.RoleSettings.Validate(); err != nil {
+		return nil, fmt.Errorf("cannot create IAMService with invalid RoleSettings: %w", err)
+	}
 	if config.ObjectLabels == nil {
 		config.ObjectLabels = map[string]string{}
 	}
 	iamClient := config.IAMClientFactory(*config.AWSConfig, config.Region)
-	eksClient := eks.NewFromConfig(*config.AWSConfig)
+	var eksClient EKSClient
+	if config.EKSClientFactory != nil {
+		eksClient = config.EKSClientFactory(*config.AWSConfig, config.Region)
+	} else {
+		eksClient = eks.NewFromConfig(*config.AWSConfig)
+	}
 
 	l := config.Log.WithValues("clusterName", config.ClusterName, "iam-role", config.RoleType)
 	s := &IAMService{
@@ -146,6 +276,21 @@ func New(config IAMServiceConfig) (*IAMService, error) {
 		region:                config.Region,
 		principalRoleARN:      config.PrincipalRoleARN,
 		customTags:            config.CustomTags,
+
+		managedPolicyRoleTypes: config.ManagedPolicyRoleTypes,
+		permissionsBoundary:    config.PermissionsBoundary,
+		roleSettingsOverrides:  config.RoleSettings,
+		policyTemplates:        config.PolicyTemplates,
+		additionalStatements:   config.AdditionalStatements,
+		featureGates:           config.FeatureGates,
+		irsaRoleTypes:          config.IRSARoleTypes,
+		serviceAccounts:        config.ServiceAccounts,
+		karpenter:              config.Karpenter,
+		serviceAccountTrust:    config.ServiceAccountTrust,
+		eksClusterName:         config.EKSClusterName,
+
+		manageOIDCProviders:     config.ManageOIDCProviders,
+		oidcProviderThumbprints: config.OIDCProviderThumbprints,
 	}
 
 	return s, nil
@@ -161,8 +306,8 @@ func (s *IAMService) ReconcileRole() error {
 		ObjectLabels     map[string]string
 	}{
 		ClusterName:      s.clusterName,
-		EC2ServiceDomain: ec2ServiceDomain(s.region),
-		AWSPartition:     awsPartition(s.region),
+		EC2ServiceDomain: partition.ForRegion(s.region).EC2ServicePrincipal,
+		AWSPartition:     partition.ForRegion(s.region).Name,
 		ObjectLabels:     s.objectLabels,
 	}
 	err := s.reconcileRole(s.mainRoleName, s.roleType, params)
@@ -177,15 +322,40 @@ func (s *IAMService) ReconcileRole() error {
 func (s *IAMService) ReconcileRolesForIRSA(awsAccountID string, irsaTrustDomains []string) error {
 	s.log.Info("reconciling IAM roles for IRSA")
 
-	for _, roleTypeToReconcile := range getIRSARoles() {
+	var associations []podIdentityAssociation
+	for _, definition := range irsaRoleDefinitions() {
+		if !s.isEnabled(definition) {
+			s.log.Info("IRSA role is disabled for the cluster, deleting it if the operator created it", "role_type", definition.Type)
+			err := s.deleteDisabledRole(definition.RoleName(s.clusterName))
+			if err != nil {
+				return err
+			}
+			continue
+		}
+
 		var params Route53RoleParams
-		params, err := s.generateRoute53RoleParams(roleTypeToReconcile, awsAccountID, irsaTrustDomains)
+		params, err := s.generateRoute53RoleParams(definition, awsAccountID, irsaTrustDomains)
 		if err != nil {
 			s.log.Error(err, "failed to generate Route53 role parameters")
 			return err
 		}
 
-		err = s.reconcileRole(roleName(roleTypeToReconcile, s.clusterName), roleTypeToReconcile, params)
+		err = s.reconcileRole(definition.RoleName(s.clusterName), definition.Type, params)
+		if err != nil {
+			return err
+		}
+
+		if s.usesServiceAccountTrust(TrustKindPodIdentity) {
+			roleAssociations, err := s.desiredPodIdentityAssociations(definition.RoleName(s.clusterName), params.ServiceAccounts)
+			if err != nil {
+				return err
+			}
+			associations = append(associations, roleAssociations...)
+		}
+	}
+
+	if s.usesServiceAccountTrust(TrustKindPodIdentity) {
+		err := s.reconcilePodIdentityAssociations(associations)
 		if err != nil {
 			return err
 		}
@@ -195,25 +365,49 @@ func (s *IAMService) ReconcileRolesForIRSA(awsAccountID string, irsaTrustDomains
 	return nil
 }
 
-func (s *IAMService) generateRoute53RoleParams(roleTypeToReconcile string, awsAccountID string, irsaTrustDomains []string) (Route53RoleParams, error) {
-	if len(irsaTrustDomains) == 0 || slices.ContainsFunc(irsaTrustDomains, func(irsaTrustDomain string) bool { return irsaTrustDomain == "" }) {
+func (s *IAMService) generateRoute53RoleParams(definition RoleDefinition, awsAccountID string, irsaTrustDomains []string) (Route53RoleParams, error) {
+	// without IRSA trust, roles are only assumed through Pod Identity, which needs no OIDC provider
+	if (s.usesServiceAccountTrust(TrustKindIRSA) && len(irsaTrustDomains) == 0) || slices.ContainsFunc(irsaTrustDomains, func(irsaTrustDomain string) bool { return irsaTrustDomain == "" }) {
 		return Route53RoleParams{}, fmt.Errorf("irsaTrustDomains cannot be empty or have empty values: %v", irsaTrustDomains)
 	}
-
-	namespace := "kube-system"
-	serviceAccount, err := getServiceAccount(roleTypeToReconcile)
-	if err != nil {
-		s.log.Error(err, "failed to get service account for role")
-		return Route53RoleParams{}, err
+	serviceAccounts := definition.ServiceAccounts
+	if override, ok := s.serviceAccounts[definition.Type]; ok {
+		serviceAccounts = override
+	}
+	if len(serviceAccounts) == 0 {
+		return Route53RoleParams{}, fmt.Errorf("cannot get service account for specified role - %s", definition.Type)
+	}
+	// Pod Identity associations need the exact namespace and name, so without IRSA a wildcard leaves the role unusable
+	if !s.usesServiceAccountTrust(TrustKindIRSA) {
+		for _, sa := range serviceAccounts {
+			if strings.Contains(sa.String(), "*") {
+				return Route53RoleParams{}, microerror.Maskf(invalidServiceAccountsError, "role type %q: service account %q has wildcards, which Pod Identity does not support, set exact service accounts for the role type", definition.Type, sa.String())
+			}
+		}
 	}
 
 	params := Route53RoleParams{
-		AWSPartition:     awsPartition(s.region),
-		EC2ServiceDomain: ec2ServiceDomain(s.region),
+		AWSPartition:     partition.ForRegion(s.region).Name,
+		EC2ServiceDomain: partition.ForRegion(s.region).EC2ServicePrincipal,
 		AccountID:        awsAccountID,
 		IRSATrustDomains: irsaTrustDomains,
-		Namespace:        namespace,
-		ServiceAccount:   serviceAccount,
+		ServiceAccounts:  serviceAccounts,
+		Namespace:        serviceAccounts[0].Namespace,
+		ServiceAccount:   serviceAccounts[0].Name,
+
+		ClusterName:          s.clusterName,
+		Region:               s.region,
+		InterruptionQueueARN: s.karpenter.InterruptionQueueARN,
+
+		EKSClusterName: s.eksClusterName,
+	}
+
+	if definition.Type == KarpenterControllerRole {
+		nodeRoleARNs, err := s.karpenterNodeRoleARNs(awsAccountID)
+		if err != nil {
+			return Route53RoleParams{}, err
+		}
+		params.NodeRoleARNs = nodeRoleARNs
 	}
 
 	return params, nil
@@ -228,8 +422,13 @@ func (s *IAMService) reconcileRole(roleName string, roleType string, params any)
 		return err
 	}
 
+	definition, ok := GetRoleDefinition(roleType)
+	if !ok {
+		return fmt.Errorf("unknown role type %q", roleType)
+	}
+
 	// If a cluster is using a release equal or greater than the release containing these changes, we skip the nodes IAM Role creation.
-	if (roleType == ControlPlaneRole || roleType == NodesRole) && currentVersion.GreaterThanEqual(GiantSwarmReleaseCrossplaneNodesIAMRoles) {
+	if definition.managedByCrossplane(currentVersion) {
 		l.Info("Crossplane-enabled Release, skipping reconciliation")
 		return nil
 	}
@@ -243,20 +442,49 @@ func (s *IAMService) reconcileRole(roleName string, roleType string, params any)
 		return nil
 	}
 
-	err = s.createRole(roleName, roleType, params)
+	created, err := s.createRole(roleName, roleType, params)
+	if err != nil {
+		return err
+	}
+
+	err = s.ensureInstanceProfile(roleName, roleType)
 	if err != nil {
 		return err
 	}
 
-	if roleType == IRSARole || roleType == CertManagerRole || roleType == Route53Role || roleType == ALBConrollerRole || roleType == EBSCSIDriverRole || roleType == EFSCSIDriverRole || roleType == ClusterAutoscalerRole {
+	// A freshly created role already carries the expected trust policy and tags.
+	if !created {
 		if err = s.applyAssumePolicyRole(roleName, roleType, params); err != nil {
 			l.Error(err, "Failed to apply assume role policy to role")
 			return err
 		}
+
+		if err = s.reconcileTags(roleName); err != nil {
+			l.Error(err, "Failed to reconcile tags of role")
+			return err
+		}
+
+		if err = s.reconcilePermissionsBoundary(roleName); err != nil {
+			l.Error(err, "Failed to reconcile permissions boundary of role")
+			return err
+		}
+
+		if err = s.reconcileRoleSettings(roleName, roleType); err != nil {
+			l.Error(err, "Failed to reconcile settings of role")
+			return err
+		}
 	}
 
-	// we only attach the inline policy to a role that is owned (and was created) by iam controller
-	err = s.attachInlinePolicy(roleName, roleType, params)
+	// we only attach the policy to a role that is owned (and was created) by iam controller
+	if s.usesManagedPolicy(roleType) {
+		err = s.attachManagedPolicy(roleName, roleType, params)
+	} else {
+		err = s.attachInlinePolicy(roleName, roleType, params)
+		if err == nil && !created {
+			// an existing role may have used a managed policy before, which is replaced by the inline policy now
+			err = s.detachManagedPolicy(roleName)
+		}
+	}
 	if err != nil {
 		return err
 	}
@@ -264,8 +492,19 @@ func (s *IAMService) reconcileRole(roleName string, roleType string, params any)
 	return nil
 }
 
-// createRole will create requested IAM role
-func (s *IAMService) createRole(roleName string, roleType string, params any) error {
+// isEnabled returns true if the feature gate of the role, if any, is enabled and the role is selected for the cluster.
+func (s *IAMService) isEnabled(definition RoleDefinition) bool {
+	if definition.FeatureGate != "" && !s.featureGates[definition.FeatureGate] {
+		return false
+	}
+	if !definition.Main && s.irsaRoleTypes != nil {
+		return slices.Contains(s.irsaRoleTypes, definition.Type)
+	}
+	return true
+}
+
+// createRole will create requested IAM role. It returns true if the role did not exist before.
+func (s *IAMService) createRole(roleName string, roleType string, params any) (bool, error) {
 	l := s.log.WithValues("role_name", roleName, "role_type", roleType)
 
 	_, err := s.iamClient.GetRole(context.TODO(), &iam.GetRoleInput{
@@ -275,86 +514,115 @@ func (s *IAMService) createRole(roleName string, roleType string, params any) er
 	// create new IAMRole if it does not exist yet
 	if err == nil {
 		l.Info("IAM Role already exists, skipping creation")
-		return nil
+		return false, nil
 	}
 	if !IsNotFound(err) {
 		l.Error(err, "Failed to fetch IAM Role")
-		return err
+		return false, err
 	}
 
-	tmpl := getTrustPolicyTemplate(roleType)
-
-	assumeRolePolicyDocument, err := generatePolicyDocument(tmpl, params)
+	assumeRolePolicyDocument, err := s.generateTrustPolicyDocument(roleType, params)
 	if err != nil {
 		l.Error(err, "failed to generate assume policy document from template for IAM role")
-		return err
+		return false, err
 	}
 
-	tags := []iamtypes.Tag{
-		{
-			Key:   aws.String(IAMControllerOwnedTag),
-			Value: aws.String(""),
-		},
-		{
-			Key:   aws.String(fmt.Sprintf(ClusterIDTag, s.clusterName)),
-			Value: aws.String("owned"),
-		},
-	}
-	for k, v := range s.customTags {
-		tags = append(tags, iamtypes.Tag{
-			Key:   aws.String(k),
-			Value: aws.String(v),
-		})
-	}
+	tags := s.desiredTags()
+	settings := s.roleSettings(roleType)
 
-	_, err = s.iamClient.CreateRole(context.TODO(), &iam.CreateRoleInput{
+	i := &iam.CreateRoleInput{
 		RoleName:                 aws.String(roleName),
 		AssumeRolePolicyDocument: aws.String(assumeRolePolicyDocument),
 		Tags:                     tags,
-	})
+		Path:                     aws.String(settings.Path),
+		Description:              aws.String(settings.Description),
+		MaxSessionDuration:       aws.Int32(settings.MaxSessionDuration),
+	}
+	if s.permissionsBoundary != "" {
+		i.PermissionsBoundary = aws.String(s.permissionsBoundary)
+	}
+
+	_, err = s.iamClient.CreateRole(context.TODO(), i)
 	if err != nil {
 		l.Error(err, "failed to create IAM Role")
-		return err
+		return false, err
 	}
 
-	i2 := &iam.CreateInstanceProfileInput{
+	l.Info("successfully created a new IAM role")
+
+	return true, nil
+}
+
+// ensureInstanceProfile makes sure the instance profile of the role exists and contains the role. It runs on every
+// reconciliation, so that a setup which was interrupted after creating the role is completed later on.
+func (s *IAMService) ensureInstanceProfile(roleName string, roleType string) error {
+	l := s.log.WithValues("role_name", roleName)
+
+	var roles []iamtypes.Role
+	o, err := s.iamClient.GetInstanceProfile(context.TODO(), &iam.GetInstanceProfileInput{
 		InstanceProfileName: aws.String(roleName),
-		Tags:                tags,
-	}
+	})
+	if IsNotFound(err) {
+		i := &iam.CreateInstanceProfileInput{
+			InstanceProfileName: aws.String(roleName),
+			Path:                aws.String(s.roleSettings(roleType).Path),
+			Tags:                s.desiredTags(),
+		}
 
-	_, err = s.iamClient.CreateInstanceProfile(context.TODO(), i2)
-	if IsAlreadyExists(err) {
-		// fall thru
+		_, err = s.iamClient.CreateInstanceProfile(context.TODO(), i)
+		if IsAlreadyExists(err) {
+			// fall thru
+		} else if err != nil {
+			l.Error(err, "failed to create instance profile")
+			return err
+		} else {
+			l.Info("successfully created instance profile")
+		}
 	} else if err != nil {
-		l.Error(err, "failed to create instance profile")
+		l.Error(err, "failed to fetch instance profile")
 		return err
+	} else {
+		roles = o.InstanceProfile.Roles
 	}
 
-	i3 := &iam.AddRoleToInstanceProfileInput{
+	for _, role := range roles {
+		if aws.ToString(role.RoleName) == roleName {
+			return nil
+		}
+	}
+	if len(roles) > 0 {
+		err = microerror.Maskf(instanceProfileConflictError, "instance profile %q contains role %q instead of %q", roleName, aws.ToString(roles[0].RoleName), roleName)
+		l.Error(err, "instance profile is used by a different role")
+		return err
+	}
+
+	i := &iam.AddRoleToInstanceProfileInput{
 		InstanceProfileName: aws.String(roleName),
 		RoleName:            aws.String(roleName),
 	}
 
-	_, err = s.iamClient.AddRoleToInstanceProfile(context.TODO(), i3)
+	_, err = s.iamClient.AddRoleToInstanceProfile(context.TODO(), i)
 	if IsAlreadyExists(err) {
 		// fall thru
 	} else if err != nil {
 		l.Error(err, "failed to add role to instance profile")
 		return err
+	} else {
+		l.Info("successfully added role to instance profile")
 	}
 
-	l.Info("successfully created a new IAM role")
-
 	return nil
 }
 
+// applyAssumePolicyRole compares the trust policy of an existing role with the one rendered from the template and
+// restores it when they differ, e.g. because somebody edited it by hand.
 func (s *IAMService) applyAssumePolicyRole(roleName string, roleType string, params any) error {
 	log := s.log.WithValues("role_name", roleName)
 	i := &iam.GetRoleInput{
 		RoleName: aws.String(roleName),
 	}
 
-	_, err := s.iamClient.GetRole(context.TODO(), i)
+	o, err := s.iamClient.GetRole(context.TODO(), i)
 
 	if IsNotFound(err) {
 		log.Info("role doesn't exist. Skipping application of assume policy")
@@ -366,15 +634,34 @@ func (s *IAMService) applyAssumePolicyRole(roleName string, roleType string, par
 		return err
 	}
 
-	log.Info("applying assume policy role to role")
-
-	tmpl := getTrustPolicyTemplate(roleType)
-	assumeRolePolicyDocument, err := generatePolicyDocument(tmpl, params)
+	assumeRolePolicyDocument, err := s.generateTrustPolicyDocument(roleType, params)
 	if err != nil {
 		log.Error(err, "failed to generate assume policy document from template for IAM role")
 		return err
 	}
 
+	if o.Role != nil && o.Role.AssumeRolePolicyDocument != nil {
+		isEqual, err := areEqualPolicy(*o.Role.AssumeRolePolicyDocument, assumeRolePolicyDocument)
+		if err != nil {
+			log.Error(err, "failed to compare assume policy documents")
+			return err
+		}
+		if isEqual {
+			log.Info("assume policy of IAM role is up to date")
+			return nil
+		}
+
+		diff, err := policyDiff(*o.Role.AssumeRolePolicyDocument, assumeRolePolicyDocument)
+		if err != nil {
+			log.Error(err, "failed to compute assume policy diff")
+			return err
+		}
+		trustPolicyDriftTotal.WithLabelValues(s.clusterName, roleType, roleName).Inc()
+		log.Info("detected drift in assume policy of IAM role, restoring it", "diff", diff)
+	}
+
+	log.Info("applying assume policy role to role")
+
 	updateInput := &iam.UpdateAssumeRolePolicyInput{
 		RoleName:       aws.String(roleName),
 		PolicyDocument: aws.String(assumeRolePolicyDocument),
@@ -385,12 +672,13 @@ func (s *IAMService) applyAssumePolicyRole(roleName string, roleType string, par
 	return err
 }
 
-// attachInlinePolicy  will attach inline policy to the main IAM role
+// attachInlinePolicy  will attach inline policy to the main IAM role. PutRolePolicy replaces an existing policy in
+// place, so the role always has either the previous or the new policy.
 func (s *IAMService) attachInlinePolicy(roleName string, roleType string, params any) error {
 	l := s.log.WithValues("role_name", roleName)
-	tmpl := getInlinePolicyTemplate(roleType, s.objectLabels)
+	inlinePolicyName := policyName(roleType, s.clusterName)
 
-	policyDocument, err := generatePolicyDocument(tmpl, params)
+	policyDocument, err := s.generateInlinePolicyDocument(roleType, params)
 	if err != nil {
 		l.Error(err, "failed to generate inline policy document from template for IAM role")
 		return err
@@ -399,13 +687,14 @@ func (s *IAMService) attachInlinePolicy(roleName string, roleType string, params
 	// check if the inline policy already exists
 	output, err := s.iamClient.GetRolePolicy(context.TODO(), &iam.GetRolePolicyInput{
 		RoleName:   aws.String(roleName),
-		PolicyName: aws.String(policyName(s.roleType, s.clusterName)),
+		PolicyName: aws.String(inlinePolicyName),
 	})
 	if err != nil && !IsNotFound(err) {
 		l.Error(err, "failed to fetch inline policy for IAM role")
 		return err
 	}
 
+	var previousPolicyDocument string
 	if err == nil {
 		// Policy already exists
 
@@ -416,21 +705,18 @@ func (s *IAMService) attachInlinePolicy(roleName string, roleType string, params
 		}
 		if isEqual {
 			l.Info("inline policy for IAM role already exists, skipping")
-			return nil
+			return s.deleteLegacyInlinePolicy(roleName, roleType)
 		}
 
-		_, err = s.iamClient.DeleteRolePolicy(context.TODO(), &iam.DeleteRolePolicyInput{
-			PolicyName: aws.String(policyName(s.roleType, s.clusterName)),
-			RoleName:   aws.String(roleName),
-		})
+		previousPolicyDocument, err = urlDecode(*output.PolicyDocument)
 		if err != nil {
-			l.Error(err, "failed to delete inline policy from IAM Role")
+			l.Error(err, "failed to decode inline policy document")
 			return err
 		}
 	}
 
 	i := &iam.PutRolePolicyInput{
-		PolicyName:     aws.String(policyName(s.roleType, s.clusterName)),
+		PolicyName:     aws.String(inlinePolicyName),
 		PolicyDocument: aws.String(policyDocument),
 		RoleName:       aws.String(roleName),
 	}
@@ -438,13 +724,86 @@ func (s *IAMService) attachInlinePolicy(roleName string, roleType string, params
 	_, err = s.iamClient.PutRolePolicy(context.TODO(), i)
 	if err != nil {
 		l.Error(err, "failed to add inline policy to IAM Role")
+		if previousPolicyDocument != "" {
+			s.restoreInlinePolicy(roleName, inlinePolicyName, previousPolicyDocument)
+		}
 		return err
 	}
 	l.Info("successfully added inline policy to IAM role")
 
+	return s.deleteLegacyInlinePolicy(roleName, roleType)
+}
+
+// deleteLegacyInlinePolicy deletes the inline policy named after the main role type of the service, which older
+// versions used for all roles, once the role has its own policy.
+func (s *IAMService) deleteLegacyInlinePolicy(roleName string, roleType string) error {
+	legacyPolicyName := policyName(s.roleType, s.clusterName)
+	if legacyPolicyName == policyName(roleType, s.clusterName) {
+		return nil
+	}
+	l := s.log.WithValues("role_name", roleName, "policy_name", legacyPolicyName)
+
+	o, err := s.iamClient.ListRolePolicies(context.TODO(), &iam.ListRolePoliciesInput{
+		RoleName: aws.String(roleName),
+	})
+	if err != nil {
+		l.Error(err, "failed to list inline policies of IAM role")
+		return err
+	}
+	if !slices.Contains(o.PolicyNames, legacyPolicyName) {
+		return nil
+	}
+
+	_, err = s.iamClient.DeleteRolePolicy(context.TODO(), &iam.DeleteRolePolicyInput{
+		PolicyName: aws.String(legacyPolicyName),
+		RoleName:   aws.String(roleName),
+	})
+	if err != nil && !IsNotFound(err) {
+		l.Error(err, "failed to delete legacy inline policy from IAM role")
+		return err
+	}
+	l.Info("deleted legacy inline policy from IAM role")
+
 	return nil
 }
 
+// restoreInlinePolicy puts the previous inline policy back if it is no longer in place after a failed update. Errors
+// are only logged, as the caller reports the failed update anyway.
+func (s *IAMService) restoreInlinePolicy(roleName string, inlinePolicyName string, previousPolicyDocument string) {
+	l := s.log.WithValues("role_name", roleName)
+
+	output, err := s.iamClient.GetRolePolicy(context.TODO(), &iam.GetRolePolicyInput{
+		RoleName:   aws.String(roleName),
+		PolicyName: aws.String(inlinePolicyName),
+	})
+	if err != nil && !IsNotFound(err) {
+		l.Error(err, "failed to fetch inline policy for IAM role after failed update")
+		return
+	}
+	if err == nil {
+		isEqual, err := areEqualPolicy(*output.PolicyDocument, previousPolicyDocument)
+		if err != nil {
+			l.Error(err, "failed to compare inline policy documents after failed update")
+			return
+		}
+		if isEqual {
+			l.Info("previous inline policy of IAM role is still in place")
+			return
+		}
+	}
+
+	_, err = s.iamClient.PutRolePolicy(context.TODO(), &iam.PutRolePolicyInput{
+		PolicyName:     aws.String(inlinePolicyName),
+		PolicyDocument: aws.String(previousPolicyDocument),
+		RoleName:       aws.String(roleName),
+	})
+	if err != nil {
+		l.Error(err, "failed to roll back inline policy of IAM role")
+		return
+	}
+	l.Info("rolled back inline policy of IAM role to the previous document")
+}
+
 func (s *IAMService) DeleteRole() error {
 	s.log.Info("deleting IAM resources")
 
@@ -452,7 +811,7 @@ func (s *IAMService) DeleteRole() error {
 	// This means that we no longer need to manage the IAM Roles for nodes (workers, control-plane) from this controller.
 	// If a cluster is using a release equal or greater than the release containing these changes, we skip the node IAM Role deletion.
 	// We have an issue to delete them manually when customers have upgraded https://github.com/giantswarm/giantswarm/issues/34712.
-	if s.roleType == ControlPlaneRole || s.roleType == NodesRole {
+	if definition, _ := GetRoleDefinition(s.roleType); definition.CrossplaneRelease != nil {
 		// Parse the current cluster release version
 		currentVersion, err := semver.NewVersion(s.clusterRelease)
 		if err != nil {
@@ -460,7 +819,7 @@ func (s *IAMService) DeleteRole() error {
 		}
 
 		// Check if the current version is equal or greater than threshold version
-		if currentVersion.GreaterThanEqual(GiantSwarmReleaseCrossplaneNodesIAMRoles) {
+		if definition.managedByCrossplane(currentVersion) {
 			s.log.Info("Skipped deleting control plane role as the cluster is using a giantswarm release that uses Crossplane IAM Roles")
 			return nil
 		}
@@ -498,8 +857,16 @@ func (s *IAMService) DeleteRolesForIRSA() error {
 		return nil
 	}
 
-	for _, roleTypeToReconcile := range getIRSARoles() {
-		err := s.deleteRole(roleName(roleTypeToReconcile, s.clusterName))
+	if s.usesServiceAccountTrust(TrustKindPodIdentity) {
+		err := s.reconcilePodIdentityAssociations(nil)
+		if err != nil {
+			return err
+		}
+	}
+
+	// roles behind a disabled feature gate are deleted as well, in case they were created while it was enabled
+	for _, definition := range irsaRoleDefinitions() {
+		err := s.deleteRole(definition.RoleName(s.clusterName))
 		if err != nil {
 			return err
 		}
@@ -508,6 +875,35 @@ func (s *IAMService) DeleteRolesForIRSA() error {
 	return nil
 }
 
+// deleteDisabledRole deletes a role that is no longer enabled for the cluster. Roles not created by the operator are
+// left alone.
+func (s *IAMService) deleteDisabledRole(roleName string) error {
+	l := s.log.WithValues("role_name", roleName)
+
+	role, err := s.iamClient.GetRole(context.TODO(), &iam.GetRoleInput{
+		RoleName: aws.String(roleName),
+	})
+	if IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		l.Error(err, "Failed to fetch IAM Role")
+		return err
+	}
+	if !slices.ContainsFunc(role.Role.Tags, func(t iamtypes.Tag) bool { return aws.ToString(t.Key) == IAMControllerOwnedTag }) {
+		l.Info("IAM Role is not owned by the operator, not deleting it")
+		return nil
+	}
+
+	err = s.deleteRole(roleName)
+	if err != nil {
+		return err
+	}
+	l.Info("deleted disabled IAM Role")
+
+	return nil
+}
+
 func (s *IAMService) deleteRole(roleName string) error {
 	l := s.log.WithValues("role_name", roleName)
 
@@ -544,6 +940,14 @@ func (s *IAMService) deleteRole(roleName string) error {
 		return err
 	}
 
+	// remove the permissions boundary we set, so that deleting the role is not subject to guardrails requiring it
+	if existingRole.Role.PermissionsBoundary != nil && hasPermissionsBoundaryTag(existingRole.Role.Tags) {
+		err = s.deletePermissionsBoundary(roleName)
+		if err != nil {
+			return err
+		}
+	}
+
 	i := &iam.RemoveRoleFromInstanceProfileInput{
 		InstanceProfileName: aws.String(roleName),
 		RoleName:            aws.String(roleName),
@@ -629,6 +1033,13 @@ func (s *IAMService) cleanAttachedPolicies(roleName string) error {
 		}
 
 		l.Info(fmt.Sprintf("detached policy %s", *p.PolicyName))
+
+		if *p.PolicyName == managedPolicyName(roleName) {
+			err = s.deleteManagedPolicy(*p.PolicyArn)
+			if err != nil {
+				return err
+			}
+		}
 	}
 	return nil
 }
@@ -700,76 +1111,46 @@ func (s *IAMService) GetIRSAOpenIDForEKS(clusterName string) (string, error) {
 	return id, nil
 }
 
-func roleName(role string, clusterID string) string {
-	switch role {
-	case Route53Role:
-		return fmt.Sprintf("%s-Route53Manager-Role", clusterID)
-	case CertManagerRole:
-		return fmt.Sprintf("%s-CertManager-Role", clusterID)
-	default:
-		return fmt.Sprintf("%s-%s", clusterID, role)
-	}
-}
-
 func policyName(role string, clusterID string) string {
 	return fmt.Sprintf("%s-%s-policy", role, clusterID)
 }
 
-func getServiceAccount(role string) (string, error) {
-	switch role {
-	case CertManagerRole:
-		return "cert-manager-app", nil
-	case IRSARole:
-		return "external-dns", nil
-	case Route53Role:
-		return "external-dns", nil
-	case ALBConrollerRole:
-		return "aws-load-balancer-controller", nil
-	case EBSCSIDriverRole:
-		return "ebs-csi-controller-sa", nil
-	case EFSCSIDriverRole:
-		return "efs-csi-sa", nil
-	case ClusterAutoscalerRole:
-		return "cluster-autoscaler", nil
-	default:
-		return "", fmt.Errorf("cannot get service account for specified role - %s", role)
+// areEqualPolicy returns true if the URL-encoded policy as returned by IAM is semantically equal to the expected one.
+func areEqualPolicy(encodedPolicy, expectedPolicy string) (bool, error) {
+	actual, expected, err := normalizePolicies(encodedPolicy, expectedPolicy)
+	if err != nil {
+		return false, err
 	}
+	return reflect.DeepEqual(actual, expected), nil
 }
 
-func getIRSARoles() []string {
-	return []string{
-		Route53Role,
-		CertManagerRole,
-		ALBConrollerRole,
-		EBSCSIDriverRole,
-		EFSCSIDriverRole,
-		ClusterAutoscalerRole,
+// policyDiff returns a human-readable diff between the URL-encoded policy as returned by IAM and the expected one.
+func policyDiff(encodedPolicy, expectedPolicy string) (string, error) {
+	actual, expected, err := normalizePolicies(encodedPolicy, expectedPolicy)
+	if err != nil {
+		return "", err
 	}
+
+	// Lines prefixed with "-" are only in the actual policy, lines prefixed with "+" only in the expected one.
+	return cmp.Diff(actual, expected), nil
 }
 
-func areEqualPolicy(encodedPolicy, expectedPolicy string) (bool, error) {
+func normalizePolicies(encodedPolicy, expectedPolicy string) (map[string]any, map[string]any, error) {
 	decodedPolicy, err := urlDecode(encodedPolicy)
 	if err != nil {
-		return false, err
+		return nil, nil, err
 	}
-	return areEqualJSON(decodedPolicy, expectedPolicy)
-}
 
-func areEqualJSON(s1, s2 string) (bool, error) {
-	var o1 any
-	var o2 any
-
-	var err error
-	err = json.Unmarshal([]byte(s1), &o1)
+	actual, err := normalizePolicy(decodedPolicy)
 	if err != nil {
-		return false, err
+		return nil, nil, err
 	}
-	err = json.Unmarshal([]byte(s2), &o2)
+	expected, err := normalizePolicy(expectedPolicy)
 	if err != nil {
-		return false, err
+		return nil, nil, err
 	}
 
-	return reflect.DeepEqual(o1, o2), nil
+	return actual, expected, nil
 }
 
 func urlDecode(encodedValue string) (string, error) {
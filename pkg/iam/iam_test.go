@@ -4,11 +4,18 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
 
+	"github.com/Masterminds/semver/v3"
 	"github.com/aws/aws-sdk-go-v2/aws"
-	"github.com/aws/aws-sdk-go-v2/config"
+	awseks "github.com/aws/aws-sdk-go-v2/service/eks"
+	ekstypes "github.com/aws/aws-sdk-go-v2/service/eks/types"
 	awsiam "github.com/aws/aws-sdk-go-v2/service/iam"
 	awsiamtypes "github.com/aws/aws-sdk-go-v2/service/iam/types"
+	"github.com/aws/smithy-go"
 	"github.com/golang/mock/gomock"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
@@ -20,48 +27,153 @@ import (
 
 const awsIPAMModeLabel = "alpha.aws.giantswarm.io/ipam-mode"
 
+const (
+	controlPlaneRoleDescription = "Control plane nodes of cluster test-cluster, managed by capa-iam-operator"
+	bastionRoleDescription      = "Bastion hosts of cluster test-cluster, managed by capa-iam-operator"
+)
+
+// ownedTags are the tags of the IAM resources the operator created for the test cluster.
+var ownedTags = []awsiamtypes.Tag{
+	{Key: aws.String(iam.IAMControllerOwnedTag), Value: aws.String("")},
+	{Key: aws.String("sigs.k8s.io/cluster-api-provider-aws/cluster/test-cluster"), Value: aws.String("owned")},
+}
+
 func isValidJSON(s string) bool {
 	var out any
 	return json.Unmarshal([]byte(s), &out) == nil
 }
 
-var _ = Describe("ReconcileRole", func() {
+// newMockIAMClient returns a mocked IAM client whose expectations are verified at the end of the spec.
+func newMockIAMClient() *mocks.MockIAMClient {
+	mockCtrl := gomock.NewController(GinkgoT())
+	DeferCleanup(mockCtrl.Finish)
+	return mocks.NewMockIAMClient(mockCtrl)
+}
+
+// newMockEKSClient returns a mocked EKS client whose expectations are verified at the end of the spec.
+func newMockEKSClient() *mocks.MockEKSClient {
+	mockCtrl := gomock.NewController(GinkgoT())
+	DeferCleanup(mockCtrl.Finish)
+	return mocks.NewMockEKSClient(mockCtrl)
+}
+
+// testConfig returns the configuration of the IAM service for the main role of the given type of the test cluster,
+// using the mocked IAM client. Specs only set the fields they are about.
+func testConfig(roleType string, mockIAMClient iam.IAMClient) iam.IAMServiceConfig {
+	return iam.IAMServiceConfig{
+		ClusterName:    "test-cluster",
+		ClusterRelease: "33.0.0",
+		MainRoleName:   "test-role",
+		Region:         "test-region",
+		RoleType:       roleType,
+		Log:            ctrl.Log,
+		AWSConfig:      aws.NewConfig(),
+		IAMClientFactory: func(_ aws.Config, _ string) iam.IAMClient {
+			return mockIAMClient
+		},
+	}
+}
+
+// podIdentityConfig returns the configuration of a service that trusts EKS Pod Identity only for the given IRSA role
+// type.
+func podIdentityConfig(irsaRoleType string, mockIAMClient iam.IAMClient, mockEKSClient iam.EKSClient) iam.IAMServiceConfig {
+	config := testConfig(iam.IRSARole, mockIAMClient)
+	config.Region = "eu-west-1"
+	config.EKSClientFactory = func(_ aws.Config, _ string) iam.EKSClient {
+		return mockEKSClient
+	}
+	config.IRSARoleTypes = []string{irsaRoleType}
+	config.ServiceAccountTrust = []iam.TrustKind{iam.TrustKindPodIdentity}
+	config.EKSClusterName = "test-eks-cluster"
+	return config
+}
+
+// newTestService returns the IAM service for the configuration.
+func newTestService(config iam.IAMServiceConfig) *iam.IAMService {
+	GinkgoHelper()
+	iamService, err := iam.New(config)
+	Expect(err).To(BeNil())
+	return iamService
+}
+
+// existingRole returns a role created by the operator whose settings are up to date.
+func existingRole(description string) *awsiamtypes.Role {
+	return &awsiamtypes.Role{
+		Description:        aws.String(description),
+		MaxSessionDuration: aws.Int32(3600),
+		Tags:               ownedTags,
+	}
+}
 
+// expectExistingRole sets up the mocked IAM client for an existing role whose instance profile is up to date and
+// tagged with profileTags, or the owned tags by default. The trust policy of a role without one is updated. gomock
+// uses the first matching expectation, so expectations of a spec on these calls must be set up before.
+func expectExistingRole(mockIAMClient *mocks.MockIAMClient, role *awsiamtypes.Role, profileTags ...awsiamtypes.Tag) {
+	if len(profileTags) == 0 {
+		profileTags = ownedTags
+	}
+	mockIAMClient.EXPECT().GetRole(context.TODO(), gomock.Any()).Return(&awsiam.GetRoleOutput{Role: role}, nil).AnyTimes()
+	if role.AssumeRolePolicyDocument == nil {
+		mockIAMClient.EXPECT().UpdateAssumeRolePolicy(context.TODO(), gomock.Any()).Return(&awsiam.UpdateAssumeRolePolicyOutput{}, nil).AnyTimes()
+	}
+	mockIAMClient.EXPECT().GetInstanceProfile(context.TODO(), gomock.Any()).DoAndReturn(func(_ context.Context, in *awsiam.GetInstanceProfileInput, _ ...func(*awsiam.Options)) (*awsiam.GetInstanceProfileOutput, error) {
+		return &awsiam.GetInstanceProfileOutput{InstanceProfile: &awsiamtypes.InstanceProfile{
+			Roles: []awsiamtypes.Role{{RoleName: in.InstanceProfileName}},
+			Tags:  profileTags,
+		}}, nil
+	}).AnyTimes()
+}
+
+// expectNoManagedPolicies sets up the mocked IAM client for roles without attached managed policies.
+func expectNoManagedPolicies(mockIAMClient *mocks.MockIAMClient) {
+	mockIAMClient.EXPECT().ListAttachedRolePolicies(context.TODO(), gomock.Any()).Return(&awsiam.ListAttachedRolePoliciesOutput{}, nil).AnyTimes()
+}
+
+// expectNewRole sets up the mocked IAM client for a role that does not exist yet and is created together with its
+// instance profile. checkRole, if set, checks the request to create the role.
+func expectNewRole(mockIAMClient *mocks.MockIAMClient, checkRole func(*awsiam.CreateRoleInput)) {
+	mockIAMClient.EXPECT().GetRole(context.TODO(), gomock.Any()).Return(nil, &awsiamtypes.NoSuchEntityException{})
+	mockIAMClient.EXPECT().CreateRole(context.TODO(), gomock.Any()).DoAndReturn(func(_ context.Context, in *awsiam.CreateRoleInput, _ ...func(*awsiam.Options)) (*awsiam.CreateRoleOutput, error) {
+		if checkRole != nil {
+			checkRole(in)
+		}
+		return &awsiam.CreateRoleOutput{}, nil
+	})
+	mockIAMClient.EXPECT().GetInstanceProfile(context.TODO(), gomock.Any()).Return(nil, &awsiamtypes.NoSuchEntityException{})
+	mockIAMClient.EXPECT().CreateInstanceProfile(context.TODO(), gomock.Any()).Return(&awsiam.CreateInstanceProfileOutput{}, nil)
+	mockIAMClient.EXPECT().AddRoleToInstanceProfile(context.TODO(), gomock.Any()).Return(&awsiam.AddRoleToInstanceProfileOutput{}, nil)
+}
+
+var _ = Describe("ReconcileRole", func() {
 	var (
-		mockCtrl      *gomock.Controller
 		mockIAMClient *mocks.MockIAMClient
 		iamService    *iam.IAMService
-		err           error
 	)
 
-	const controlPlanePolicyTemplate = "%7B%0A%09%09%22Version%22%3A%20%222012-10-17%22%2C%0A%09%09%22Statement%22%3A%20%5B%0A%09%09%20%20%7B%0A%09%09%09%22Action%22%3A%20%22elasticloadbalancing%3A*%22%2C%0A%09%09%09%22Resource%22%3A%20%22*%22%2C%0A%09%09%09%22Effect%22%3A%20%22Allow%22%0A%09%09%20%20%7D%2C%0A%09%09%20%20%7B%0A%09%09%09%22Action%22%3A%20%5B%0A%09%09%09%20%20%22autoscaling%3ADescribeAutoScalingGroups%22%2C%0A%09%09%09%20%20%22autoscaling%3ADescribeAutoScalingInstances%22%2C%0A%09%09%09%20%20%22autoscaling%3ADescribeTags%22%2C%0A%09%09%09%20%20%22autoscaling%3ADescribeLaunchConfigurations%22%2C%0A%09%09%09%20%20%22ec2%3ADescribeLaunchTemplateVersions%22%0A%09%09%09%5D%2C%0A%09%09%09%22Resource%22%3A%20%22*%22%2C%0A%09%09%09%22Effect%22%3A%20%22Allow%22%0A%09%09%20%20%7D%2C%0A%09%09%20%20%7B%0A%09%09%09%22Condition%22%3A%20%7B%0A%09%09%09%20%20%22StringEquals%22%3A%20%7B%0A%09%09%09%09%22autoscaling%3AResourceTag%2Fsigs.k8s.io%2Fcluster-api-provider-aws%2Fcluster%2Ftest-cluster%22%3A%20%22owned%22%0A%09%09%09%20%20%7D%0A%09%09%09%7D%2C%0A%09%09%09%22Action%22%3A%20%5B%0A%09%09%09%20%20%22autoscaling%3ASetDesiredCapacity%22%2C%0A%09%09%09%20%20%22autoscaling%3ATerminateInstanceInAutoScalingGroup%22%0A%09%09%09%5D%2C%0A%09%09%09%22Resource%22%3A%20%22*%22%2C%0A%09%09%09%22Effect%22%3A%20%22Allow%22%0A%09%09%20%20%7D%2C%0A%09%09%20%20%7B%0A%09%09%09%22Action%22%3A%20%5B%0A%09%09%09%20%20%22ecr%3AGetAuthorizationToken%22%2C%0A%09%09%09%20%20%22ecr%3ABatchCheckLayerAvailability%22%2C%0A%09%09%09%20%20%22ecr%3AGetDownloadUrlForLayer%22%2C%0A%09%09%09%20%20%22ecr%3AGetRepositoryPolicy%22%2C%0A%09%09%09%20%20%22ecr%3ADescribeRepositories%22%2C%0A%09%09%09%20%20%22ecr%3AListImages%22%2C%0A%09%09%09%20%20%22ecr%3ABatchGetImage%22%0A%09%09%09%5D%2C%0A%09%09%09%22Resource%22%3A%20%22*%22%2C%0A%09%09%09%22Effect%22%3A%20%22Allow%22%0A%09%09%20%20%7D%2C%0A%09%09%20%20%7B%0A%09%09%09%22Action%22%3A%20%5B%0A%09%09%09%20%20%22ec2%3AAssignPrivateIpAddresses%22%2C%0A%09%09%09%20%20%22ec2%3AAttachNetworkInterface%22%2C%0A%09%09%09%20%20%22ec2%3ACreateNetworkInterface%22%2C%0A%09%09%09%20%20%22ec2%3ADeleteNetworkInterface%22%2C%0A%09%09%09%20%20%22ec2%3ADescribeInstances%22%2C%0A%09%09%09%20%20%22ec2%3ADescribeInstanceTypes%22%2C%0A%09%09%09%20%20%22ec2%3ADescribeTags%22%2C%0A%09%09%09%20%20%22ec2%3ADescribeNetworkInterfaces%22%2C%0A%09%09%09%20%20%22ec2%3ADetachNetworkInterface%22%2C%0A%09%09%09%20%20%22ec2%3AModifyNetworkInterfaceAttribute%22%2C%0A%09%09%09%20%20%22ec2%3AUnassignPrivateIpAddresses%22%0A%09%09%09%5D%2C%0A%09%09%09%22Resource%22%3A%20%22*%22%2C%0A%09%09%09%22Effect%22%3A%20%22Allow%22%0A%09%09%20%20%7D%2C%0A%09%09%20%20%7B%0A%09%09%09%22Action%22%3A%20%5B%0A%09%09%09%20%20%22autoscaling%3ADescribeAutoScalingGroups%22%2C%0A%09%09%09%20%20%22autoscaling%3ADescribeLaunchConfigurations%22%2C%0A%09%09%09%20%20%22autoscaling%3ADescribeTags%22%2C%0A%09%09%09%20%20%22ec2%3ADescribeAvailabilityZones%22%2C%0A%09%09%09%20%20%22ec2%3ADescribeInstances%22%2C%0A%09%09%09%20%20%22ec2%3ADescribeImages%22%2C%0A%09%09%09%20%20%22ec2%3ADescribeRegions%22%2C%0A%09%09%09%20%20%22ec2%3ADescribeRouteTables%22%2C%0A%09%09%09%20%20%22ec2%3ADescribeSecurityGroups%22%2C%0A%09%09%09%20%20%22ec2%3ADescribeSubnets%22%2C%0A%09%09%09%20%20%22ec2%3ADescribeVolumes%22%2C%0A%09%09%09%20%20%22ec2%3ACreateSecurityGroup%22%2C%0A%09%09%09%20%20%22ec2%3ACreateTags%22%2C%0A%09%09%09%20%20%22ec2%3ACreateVolume%22%2C%0A%09%09%09%20%20%22ec2%3AModifyInstanceAttribute%22%2C%0A%09%09%09%20%20%22ec2%3AModifyVolume%22%2C%0A%09%09%09%20%20%22ec2%3AAttachVolume%22%2C%0A%09%09%09%20%20%22ec2%3ADescribeVolumesModifications%22%2C%0A%09%09%09%20%20%22ec2%3AAuthorizeSecurityGroupIngress%22%2C%0A%09%09%09%20%20%22ec2%3ACreateRoute%22%2C%0A%09%09%09%20%20%22ec2%3ADeleteRoute%22%2C%0A%09%09%09%20%20%22ec2%3ADeleteSecurityGroup%22%2C%0A%09%09%09%20%20%22ec2%3ADeleteVolume%22%2C%0A%09%09%09%20%20%22ec2%3ADetachVolume%22%2C%0A%09%09%09%20%20%22ec2%3ARevokeSecurityGroupIngress%22%2C%0A%09%09%09%20%20%22ec2%3ADescribeVpcs%22%2C%0A%09%09%09%20%20%22ec2%3ADescribeInstanceTopology%22%2C%0A%09%09%09%20%20%22elasticloadbalancing%3AAddTags%22%2C%0A%09%09%09%20%20%22elasticloadbalancing%3AAttachLoadBalancerToSubnets%22%2C%0A%09%09%09%20%20%22elasticloadbalancing%3AApplySecurityGroupsToLoadBalancer%22%2C%0A%09%09%09%20%20%22elasticloadbalancing%3ACreateLoadBalancer%22%2C%0A%09%09%09%20%20%22elasticloadbalancing%3ACreateLoadBalancerPolicy%22%2C%0A%09%09%09%20%20%22elasticloadbalancing%3ACreateLoadBalancerListeners%22%2C%0A%09%09%09%20%20%22elasticloadbalancing%3AConfigureHealthCheck%22%2C%0A%09%09%09%20%20%22elasticloadbalancing%3ADeleteLoadBalancer%22%2C%0A%09%09%09%20%20%22elasticloadbalancing%3ADeleteLoadBalancerListeners%22%2C%0A%09%09%09%20%20%22elasticloadbalancing%3ADescribeLoadBalancers%22%2C%0A%09%09%09%20%20%22elasticloadbalancing%3ADescribeLoadBalancerAttributes%22%2C%0A%09%09%09%20%20%22elasticloadbalancing%3ADetachLoadBalancerFromSubnets%22%2C%0A%09%09%09%20%20%22elasticloadbalancing%3ADeregisterInstancesFromLoadBalancer%22%2C%0A%09%09%09%20%20%22elasticloadbalancing%3AModifyLoadBalancerAttributes%22%2C%0A%09%09%09%20%20%22elasticloadbalancing%3ARegisterInstancesWithLoadBalancer%22%2C%0A%09%09%09%20%20%22elasticloadbalancing%3ASetLoadBalancerPoliciesForBackendServer%22%2C%0A%09%09%09%20%20%22elasticloadbalancing%3AAddTags%22%2C%0A%09%09%09%20%20%22elasticloadbalancing%3ACreateListener%22%2C%0A%09%09%09%20%20%22elasticloadbalancing%3ACreateTargetGroup%22%2C%0A%09%09%09%20%20%22elasticloadbalancing%3ADeleteListener%22%2C%0A%09%09%09%20%20%22elasticloadbalancing%3ADeleteTargetGroup%22%2C%0A%09%09%09%20%20%22elasticloadbalancing%3ADescribeListeners%22%2C%0A%09%09%09%20%20%22elasticloadbalancing%3ADescribeLoadBalancerPolicies%22%2C%0A%09%09%09%20%20%22elasticloadbalancing%3ADescribeTargetGroups%22%2C%0A%09%09%09%20%20%22elasticloadbalancing%3ADescribeTargetHealth%22%2C%0A%09%09%09%20%20%22elasticloadbalancing%3AModifyListener%22%2C%0A%09%09%09%20%20%22elasticloadbalancing%3AModifyTargetGroup%22%2C%0A%09%09%09%20%20%22elasticloadbalancing%3ARegisterTargets%22%2C%0A%09%09%09%20%20%22elasticloadbalancing%3ADeregisterTargets%22%2C%0A%09%09%09%20%20%22elasticloadbalancing%3ASetLoadBalancerPoliciesOfListener%22%2C%0A%09%09%09%20%20%22iam%3ACreateServiceLinkedRole%22%2C%0A%09%09%09%20%20%22kms%3ADescribeKey%22%0A%09%09%09%5D%2C%0A%09%09%09%22Resource%22%3A%20%5B%0A%09%09%09%20%20%22*%22%0A%09%09%09%5D%2C%0A%09%09%09%22Effect%22%3A%20%22Allow%22%0A%09%09%20%20%7D%2C%0A%09%09%20%20%7B%0A%09%09%09%22Action%22%3A%20%5B%0A%09%09%09%20%20%22secretsmanager%3AGetSecretValue%22%2C%0A%09%09%09%20%20%22secretsmanager%3ADeleteSecret%22%0A%09%09%09%5D%2C%0A%09%09%09%22Resource%22%3A%20%22arn%3A*%3Asecretsmanager%3A*%3A*%3Asecret%3Aaws.cluster.x-k8s.io%2F*%22%2C%0A%09%09%09%22Effect%22%3A%20%22Allow%22%0A%09%09%20%20%7D%0A%09%09%5D%0A%09%20%20%7D"
+	const controlPlanePolicyTemplate = "%7B%0A%09%09%22Version%22%3A%20%222012-10-17%22%2C%0A%09%09%22Statement%22%3A%20%5B%0A%09%09%20%20%7B%0A%09%09%09%22Action%22%3A%20%22elasticloadbalancing%3A*%22%2C%0A%09%09%09%22Resource%22%3A%20%22*%22%2C%0A%09%09%09%22Effect%22%3A%20%22Allow%22%0A%09%09%20%20%7D%2C%0A%09%09%20%20%7B%0A%09%09%09%22Action%22%3A%20%5B%0A%09%09%09%20%20%22autoscaling%3ADescribeAutoScalingGroups%22%2C%0A%09%09%09%20%20%22autoscaling%3ADescribeAutoScalingInstances%22%2C%0A%09%09%09%20%20%22autoscaling%3ADescribeTags%22%2C%0A%09%09%09%20%20%22autoscaling%3ADescribeLaunchConfigurations%22%2C%0A%09%09%09%20%20%22ec2%3ADescribeLaunchTemplateVersions%22%0A%09%09%09%5D%2C%0A%09%09%09%22Resource%22%3A%20%22*%22%2C%0A%09%09%09%22Effect%22%3A%20%22Allow%22%0A%09%09%20%20%7D%2C%0A%09%09%20%20%7B%0A%09%09%09%22Condition%22%3A%20%7B%0A%09%09%09%20%20%22StringEquals%22%3A%20%7B%0A%09%09%09%09%22autoscaling%3AResourceTag%2Fsigs.k8s.io%2Fcluster-api-provider-aws%2Fcluster%2Ftest-cluster%22%3A%20%22owned%22%0A%09%09%09%20%20%7D%0A%09%09%09%7D%2C%0A%09%09%09%22Action%22%3A%20%5B%0A%09%09%09%20%20%22autoscaling%3ASetDesiredCapacity%22%2C%0A%09%09%09%20%20%22autoscaling%3ATerminateInstanceInAutoScalingGroup%22%0A%09%09%09%5D%2C%0A%09%09%09%22Resource%22%3A%20%22*%22%2C%0A%09%09%09%22Effect%22%3A%20%22Allow%22%0A%09%09%20%20%7D%2C%0A%09%09%20%20%7B%0A%09%09%09%22Action%22%3A%20%5B%0A%09%09%09%20%20%22ecr%3AGetAuthorizationToken%22%2C%0A%09%09%09%20%20%22ecr%3ABatchCheckLayerAvailability%22%2C%0A%09%09%09%20%20%22ecr%3AGetDownloadUrlForLayer%22%2C%0A%09%09%09%20%20%22ecr%3AGetRepositoryPolicy%22%2C%0A%09%09%09%20%20%22ecr%3ADescribeRepositories%22%2C%0A%09%09%09%20%20%22ecr%3AListImages%22%2C%0A%09%09%09%20%20%22ecr%3ABatchGetImage%22%0A%09%09%09%5D%2C%0A%09%09%09%22Resource%22%3A%20%22*%22%2C%0A%09%09%09%22Effect%22%3A%20%22Allow%22%0A%09%09%20%20%7D%2C%0A%09%09%20%20%7B%0A%09%09%09%22Action%22%3A%20%5B%0A%09%09%09%20%20%22ec2%3AAssignPrivateIpAddresses%22%2C%0A%09%09%09%20%20%22ec2%3AAttachNetworkInterface%22%2C%0A%09%09%09%20%20%22ec2%3ACreateNetworkInterface%22%2C%0A%09%09%09%20%20%22ec2%3ADeleteNetworkInterface%22%2C%0A%09%09%09%20%20%22ec2%3ADescribeInstances%22%2C%0A%09%09%09%20%20%22ec2%3ADescribeInstanceTypes%22%2C%0A%09%09%09%20%20%22ec2%3ADescribeTags%22%2C%0A%09%09%09%20%20%22ec2%3ADescribeNetworkInterfaces%22%2C%0A%09%09%09%20%20%22ec2%3ADetachNetworkInterface%22%2C%0A%09%09%09%20%20%22ec2%3AModifyNetworkInterfaceAttribute%22%2C%0A%09%09%09%20%20%22ec2%3AUnassignPrivateIpAddresses%22%0A%09%09%09%5D%2C%0A%09%09%09%22Resource%22%3A%20%22*%22%2C%0A%09%09%09%22Effect%22%3A%20%22Allow%22%0A%09%09%20%20%7D%2C%0A%09%09%20%20%7B%0A%09%09%09%22Action%22%3A%20%5B%0A%09%09%09%20%20%22autoscaling%3ADescribeAutoScalingGroups%22%2C%0A%09%09%09%20%20%22autoscaling%3ADescribeLaunchConfigurations%22%2C%0A%09%09%09%20%20%22autoscaling%3ADescribeTags%22%2C%0A%09%09%09%20%20%22ec2%3ADescribeAvailabilityZones%22%2C%0A%09%09%09%20%20%22ec2%3ADescribeInstances%22%2C%0A%09%09%09%20%20%22ec2%3ADescribeImages%22%2C%0A%09%09%09%20%20%22ec2%3ADescribeRegions%22%2C%0A%09%09%09%20%20%22ec2%3ADescribeRouteTables%22%2C%0A%09%09%09%20%20%22ec2%3ADescribeSecurityGroups%22%2C%0A%09%09%09%20%20%22ec2%3ADescribeSubnets%22%2C%0A%09%09%09%20%20%22ec2%3ADescribeVolumes%22%2C%0A%09%09%09%20%20%22ec2%3ACreateSecurityGroup%22%2C%0A%09%09%09%20%20%22ec2%3ACreateTags%22%2C%0A%09%09%09%20%20%22ec2%3ACreateVolume%22%2C%0A%09%09%09%20%20%22ec2%3AModifyInstanceAttribute%22%2C%0A%09%09%09%20%20%22ec2%3AModifyVolume%22%2C%0A%09%09%09%20%20%22ec2%3AAttachVolume%22%2C%0A%09%09%09%20%20%22ec2%3ADescribeVolumesModifications%22%2C%0A%09%09%09%20%20%22ec2%3AAuthorizeSecurityGroupIngress%22%2C%0A%09%09%09%20%20%22ec2%3ACreateRoute%22%2C%0A%09%09%09%20%20%22ec2%3ADeleteRoute%22%2C%0A%09%09%09%20%20%22ec2%3ADeleteSecurityGroup%22%2C%0A%09%09%09%20%20%22ec2%3ADeleteVolume%22%2C%0A%09%09%09%20%20%22ec2%3ADetachVolume%22%2C%0A%09%09%09%20%20%22ec2%3ARevokeSecurityGroupIngress%22%2C%0A%09%09%09%20%20%22ec2%3ADescribeVpcs%22%2C%0A%09%09%09%20%20%22ec2%3ADescribeInstanceTopology%22%2C%0A%09%09%09%20%20%22elasticloadbalancing%3AAddTags%22%2C%0A%09%09%09%20%20%22elasticloadbalancing%3AAttachLoadBalancerToSubnets%22%2C%0A%09%09%09%20%20%22elasticloadbalancing%3AApplySecurityGroupsToLoadBalancer%22%2C%0A%09%09%09%20%20%22elasticloadbalancing%3ACreateLoadBalancer%22%2C%0A%09%09%09%20%20%22elasticloadbalancing%3ACreateLoadBalancerPolicy%22%2C%0A%09%09%09%20%20%22elasticloadbalancing%3ACreateLoadBalancerListeners%22%2C%0A%09%09%09%20%20%22elasticloadbalancing%3AConfigureHealthCheck%22%2C%0A%09%09%09%20%20%22elasticloadbalancing%3ADeleteLoadBalancer%22%2C%0A%09%09%09%20%20%22elasticloadbalancing%3ADeleteLoadBalancerListeners%22%2C%0A%09%09%09%20%20%22elasticloadbalancing%3ADescribeLoadBalancers%22%2C%0A%09%09%09%20%20%22elasticloadbalancing%3ADescribeLoadBalancerAttributes%22%2C%0A%09%09%09%20%20%22elasticloadbalancing%3ADetachLoadBalancerFromSubnets%22%2C%0A%09%09%09%20%20%22elasticloadbalancing%3ADeregisterInstancesFromLoadBalancer%22%2C%0A%09%09%09%20%20%22elasticloadbalancing%3AModifyLoadBalancerAttributes%22%2C%0A%09%09%09%20%20%22elasticloadbalancing%3ARegisterInstancesWithLoadBalancer%22%2C%0A%09%09%09%20%20%22elasticloadbalancing%3ASetLoadBalancerPoliciesForBackendServer%22%2C%0A%09%09%09%20%20%22elasticloadbalancing%3AAddTags%22%2C%0A%09%09%09%20%20%22elasticloadbalancing%3ACreateListener%22%2C%0A%09%09%09%20%20%22elasticloadbalancing%3ACreateTargetGroup%22%2C%0A%09%09%09%20%20%22elasticloadbalancing%3ADeleteListener%22%2C%0A%09%09%09%20%20%22elasticloadbalancing%3ADeleteTargetGroup%22%2C%0A%09%09%09%20%20%22elasticloadbalancing%3ADescribeListeners%22%2C%0A%09%09%09%20%20%22elasticloadbalancing%3ADescribeLoadBalancerPolicies%22%2C%0A%09%09%09%20%20%22elasticloadbalancing%3ADescribeTargetGroups%22%2C%0A%09%09%09%20%20%22elasticloadbalancing%3ADescribeTargetHealth%22%2C%0A%09%09%09%20%20%22elasticloadbalancing%3AModifyListener%22%2C%0A%09%09%09%20%20%22elasticloadbalancing%3AModifyTargetGroup%22%2C%0A%09%09%09%20%20%22elasticloadbalancing%3ARegisterTargets%22%2C%0A%09%09%09%20%20%22elasticloadbalancing%3ADeregisterTargets%22%2C%0A%09%09%09%20%20%22elasticloadbalancing%3ASetLoadBalancerPoliciesOfListener%22%2C%0A%09%09%09%20%20%22iam%3ACreateServiceLinkedRole%22%2C%0A%09%09%09%20%20%22kms%3ADescribeKey%22%0A%09%09%09%5D%2C%0A%09%09%09%22Resource%22%3A%20%5B%0A%09%09%09%20%20%22*%22%0A%09%09%09%5D%2C%0A%09%09%09%22Effect%22%3A%20%22Allow%22%0A%09%09%20%20%7D%2C%0A%09%09%20%20%7B%0A%09%09%09%22Action%22%3A%20%5B%0A%09%09%09%20%20%22secretsmanager%3AGetSecretValue%22%2C%0A%09%09%09%20%20%22secretsmanager%3ADeleteSecret%22%0A%09%09%09%5D%2C%0A%09%09%09%22Resource%22%3A%20%22arn%3Aaws%3Asecretsmanager%3A*%3A*%3Asecret%3Aaws.cluster.x-k8s.io%2F*%22%2C%0A%09%09%09%22Effect%22%3A%20%22Allow%22%0A%09%09%20%20%7D%0A%09%09%5D%0A%09%20%20%7D"
+
+	const ec2TrustPolicy = "%7B%22Version%22%3A%222012-10-17%22%2C%22Statement%22%3A%5B%7B%22Effect%22%3A%22Allow%22%2C%22Principal%22%3A%7B%22Service%22%3A%22ec2.amazonaws.com%22%7D%2C%22Action%22%3A%22sts%3AAssumeRole%22%7D%5D%7D"
+
+	// role is an up-to-date control plane role
+	role := func() *awsiamtypes.Role {
+		role := existingRole(controlPlaneRoleDescription)
+		role.AssumeRolePolicyDocument = aws.String(ec2TrustPolicy)
+		return role
+	}
 
 	BeforeEach(func() {
-		mockCtrl = gomock.NewController(GinkgoT())
-		mockIAMClient = mocks.NewMockIAMClient(mockCtrl)
-
-		iamConfig := iam.IAMServiceConfig{
-			ClusterName:      "test-cluster",
-			ClusterRelease:   "33.0.0",
-			MainRoleName:     "test-role",
-			Region:           "test-region",
-			RoleType:         "control-plane",
-			PrincipalRoleARN: "test-principal-role-arn",
-			Log:              ctrl.Log,
-			AWSConfig:        aws.NewConfig(),
-			IAMClientFactory: func(_ aws.Config, _ string) iam.IAMClient {
-				return mockIAMClient
-			},
-		}
-		iamService, err = iam.New(iamConfig)
-		Expect(err).To(BeNil())
+		mockIAMClient = newMockIAMClient()
+
+		iamConfig := testConfig(iam.ControlPlaneRole, mockIAMClient)
+		iamConfig.PrincipalRoleARN = "test-principal-role-arn"
+		iamService = newTestService(iamConfig)
+
+		expectNoManagedPolicies(mockIAMClient)
 	})
 
 	When("role is present", func() {
 		BeforeEach(func() {
-			mockIAMClient.EXPECT().GetRole(context.TODO(), gomock.Any()).Return(&awsiam.GetRoleOutput{Role: &awsiamtypes.Role{
-				Tags: []awsiamtypes.Tag{{Key: aws.String("capi-iam-controller/owned"), Value: aws.String("test-cluster")}},
-			}}, nil).AnyTimes()
+			expectExistingRole(mockIAMClient, role())
 		})
 		When("inline policy is already attached", func() {
 			BeforeEach(func() {
@@ -88,15 +200,102 @@ var _ = Describe("ReconcileRole", func() {
 		})
 	})
 
-	When("role is not present", func() {
+	When("trust policy of the role was modified", func() {
 		BeforeEach(func() {
-			mockIAMClient.EXPECT().GetRole(context.TODO(), gomock.Any()).Return(&awsiam.GetRoleOutput{}, &awsiamtypes.NoSuchEntityException{}).Times(1)
-			mockIAMClient.EXPECT().CreateRole(context.TODO(), gomock.Any()).Return(&awsiam.CreateRoleOutput{}, nil)
-			mockIAMClient.EXPECT().CreateInstanceProfile(context.TODO(), gomock.Any()).Return(&awsiam.CreateInstanceProfileOutput{}, nil)
-			mockIAMClient.EXPECT().AddRoleToInstanceProfile(context.TODO(), gomock.Any()).Return(&awsiam.AddRoleToInstanceProfileOutput{}, nil)
-			mockIAMClient.EXPECT().GetRole(context.TODO(), gomock.Any()).Return(&awsiam.GetRoleOutput{Role: &awsiamtypes.Role{
-				Tags: []awsiamtypes.Tag{{Key: aws.String("capi-iam-controller/owned"), Value: aws.String("test-cluster")}},
+			modified := role()
+			modified.AssumeRolePolicyDocument = aws.String("%7B%22Version%22%3A%222012-10-17%22%2C%22Statement%22%3A%5B%7B%22Effect%22%3A%22Allow%22%2C%22Principal%22%3A%7B%22AWS%22%3A%22arn%3Aaws%3Aiam%3A%3A999999999999%3Aroot%22%7D%2C%22Action%22%3A%22sts%3AAssumeRole%22%7D%5D%7D")
+			expectExistingRole(mockIAMClient, modified)
+			mockIAMClient.EXPECT().GetRolePolicy(context.TODO(), gomock.Any()).Return(&awsiam.GetRolePolicyOutput{
+				PolicyDocument: aws.String(controlPlanePolicyTemplate),
+				PolicyName:     aws.String("control-plane-test-cluster-policy"),
+				RoleName:       aws.String("test-role"),
+			}, nil).AnyTimes()
+		})
+		It("should restore the trust policy", func() {
+			mockIAMClient.EXPECT().UpdateAssumeRolePolicy(context.TODO(), gomock.Any()).DoAndReturn(func(ctx context.Context, input *awsiam.UpdateAssumeRolePolicyInput, optFns ...func(*awsiam.Options)) (*awsiam.UpdateAssumeRolePolicyOutput, error) {
+				Expect(input.RoleName).To(BeComparableTo(aws.String("test-role")))
+				Expect(*input.PolicyDocument).To(ContainSubstring(`"Service": "ec2.amazonaws.com"`))
+				Expect(*input.PolicyDocument).NotTo(ContainSubstring("999999999999"))
+				return &awsiam.UpdateAssumeRolePolicyOutput{}, nil
+			})
+
+			err := iamService.ReconcileRole()
+			Expect(err).To(BeNil())
+		})
+	})
+
+	When("instance profile of an existing role is missing", func() {
+		BeforeEach(func() {
+			mockIAMClient.EXPECT().GetRole(context.TODO(), gomock.Any()).Return(&awsiam.GetRoleOutput{Role: role()}, nil).AnyTimes()
+			mockIAMClient.EXPECT().GetRolePolicy(context.TODO(), gomock.Any()).Return(&awsiam.GetRolePolicyOutput{
+				PolicyDocument: aws.String(controlPlanePolicyTemplate),
+				PolicyName:     aws.String("control-plane-test-cluster-policy"),
+				RoleName:       aws.String("test-role"),
+			}, nil).AnyTimes()
+		})
+		It("should create the instance profile and add the role", func() {
+			gomock.InOrder(
+				mockIAMClient.EXPECT().GetInstanceProfile(context.TODO(), gomock.Any()).Return(nil, &awsiamtypes.NoSuchEntityException{}),
+				mockIAMClient.EXPECT().CreateInstanceProfile(context.TODO(), &awsiam.CreateInstanceProfileInput{
+					InstanceProfileName: aws.String("test-role"),
+					Path:                aws.String("/"),
+					Tags:                ownedTags,
+				}).Return(&awsiam.CreateInstanceProfileOutput{}, nil),
+				mockIAMClient.EXPECT().AddRoleToInstanceProfile(context.TODO(), &awsiam.AddRoleToInstanceProfileInput{
+					InstanceProfileName: aws.String("test-role"),
+					RoleName:            aws.String("test-role"),
+				}).Return(&awsiam.AddRoleToInstanceProfileOutput{}, nil),
+				mockIAMClient.EXPECT().GetInstanceProfile(context.TODO(), gomock.Any()).Return(&awsiam.GetInstanceProfileOutput{InstanceProfile: &awsiamtypes.InstanceProfile{
+					Roles: []awsiamtypes.Role{{RoleName: aws.String("test-role")}},
+					Tags:  ownedTags,
+				}}, nil),
+			)
+
+			err := iamService.ReconcileRole()
+			Expect(err).To(BeNil())
+		})
+	})
+
+	When("instance profile of an existing role is empty", func() {
+		BeforeEach(func() {
+			mockIAMClient.EXPECT().GetRole(context.TODO(), gomock.Any()).Return(&awsiam.GetRoleOutput{Role: role()}, nil).AnyTimes()
+			mockIAMClient.EXPECT().GetInstanceProfile(context.TODO(), gomock.Any()).Return(&awsiam.GetInstanceProfileOutput{InstanceProfile: &awsiamtypes.InstanceProfile{
+				Tags: ownedTags,
+			}}, nil).AnyTimes()
+			mockIAMClient.EXPECT().GetRolePolicy(context.TODO(), gomock.Any()).Return(&awsiam.GetRolePolicyOutput{
+				PolicyDocument: aws.String(controlPlanePolicyTemplate),
+				PolicyName:     aws.String("control-plane-test-cluster-policy"),
+				RoleName:       aws.String("test-role"),
+			}, nil).AnyTimes()
+		})
+		It("should add the role to the instance profile", func() {
+			mockIAMClient.EXPECT().AddRoleToInstanceProfile(context.TODO(), &awsiam.AddRoleToInstanceProfileInput{
+				InstanceProfileName: aws.String("test-role"),
+				RoleName:            aws.String("test-role"),
+			}).Return(&awsiam.AddRoleToInstanceProfileOutput{}, nil)
+
+			err := iamService.ReconcileRole()
+			Expect(err).To(BeNil())
+		})
+	})
+
+	When("instance profile contains a different role", func() {
+		BeforeEach(func() {
+			mockIAMClient.EXPECT().GetRole(context.TODO(), gomock.Any()).Return(&awsiam.GetRoleOutput{Role: role()}, nil).AnyTimes()
+			mockIAMClient.EXPECT().GetInstanceProfile(context.TODO(), gomock.Any()).Return(&awsiam.GetInstanceProfileOutput{InstanceProfile: &awsiamtypes.InstanceProfile{
+				Roles: []awsiamtypes.Role{{RoleName: aws.String("another-role")}},
+				Tags:  ownedTags,
 			}}, nil).AnyTimes()
+		})
+		It("should return an error", func() {
+			err := iamService.ReconcileRole()
+			Expect(iam.IsInstanceProfileConflict(err)).To(BeTrue())
+		})
+	})
+
+	When("role is not present", func() {
+		BeforeEach(func() {
+			expectNewRole(mockIAMClient, nil)
 			mockIAMClient.EXPECT().GetRolePolicy(context.TODO(), gomock.Any()).Return(&awsiam.GetRolePolicyOutput{}, &awsiamtypes.NoSuchEntityException{}).AnyTimes()
 			mockIAMClient.EXPECT().PutRolePolicy(context.TODO(), gomock.Any()).Return(&awsiam.PutRolePolicyOutput{}, nil).AnyTimes()
 		})
@@ -105,61 +304,33 @@ var _ = Describe("ReconcileRole", func() {
 			Expect(err).To(BeNil())
 		})
 	})
-
-	AfterEach(func() {
-		mockCtrl.Finish()
-	})
 })
 
 var _ = Describe("ReconcileRole AWSMachinePool", func() {
 	var (
-		mockCtrl      *gomock.Controller
 		mockIAMClient *mocks.MockIAMClient
+		iamConfig     iam.IAMServiceConfig
 		iamService    *iam.IAMService
-		err           error
-		conf          aws.Config
 	)
 
 	BeforeEach(func() {
-		conf, err = config.LoadDefaultConfig(context.TODO(),
-			config.WithRegion("eu-west-1"),
-		)
-		Expect(err).NotTo(HaveOccurred())
+		mockIAMClient = newMockIAMClient()
+		iamConfig = testConfig(iam.NodesRole, mockIAMClient)
+	})
 
-		mockCtrl = gomock.NewController(GinkgoT())
-		mockIAMClient = mocks.NewMockIAMClient(mockCtrl)
+	JustBeforeEach(func() {
+		iamService = newTestService(iamConfig)
 	})
 
 	When("nodes role and policy are not present", func() {
 		BeforeEach(func() {
-			mockIAMClient.EXPECT().GetRole(context.TODO(), gomock.Any()).Return(&awsiam.GetRoleOutput{}, &awsiamtypes.NoSuchEntityException{})
-			mockIAMClient.EXPECT().CreateRole(context.TODO(), gomock.Any()).Return(&awsiam.CreateRoleOutput{}, nil)
-			mockIAMClient.EXPECT().CreateInstanceProfile(context.TODO(), gomock.Any()).Return(&awsiam.CreateInstanceProfileOutput{}, nil)
-			mockIAMClient.EXPECT().AddRoleToInstanceProfile(context.TODO(), gomock.Any()).Return(&awsiam.AddRoleToInstanceProfileOutput{}, nil)
+			expectNewRole(mockIAMClient, nil)
 			mockIAMClient.EXPECT().GetRolePolicy(context.TODO(), gomock.Any()).DoAndReturn(func(ctx context.Context, input *awsiam.GetRolePolicyInput, optFns ...func(*awsiam.Options)) (*awsiam.GetRolePolicyOutput, error) {
 				Expect(input.PolicyName).To(BeComparableTo(aws.String("nodes-test-cluster-policy")))
 				return &awsiam.GetRolePolicyOutput{}, &awsiamtypes.NoSuchEntityException{}
 			})
 		})
 		When("No labels present (full permission set)", func() {
-			BeforeEach(func() {
-				iamConfig := iam.IAMServiceConfig{
-					ClusterName:    "test-cluster",
-					ClusterRelease: "33.0.0",
-					MainRoleName:   "test-role",
-					Region:         "test-region",
-					RoleType:       iam.NodesRole,
-					Log:            ctrl.Log,
-					AWSConfig:      &conf,
-					IAMClientFactory: func(_ aws.Config, _ string) iam.IAMClient {
-						return mockIAMClient
-					},
-				}
-				iamService, err = iam.New(iamConfig)
-				Expect(err).To(BeNil())
-
-			})
-
 			It("should create the role", func() {
 				mockIAMClient.EXPECT().PutRolePolicy(context.TODO(), gomock.Any()).DoAndReturn(func(ctx context.Context, input *awsiam.PutRolePolicyInput, optFns ...func(*awsiam.Options)) (*awsiam.PutRolePolicyOutput, error) {
 					Expect(input.PolicyName).To(BeComparableTo(aws.String("nodes-test-cluster-policy")))
@@ -181,25 +352,9 @@ var _ = Describe("ReconcileRole AWSMachinePool", func() {
 
 		When("Reduced-permission-set label present (not Cilium ENI Mode)", func() {
 			BeforeEach(func() {
-				iamConfig := iam.IAMServiceConfig{
-					ClusterName:    "test-cluster",
-					ClusterRelease: "33.0.0",
-					MainRoleName:   "test-role",
-					Region:         "test-region",
-					RoleType:       iam.NodesRole,
-					Log:            ctrl.Log,
-					AWSConfig:      &conf,
-					IAMClientFactory: func(_ aws.Config, region string) iam.IAMClient {
-						return mockIAMClient
-					},
-
-					ObjectLabels: map[string]string{
-						iam.AWSReducedInstanceProfileIAMPermissionsForWorkersLabel: "true",
-					},
+				iamConfig.ObjectLabels = map[string]string{
+					iam.AWSReducedInstanceProfileIAMPermissionsForWorkersLabel: "true",
 				}
-				iamService, err = iam.New(iamConfig)
-				Expect(err).To(BeNil())
-
 			})
 
 			It("should create the role", func() {
@@ -227,25 +382,10 @@ var _ = Describe("ReconcileRole AWSMachinePool", func() {
 
 		When("Reduced-permission-set and Cilium ENI mode labels present", func() {
 			BeforeEach(func() {
-				iamConfig := iam.IAMServiceConfig{
-					ClusterName:    "test-cluster",
-					ClusterRelease: "33.0.0",
-					MainRoleName:   "test-role",
-					Region:         "test-region",
-					RoleType:       iam.NodesRole,
-					Log:            ctrl.Log,
-					AWSConfig:      &conf,
-					IAMClientFactory: func(_ aws.Config, _ string) iam.IAMClient {
-						return mockIAMClient
-					},
-
-					ObjectLabels: map[string]string{
-						iam.AWSReducedInstanceProfileIAMPermissionsForWorkersLabel: "true",
-						awsIPAMModeLabel: "eni",
-					},
+				iamConfig.ObjectLabels = map[string]string{
+					iam.AWSReducedInstanceProfileIAMPermissionsForWorkersLabel: "true",
+					awsIPAMModeLabel: "eni",
 				}
-				iamService, err = iam.New(iamConfig)
-				Expect(err).To(BeNil())
 			})
 
 			It("should create the role", func() {
@@ -273,8 +413,1581 @@ var _ = Describe("ReconcileRole AWSMachinePool", func() {
 			})
 		})
 	})
+})
+
+var _ = Describe("ReconcileRole tags", func() {
+	var (
+		mockIAMClient *mocks.MockIAMClient
+		iamService    *iam.IAMService
+		roleTags      []awsiamtypes.Tag
+	)
+
+	BeforeEach(func() {
+		mockIAMClient = newMockIAMClient()
+
+		iamConfig := testConfig(iam.BastionRole, mockIAMClient)
+		iamConfig.CustomTags = map[string]string{
+			"cost-center": "new",
+			"team":        "a",
+		}
+		iamService = newTestService(iamConfig)
+
+		roleTags = append([]awsiamtypes.Tag{
+			{Key: aws.String("cost-center"), Value: aws.String("old")},
+			{Key: aws.String("legacy"), Value: aws.String("x")},
+			{Key: aws.String("set-by-someone-else"), Value: aws.String("y")},
+			{Key: aws.String(iam.IAMControllerCustomTagKeysTag), Value: aws.String("cost-center legacy")},
+		}, ownedTags...)
+	})
+
+	JustBeforeEach(func() {
+		role := existingRole(bastionRoleDescription)
+		role.Tags = roleTags
+		expectExistingRole(mockIAMClient, role)
+		expectNoManagedPolicies(mockIAMClient)
+		mockIAMClient.EXPECT().GetRolePolicy(context.TODO(), gomock.Any()).Return(&awsiam.GetRolePolicyOutput{}, &awsiamtypes.NoSuchEntityException{}).AnyTimes()
+		mockIAMClient.EXPECT().PutRolePolicy(context.TODO(), gomock.Any()).Return(&awsiam.PutRolePolicyOutput{}, nil).AnyTimes()
+	})
+
+	It("syncs the custom tags without touching foreign tags", func() {
+		expectedTags := []awsiamtypes.Tag{
+			{Key: aws.String("cost-center"), Value: aws.String("new")},
+			{Key: aws.String("team"), Value: aws.String("a")},
+			{Key: aws.String(iam.IAMControllerCustomTagKeysTag), Value: aws.String("cost-center team")},
+		}
+
+		mockIAMClient.EXPECT().TagRole(context.TODO(), &awsiam.TagRoleInput{
+			RoleName: aws.String("test-role"),
+			Tags:     expectedTags,
+		}).Return(&awsiam.TagRoleOutput{}, nil)
+		mockIAMClient.EXPECT().UntagRole(context.TODO(), &awsiam.UntagRoleInput{
+			RoleName: aws.String("test-role"),
+			TagKeys:  []string{"legacy"},
+		}).Return(&awsiam.UntagRoleOutput{}, nil)
+		mockIAMClient.EXPECT().TagInstanceProfile(context.TODO(), &awsiam.TagInstanceProfileInput{
+			InstanceProfileName: aws.String("test-role"),
+			Tags:                expectedTags,
+		}).Return(&awsiam.TagInstanceProfileOutput{}, nil)
+
+		err := iamService.ReconcileRole()
+		Expect(err).To(BeNil())
+	})
+
+	When("the role was not created by the operator", func() {
+		BeforeEach(func() {
+			roleTags = []awsiamtypes.Tag{
+				{Key: aws.String("cost-center"), Value: aws.String("old")},
+			}
+		})
+
+		It("neither tags the role nor marks it as owned", func() {
+			mockIAMClient.EXPECT().TagRole(gomock.Any(), gomock.Any()).Times(0)
+			mockIAMClient.EXPECT().UntagRole(gomock.Any(), gomock.Any()).Times(0)
+			mockIAMClient.EXPECT().TagInstanceProfile(gomock.Any(), gomock.Any()).Times(0)
+
+			err := iamService.ReconcileRole()
+			Expect(err).To(BeNil())
+		})
+	})
+
+	It("does not add the ownership tags to owned roles lacking them", func() {
+		roleTags = roleTags[:len(roleTags)-1]
+		mockIAMClient.EXPECT().TagRole(context.TODO(), gomock.Any()).DoAndReturn(func(_ context.Context, in *awsiam.TagRoleInput, _ ...func(*awsiam.Options)) (*awsiam.TagRoleOutput, error) {
+			Expect(tagKeysOf(in.Tags)).NotTo(ContainElement("sigs.k8s.io/cluster-api-provider-aws/cluster/test-cluster"))
+			return &awsiam.TagRoleOutput{}, nil
+		})
+		mockIAMClient.EXPECT().UntagRole(context.TODO(), gomock.Any()).Return(&awsiam.UntagRoleOutput{}, nil)
+		mockIAMClient.EXPECT().TagInstanceProfile(context.TODO(), gomock.Any()).Return(&awsiam.TagInstanceProfileOutput{}, nil)
+
+		err := iamService.ReconcileRole()
+		Expect(err).To(BeNil())
+	})
+})
+
+func tagKeysOf(tags []awsiamtypes.Tag) []string {
+	keys := make([]string, 0, len(tags))
+	for _, t := range tags {
+		keys = append(keys, aws.ToString(t.Key))
+	}
+	return keys
+}
+
+var _ = Describe("ReconcileRole managed policy", func() {
+	var (
+		mockIAMClient *mocks.MockIAMClient
+		iamService    *iam.IAMService
+	)
+
+	const policyARN = "arn:aws:iam::123456789012:policy/test-role-policy"
+
+	BeforeEach(func() {
+		mockIAMClient = newMockIAMClient()
+
+		iamConfig := testConfig(iam.BastionRole, mockIAMClient)
+		iamConfig.ManagedPolicyRoleTypes = []string{iam.BastionRole}
+		iamService = newTestService(iamConfig)
+
+		role := existingRole(bastionRoleDescription)
+		role.Arn = aws.String("arn:aws:iam::123456789012:role/test-role")
+		expectExistingRole(mockIAMClient, role)
+	})
+
+	When("the managed policy does not exist yet", func() {
+		It("creates and attaches it and removes the inline policy", func() {
+			gomock.InOrder(
+				mockIAMClient.EXPECT().GetPolicy(context.TODO(), gomock.Any()).Return(nil, &awsiamtypes.NoSuchEntityException{}),
+				mockIAMClient.EXPECT().CreatePolicy(context.TODO(), gomock.Any()).DoAndReturn(func(ctx context.Context, input *awsiam.CreatePolicyInput, optFns ...func(*awsiam.Options)) (*awsiam.CreatePolicyOutput, error) {
+					Expect(input.PolicyName).To(BeComparableTo(aws.String("test-role-policy")))
+					Expect(isValidJSON(*input.PolicyDocument)).To(BeTrue(), *input.PolicyDocument)
+					Expect(input.Tags).To(ContainElement(ownedTags[0]))
+					return &awsiam.CreatePolicyOutput{}, nil
+				}),
+				mockIAMClient.EXPECT().ListAttachedRolePolicies(context.TODO(), gomock.Any()).Return(&awsiam.ListAttachedRolePoliciesOutput{}, nil),
+				mockIAMClient.EXPECT().AttachRolePolicy(context.TODO(), &awsiam.AttachRolePolicyInput{
+					PolicyArn: aws.String(policyARN),
+					RoleName:  aws.String("test-role"),
+				}).Return(&awsiam.AttachRolePolicyOutput{}, nil),
+				mockIAMClient.EXPECT().ListRolePolicies(context.TODO(), gomock.Any()).Return(&awsiam.ListRolePoliciesOutput{
+					PolicyNames: []string{"bastion-test-cluster-policy"},
+				}, nil),
+				mockIAMClient.EXPECT().DeleteRolePolicy(context.TODO(), &awsiam.DeleteRolePolicyInput{
+					PolicyName: aws.String("bastion-test-cluster-policy"),
+					RoleName:   aws.String("test-role"),
+				}).Return(&awsiam.DeleteRolePolicyOutput{}, nil),
+			)
+
+			err := iamService.ReconcileRole()
+			Expect(err).To(BeNil())
+		})
+	})
+
+	When("the managed policy document is outdated", func() {
+		It("prunes the oldest version and sets a new default version", func() {
+			mockIAMClient.EXPECT().GetPolicy(context.TODO(), gomock.Any()).Return(&awsiam.GetPolicyOutput{Policy: &awsiamtypes.Policy{
+				Arn:              aws.String(policyARN),
+				DefaultVersionId: aws.String("v5"),
+				Tags:             ownedTags,
+			}}, nil)
+			mockIAMClient.EXPECT().GetPolicyVersion(context.TODO(), gomock.Any()).Return(&awsiam.GetPolicyVersionOutput{PolicyVersion: &awsiamtypes.PolicyVersion{
+				Document: aws.String("%7B%22Version%22%3A%222012-10-17%22%2C%22Statement%22%3A%5B%5D%7D"),
+			}}, nil)
+
+			versions := []awsiamtypes.PolicyVersion{}
+			for i := 1; i <= 5; i++ {
+				versions = append(versions, awsiamtypes.PolicyVersion{
+					VersionId:        aws.String(fmt.Sprintf("v%d", i)),
+					IsDefaultVersion: i == 5,
+					CreateDate:       aws.Time(time.Date(2024, 1, i, 0, 0, 0, 0, time.UTC)),
+				})
+			}
+			mockIAMClient.EXPECT().ListPolicyVersions(context.TODO(), gomock.Any()).Return(&awsiam.ListPolicyVersionsOutput{Versions: versions}, nil)
+			mockIAMClient.EXPECT().DeletePolicyVersion(context.TODO(), &awsiam.DeletePolicyVersionInput{
+				PolicyArn: aws.String(policyARN),
+				VersionId: aws.String("v1"),
+			}).Return(&awsiam.DeletePolicyVersionOutput{}, nil)
+			mockIAMClient.EXPECT().CreatePolicyVersion(context.TODO(), gomock.Any()).DoAndReturn(func(ctx context.Context, input *awsiam.CreatePolicyVersionInput, optFns ...func(*awsiam.Options)) (*awsiam.CreatePolicyVersionOutput, error) {
+				Expect(input.PolicyArn).To(BeComparableTo(aws.String(policyARN)))
+				Expect(input.SetAsDefault).To(BeTrue())
+				return &awsiam.CreatePolicyVersionOutput{}, nil
+			})
+			mockIAMClient.EXPECT().ListAttachedRolePolicies(context.TODO(), gomock.Any()).Return(&awsiam.ListAttachedRolePoliciesOutput{
+				AttachedPolicies: []awsiamtypes.AttachedPolicy{{PolicyArn: aws.String(policyARN), PolicyName: aws.String("test-role-policy")}},
+			}, nil)
+			mockIAMClient.EXPECT().ListRolePolicies(context.TODO(), gomock.Any()).Return(&awsiam.ListRolePoliciesOutput{}, nil)
+
+			err := iamService.ReconcileRole()
+			Expect(err).To(BeNil())
+		})
+	})
+
+	When("a managed policy of the same name was not created by the operator", func() {
+		It("does not change it", func() {
+			mockIAMClient.EXPECT().GetPolicy(context.TODO(), gomock.Any()).Return(&awsiam.GetPolicyOutput{Policy: &awsiamtypes.Policy{
+				Arn:              aws.String(policyARN),
+				DefaultVersionId: aws.String("v1"),
+			}}, nil)
+
+			err := iamService.ReconcileRole()
+			Expect(iam.IsManagedPolicyConflict(err)).To(BeTrue())
+		})
+	})
+})
+
+var _ = Describe("ReconcileRole without managed policy", func() {
+	var (
+		mockIAMClient *mocks.MockIAMClient
+		iamService    *iam.IAMService
+		policyTags    []awsiamtypes.Tag
+	)
+
+	const policyARN = "arn:aws:iam::123456789012:policy/test-role-policy"
+
+	BeforeEach(func() {
+		mockIAMClient = newMockIAMClient()
+		policyTags = ownedTags
+
+		iamService = newTestService(testConfig(iam.BastionRole, mockIAMClient))
+
+		expectExistingRole(mockIAMClient, existingRole(bastionRoleDescription))
+		inlinePolicy := ""
+		mockIAMClient.EXPECT().GetRolePolicy(context.TODO(), gomock.Any()).DoAndReturn(func(_ context.Context, _ *awsiam.GetRolePolicyInput, _ ...func(*awsiam.Options)) (*awsiam.GetRolePolicyOutput, error) {
+			if inlinePolicy == "" {
+				return nil, &awsiamtypes.NoSuchEntityException{}
+			}
+			return &awsiam.GetRolePolicyOutput{PolicyDocument: aws.String(url.QueryEscape(inlinePolicy))}, nil
+		}).AnyTimes()
+		mockIAMClient.EXPECT().PutRolePolicy(context.TODO(), gomock.Any()).DoAndReturn(func(_ context.Context, in *awsiam.PutRolePolicyInput, _ ...func(*awsiam.Options)) (*awsiam.PutRolePolicyOutput, error) {
+			inlinePolicy = aws.ToString(in.PolicyDocument)
+			return &awsiam.PutRolePolicyOutput{}, nil
+		})
+		mockIAMClient.EXPECT().ListAttachedRolePolicies(context.TODO(), gomock.Any()).Return(&awsiam.ListAttachedRolePoliciesOutput{
+			AttachedPolicies: []awsiamtypes.AttachedPolicy{{PolicyArn: aws.String(policyARN), PolicyName: aws.String("test-role-policy")}},
+		}, nil).MinTimes(1)
+	})
+
+	JustBeforeEach(func() {
+		mockIAMClient.EXPECT().GetPolicy(context.TODO(), gomock.Any()).Return(&awsiam.GetPolicyOutput{Policy: &awsiamtypes.Policy{
+			Arn:  aws.String(policyARN),
+			Tags: policyTags,
+		}}, nil).AnyTimes()
+	})
+
+	When("the role type used a managed policy before", func() {
+		It("detaches and deletes the managed policy", func() {
+			gomock.InOrder(
+				mockIAMClient.EXPECT().DetachRolePolicy(context.TODO(), &awsiam.DetachRolePolicyInput{
+					PolicyArn: aws.String(policyARN),
+					RoleName:  aws.String("test-role"),
+				}).Return(&awsiam.DetachRolePolicyOutput{}, nil),
+				mockIAMClient.EXPECT().ListPolicyVersions(context.TODO(), gomock.Any()).Return(&awsiam.ListPolicyVersionsOutput{
+					Versions: []awsiamtypes.PolicyVersion{{VersionId: aws.String("v1"), IsDefaultVersion: true}},
+				}, nil),
+				mockIAMClient.EXPECT().DeletePolicy(context.TODO(), &awsiam.DeletePolicyInput{
+					PolicyArn: aws.String(policyARN),
+				}).Return(&awsiam.DeletePolicyOutput{}, nil),
+			)
+
+			err := iamService.ReconcileRole()
+			Expect(err).To(BeNil())
+		})
+
+		It("detaches the managed policy on the next reconciliation if it failed before", func() {
+			gomock.InOrder(
+				mockIAMClient.EXPECT().DetachRolePolicy(context.TODO(), gomock.Any()).Return(nil, errors.New("test error")),
+				mockIAMClient.EXPECT().DetachRolePolicy(context.TODO(), gomock.Any()).Return(&awsiam.DetachRolePolicyOutput{}, nil),
+				mockIAMClient.EXPECT().ListPolicyVersions(context.TODO(), gomock.Any()).Return(&awsiam.ListPolicyVersionsOutput{}, nil),
+				mockIAMClient.EXPECT().DeletePolicy(context.TODO(), gomock.Any()).Return(&awsiam.DeletePolicyOutput{}, nil),
+			)
+
+			err := iamService.ReconcileRole()
+			Expect(err).NotTo(BeNil())
+
+			err = iamService.ReconcileRole()
+			Expect(err).To(BeNil())
+		})
+	})
+
+	When("the managed policy was not created by the operator", func() {
+		BeforeEach(func() {
+			policyTags = nil
+		})
+
+		It("leaves it attached", func() {
+			err := iamService.ReconcileRole()
+			Expect(err).To(BeNil())
+		})
+	})
+})
+
+var _ = Describe("ReconcileRole permissions boundary", func() {
+	var (
+		mockIAMClient       *mocks.MockIAMClient
+		iamService          *iam.IAMService
+		permissionsBoundary string
+	)
+
+	const boundaryARN = "arn:aws:iam::123456789012:policy/boundary"
+
+	boundaryTag := awsiamtypes.Tag{Key: aws.String(iam.IAMControllerPermissionsBoundaryTag), Value: aws.String("")}
+
+	BeforeEach(func() {
+		mockIAMClient = newMockIAMClient()
+		permissionsBoundary = boundaryARN
+	})
+
+	JustBeforeEach(func() {
+		iamConfig := testConfig(iam.BastionRole, mockIAMClient)
+		iamConfig.PermissionsBoundary = permissionsBoundary
+		iamService = newTestService(iamConfig)
+
+		mockIAMClient.EXPECT().GetRolePolicy(context.TODO(), gomock.Any()).Return(&awsiam.GetRolePolicyOutput{}, &awsiamtypes.NoSuchEntityException{}).AnyTimes()
+		mockIAMClient.EXPECT().PutRolePolicy(context.TODO(), gomock.Any()).Return(&awsiam.PutRolePolicyOutput{}, nil).AnyTimes()
+	})
+
+	It("rejects an invalid permissions boundary", func() {
+		iamConfig := testConfig(iam.BastionRole, mockIAMClient)
+		iamConfig.PermissionsBoundary = "not-an-arn"
+		_, err := iam.New(iamConfig)
+		Expect(err).To(HaveOccurred())
+	})
+
+	When("the role does not exist", func() {
+		It("creates the role with the permissions boundary", func() {
+			expectNewRole(mockIAMClient, func(input *awsiam.CreateRoleInput) {
+				Expect(input.PermissionsBoundary).To(BeComparableTo(aws.String(boundaryARN)))
+				Expect(input.Tags).To(ContainElement(boundaryTag))
+			})
+
+			err := iamService.ReconcileRole()
+			Expect(err).To(BeNil())
+		})
+	})
+
+	When("the role exists", func() {
+		var role *awsiamtypes.Role
+
+		JustBeforeEach(func() {
+			expectExistingRole(mockIAMClient, role, append(ownedTags, boundaryTag)...)
+			expectNoManagedPolicies(mockIAMClient)
+		})
+
+		When("it has a different permissions boundary", func() {
+			BeforeEach(func() {
+				role = existingRole(bastionRoleDescription)
+				role.Tags = append(ownedTags, boundaryTag)
+				role.PermissionsBoundary = &awsiamtypes.AttachedPermissionsBoundary{
+					PermissionsBoundaryArn: aws.String("arn:aws:iam::123456789012:policy/old-boundary"),
+				}
+			})
+
+			It("replaces the permissions boundary", func() {
+				mockIAMClient.EXPECT().PutRolePermissionsBoundary(context.TODO(), &awsiam.PutRolePermissionsBoundaryInput{
+					RoleName:            aws.String("test-role"),
+					PermissionsBoundary: aws.String(boundaryARN),
+				}).Return(&awsiam.PutRolePermissionsBoundaryOutput{}, nil)
+
+				err := iamService.ReconcileRole()
+				Expect(err).To(BeNil())
+			})
+		})
+
+		When("no permissions boundary is configured anymore", func() {
+			BeforeEach(func() {
+				permissionsBoundary = ""
+				role = existingRole(bastionRoleDescription)
+				role.Tags = append(ownedTags, boundaryTag)
+				role.PermissionsBoundary = &awsiamtypes.AttachedPermissionsBoundary{
+					PermissionsBoundaryArn: aws.String(boundaryARN),
+				}
+			})
+
+			It("removes the permissions boundary set by the operator", func() {
+				mockIAMClient.EXPECT().DeleteRolePermissionsBoundary(context.TODO(), &awsiam.DeleteRolePermissionsBoundaryInput{
+					RoleName: aws.String("test-role"),
+				}).Return(&awsiam.DeleteRolePermissionsBoundaryOutput{}, nil)
+				mockIAMClient.EXPECT().UntagRole(context.TODO(), &awsiam.UntagRoleInput{
+					RoleName: aws.String("test-role"),
+					TagKeys:  []string{iam.IAMControllerPermissionsBoundaryTag},
+				}).Return(&awsiam.UntagRoleOutput{}, nil)
+
+				err := iamService.ReconcileRole()
+				Expect(err).To(BeNil())
+			})
+		})
+
+		When("the permissions boundary was set by someone else", func() {
+			BeforeEach(func() {
+				permissionsBoundary = ""
+				role = existingRole(bastionRoleDescription)
+				role.PermissionsBoundary = &awsiamtypes.AttachedPermissionsBoundary{
+					PermissionsBoundaryArn: aws.String(boundaryARN),
+				}
+			})
+
+			It("leaves the permissions boundary alone", func() {
+				err := iamService.ReconcileRole()
+				Expect(err).To(BeNil())
+			})
+		})
+	})
+
+	When("the role is deleted", func() {
+		BeforeEach(func() {
+			mockIAMClient.EXPECT().GetRole(context.TODO(), gomock.Any()).Return(&awsiam.GetRoleOutput{Role: &awsiamtypes.Role{
+				Tags: append(ownedTags, boundaryTag),
+				PermissionsBoundary: &awsiamtypes.AttachedPermissionsBoundary{
+					PermissionsBoundaryArn: aws.String(boundaryARN),
+				},
+			}}, nil)
+		})
 
-	AfterEach(func() {
-		mockCtrl.Finish()
+		It("removes the permissions boundary before deleting the role", func() {
+			mockIAMClient.EXPECT().ListAttachedRolePolicies(context.TODO(), gomock.Any()).Return(&awsiam.ListAttachedRolePoliciesOutput{}, nil)
+			mockIAMClient.EXPECT().ListRolePolicies(context.TODO(), gomock.Any()).Return(&awsiam.ListRolePoliciesOutput{}, nil)
+			gomock.InOrder(
+				mockIAMClient.EXPECT().DeleteRolePermissionsBoundary(context.TODO(), gomock.Any()).Return(&awsiam.DeleteRolePermissionsBoundaryOutput{}, nil),
+				mockIAMClient.EXPECT().RemoveRoleFromInstanceProfile(context.TODO(), gomock.Any()).Return(&awsiam.RemoveRoleFromInstanceProfileOutput{}, nil),
+				mockIAMClient.EXPECT().DeleteInstanceProfile(context.TODO(), gomock.Any()).Return(&awsiam.DeleteInstanceProfileOutput{}, nil),
+				mockIAMClient.EXPECT().DeleteRole(context.TODO(), gomock.Any()).Return(&awsiam.DeleteRoleOutput{}, nil),
+			)
+
+			err := iamService.DeleteRole()
+			Expect(err).To(BeNil())
+		})
 	})
 })
+
+var _ = Describe("ReconcileRole role settings", func() {
+	var (
+		mockIAMClient *mocks.MockIAMClient
+		iamConfig     iam.IAMServiceConfig
+		iamService    *iam.IAMService
+	)
+
+	BeforeEach(func() {
+		mockIAMClient = newMockIAMClient()
+
+		iamConfig = testConfig(iam.BastionRole, mockIAMClient)
+		iamConfig.RoleSettings = iam.RoleSettingsOverrides{
+			Default: iam.RoleSettings{
+				Path:               "/giantswarm/",
+				MaxSessionDuration: 7200,
+			},
+			ByRoleType: map[string]iam.RoleSettings{
+				iam.NodesRole: {Path: "/nodes/", Description: "Nodes only"},
+			},
+		}
+
+		mockIAMClient.EXPECT().GetRolePolicy(context.TODO(), gomock.Any()).Return(&awsiam.GetRolePolicyOutput{}, &awsiamtypes.NoSuchEntityException{}).AnyTimes()
+		mockIAMClient.EXPECT().PutRolePolicy(context.TODO(), gomock.Any()).Return(&awsiam.PutRolePolicyOutput{}, nil).AnyTimes()
+	})
+
+	JustBeforeEach(func() {
+		iamService = newTestService(iamConfig)
+	})
+
+	It("rejects invalid overrides", func() {
+		for _, settings := range []iam.RoleSettingsOverrides{
+			{Default: iam.RoleSettings{Path: "giantswarm"}},
+			{Default: iam.RoleSettings{MaxSessionDuration: 60}},
+			{Default: iam.RoleSettings{Description: "All roles"}},
+			{ByRoleType: map[string]iam.RoleSettings{"unknown": {Path: "/unknown/"}}},
+			{ByRoleType: map[string]iam.RoleSettings{iam.BastionRole: {MaxSessionDuration: 60}}},
+		} {
+			iamConfig.RoleSettings = settings
+			_, err := iam.New(iamConfig)
+			Expect(err).To(HaveOccurred(), "%+v", settings)
+		}
+	})
+
+	When("the role does not exist", func() {
+		It("creates the role and instance profile with the overridden settings", func() {
+			mockIAMClient.EXPECT().GetRole(context.TODO(), gomock.Any()).Return(nil, &awsiamtypes.NoSuchEntityException{})
+			mockIAMClient.EXPECT().CreateRole(context.TODO(), gomock.Any()).DoAndReturn(func(ctx context.Context, input *awsiam.CreateRoleInput, optFns ...func(*awsiam.Options)) (*awsiam.CreateRoleOutput, error) {
+				Expect(input.Path).To(BeComparableTo(aws.String("/giantswarm/")))
+				Expect(input.Description).To(BeComparableTo(aws.String(bastionRoleDescription)))
+				Expect(input.MaxSessionDuration).To(BeComparableTo(aws.Int32(7200)))
+				return &awsiam.CreateRoleOutput{}, nil
+			})
+			mockIAMClient.EXPECT().GetInstanceProfile(context.TODO(), gomock.Any()).Return(nil, &awsiamtypes.NoSuchEntityException{})
+			mockIAMClient.EXPECT().CreateInstanceProfile(context.TODO(), gomock.Any()).DoAndReturn(func(ctx context.Context, input *awsiam.CreateInstanceProfileInput, optFns ...func(*awsiam.Options)) (*awsiam.CreateInstanceProfileOutput, error) {
+				Expect(input.Path).To(BeComparableTo(aws.String("/giantswarm/")))
+				return &awsiam.CreateInstanceProfileOutput{}, nil
+			})
+			mockIAMClient.EXPECT().AddRoleToInstanceProfile(context.TODO(), gomock.Any()).Return(&awsiam.AddRoleToInstanceProfileOutput{}, nil)
+
+			err := iamService.ReconcileRole()
+			Expect(err).To(BeNil())
+		})
+	})
+
+	When("the role type has its own overrides", func() {
+		BeforeEach(func() {
+			iamConfig.RoleSettings.ByRoleType = map[string]iam.RoleSettings{
+				iam.BastionRole: {Path: "/bastion/", Description: "Bastion"},
+			}
+		})
+
+		It("prefers them over the overrides for all roles", func() {
+			expectNewRole(mockIAMClient, func(input *awsiam.CreateRoleInput) {
+				Expect(input.Path).To(BeComparableTo(aws.String("/bastion/")))
+				Expect(input.Description).To(BeComparableTo(aws.String("Bastion")))
+				Expect(input.MaxSessionDuration).To(BeComparableTo(aws.Int32(7200)))
+			})
+
+			err := iamService.ReconcileRole()
+			Expect(err).To(BeNil())
+		})
+	})
+
+	When("the settings of an existing role are outdated", func() {
+		BeforeEach(func() {
+			role := existingRole("")
+			role.Path = aws.String("/giantswarm/")
+			expectExistingRole(mockIAMClient, role)
+			expectNoManagedPolicies(mockIAMClient)
+		})
+
+		It("updates the role", func() {
+			mockIAMClient.EXPECT().UpdateRole(context.TODO(), &awsiam.UpdateRoleInput{
+				RoleName:           aws.String("test-role"),
+				Description:        aws.String(bastionRoleDescription),
+				MaxSessionDuration: aws.Int32(7200),
+			}).Return(&awsiam.UpdateRoleOutput{}, nil)
+
+			err := iamService.ReconcileRole()
+			Expect(err).To(BeNil())
+		})
+	})
+})
+
+var _ = DescribeTable("ReconcileRole partitions",
+	func(region string, expectedPartition string, expectedPrincipal string) {
+		mockIAMClient := newMockIAMClient()
+
+		iamConfig := testConfig(iam.ControlPlaneRole, mockIAMClient)
+		iamConfig.Region = region
+		iamService := newTestService(iamConfig)
+
+		expectNewRole(mockIAMClient, func(input *awsiam.CreateRoleInput) {
+			Expect(*input.AssumeRolePolicyDocument).To(ContainSubstring(fmt.Sprintf(`"Service": "%s"`, expectedPrincipal)))
+		})
+		mockIAMClient.EXPECT().GetRolePolicy(context.TODO(), gomock.Any()).Return(nil, &awsiamtypes.NoSuchEntityException{})
+		mockIAMClient.EXPECT().PutRolePolicy(context.TODO(), gomock.Any()).DoAndReturn(func(ctx context.Context, input *awsiam.PutRolePolicyInput, optFns ...func(*awsiam.Options)) (*awsiam.PutRolePolicyOutput, error) {
+			Expect(*input.PolicyDocument).To(ContainSubstring(fmt.Sprintf(`"arn:%s:secretsmanager:`, expectedPartition)))
+			Expect(*input.PolicyDocument).NotTo(ContainSubstring(`"arn:*:`))
+			return &awsiam.PutRolePolicyOutput{}, nil
+		})
+
+		err := iamService.ReconcileRole()
+		Expect(err).To(BeNil())
+	},
+	Entry("commercial", "eu-west-1", "aws", "ec2.amazonaws.com"),
+	Entry("China", "cn-north-1", "aws-cn", "ec2.amazonaws.com.cn"),
+	Entry("GovCloud", "us-gov-west-1", "aws-us-gov", "ec2.amazonaws.com"),
+	Entry("ISO", "us-iso-east-1", "aws-iso", "ec2.c2s.ic.gov"),
+	Entry("ISO-B", "us-isob-east-1", "aws-iso-b", "ec2.sc2s.sgov.gov"),
+)
+
+var _ = Describe("ParsePolicyTemplates", func() {
+	It("parses inline and trust policy templates by role type", func() {
+		templates, err := iam.ParsePolicyTemplates(map[string]string{
+			"nodes.inline-policy":            `{"Version": "2012-10-17", "Statement": []}`,
+			"cert-manager-role.trust-policy": `{"Version": "2012-10-17", "Statement": []}`,
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(templates.Inline).To(HaveKey(iam.NodesRole))
+		Expect(templates.Trust).To(HaveKey(iam.CertManagerRole))
+	})
+
+	It("rejects invalid keys and templates", func() {
+		for _, data := range []map[string]string{
+			{"nodes": "{}"},
+			{"unknown-role.inline-policy": "{}"},
+			{"nodes.inline-policy": "{{ .ClusterName "},
+		} {
+			_, err := iam.ParsePolicyTemplates(data)
+			Expect(iam.IsInvalidPolicyTemplate(err)).To(BeTrue(), "%v", data)
+		}
+	})
+
+	It("lets overrides take precedence when merging", func() {
+		merged := iam.PolicyTemplates{
+			Inline: map[string]string{iam.NodesRole: "operator", iam.BastionRole: "operator"},
+		}.Merge(iam.PolicyTemplates{
+			Inline: map[string]string{iam.NodesRole: "cluster"},
+		})
+		Expect(merged.Inline).To(Equal(map[string]string{iam.NodesRole: "cluster", iam.BastionRole: "operator"}))
+	})
+})
+
+var _ = Describe("ReconcileRole policy templates", func() {
+	var (
+		mockIAMClient  *mocks.MockIAMClient
+		iamService     *iam.IAMService
+		inlineTemplate string
+	)
+
+	BeforeEach(func() {
+		mockIAMClient = newMockIAMClient()
+	})
+
+	JustBeforeEach(func() {
+		policyTemplates, err := iam.ParsePolicyTemplates(map[string]string{
+			"bastion.inline-policy": inlineTemplate,
+		})
+		Expect(err).NotTo(HaveOccurred())
+
+		iamConfig := testConfig(iam.BastionRole, mockIAMClient)
+		iamConfig.PolicyTemplates = policyTemplates
+		iamService = newTestService(iamConfig)
+
+		expectNewRole(mockIAMClient, nil)
+	})
+
+	When("the override renders a valid policy", func() {
+		BeforeEach(func() {
+			inlineTemplate = `{"Version": "2012-10-17", "Statement": [{"Effect": "Allow", "Action": "ec2:DescribeTags", "Resource": "arn:{{ .AWSPartition }}:ec2:*:*:instance/{{ .ClusterName }}"}]}`
+		})
+
+		It("uses the override instead of the built-in template", func() {
+			mockIAMClient.EXPECT().GetRolePolicy(context.TODO(), gomock.Any()).Return(nil, &awsiamtypes.NoSuchEntityException{})
+			mockIAMClient.EXPECT().PutRolePolicy(context.TODO(), gomock.Any()).DoAndReturn(func(ctx context.Context, input *awsiam.PutRolePolicyInput, optFns ...func(*awsiam.Options)) (*awsiam.PutRolePolicyOutput, error) {
+				Expect(*input.PolicyDocument).To(ContainSubstring(`"Resource": "arn:aws:ec2:*:*:instance/test-cluster"`))
+				return &awsiam.PutRolePolicyOutput{}, nil
+			})
+
+			err := iamService.ReconcileRole()
+			Expect(err).To(BeNil())
+		})
+	})
+
+	When("the override does not render a valid policy", func() {
+		BeforeEach(func() {
+			inlineTemplate = `{"Version": "2012-10-17", "Statement": [{{ .ClusterName }}]}`
+		})
+
+		It("returns an error without touching the inline policy", func() {
+			err := iamService.ReconcileRole()
+			Expect(iam.IsInvalidPolicyTemplate(err)).To(BeTrue())
+		})
+	})
+})
+
+var _ = Describe("ParseAdditionalPolicyStatements", func() {
+	It("accepts a single statement or a list of statements", func() {
+		statements, err := iam.ParseAdditionalPolicyStatements(map[string]string{
+			iam.NodesRole:        `{"Sid": "ReadBucket", "Effect": "Allow", "Action": "s3:GetObject", "Resource": "arn:aws:s3:::bucket/*"}`,
+			iam.ControlPlaneRole: `[{"Sid": "Decrypt", "Effect": "Allow", "Action": ["kms:Decrypt"], "Resource": "*"}, {"Sid": "DenyOthers", "Effect": "Deny", "NotAction": "kms:*", "NotResource": "*"}]`,
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(statements[iam.NodesRole]).To(HaveLen(1))
+		Expect(statements[iam.ControlPlaneRole]).To(HaveLen(2))
+	})
+
+	It("rejects invalid statements with a reason", func() {
+		for _, data := range []map[string]string{
+			{"unknown-role": `{"Sid": "Read", "Effect": "Allow", "Action": "s3:GetObject", "Resource": "*"}`},
+			{iam.NodesRole: `not json`},
+			{iam.NodesRole: `{"Sid": "Read", "Effect": "Maybe", "Action": "s3:GetObject", "Resource": "*"}`},
+			{iam.NodesRole: `{"Sid": "Read", "Effect": "Allow", "Resource": "*"}`},
+			{iam.NodesRole: `{"Sid": "Read", "Effect": "Allow", "Action": "s3:GetObject"}`},
+			{iam.NodesRole: `{"Effect": "Allow", "Action": "s3:GetObject", "Resource": "*"}`},
+			{iam.NodesRole: `{"Sid": "", "Effect": "Allow", "Action": "s3:GetObject", "Resource": "*"}`},
+		} {
+			_, err := iam.ParseAdditionalPolicyStatements(data)
+			Expect(iam.IsInvalidAdditionalStatements(err)).To(BeTrue(), "%v", data)
+		}
+	})
+})
+
+var _ = Describe("ReconcileRole additional statements", func() {
+	var (
+		mockIAMClient   *mocks.MockIAMClient
+		iamService      *iam.IAMService
+		err             error
+		statements      string
+		policyTemplates iam.PolicyTemplates
+	)
+
+	BeforeEach(func() {
+		mockIAMClient = newMockIAMClient()
+		policyTemplates = iam.PolicyTemplates{}
+	})
+
+	JustBeforeEach(func() {
+		additionalStatements, err := iam.ParseAdditionalPolicyStatements(map[string]string{
+			iam.BastionRole: statements,
+		})
+		Expect(err).NotTo(HaveOccurred())
+
+		iamConfig := testConfig(iam.BastionRole, mockIAMClient)
+		iamConfig.PolicyTemplates = policyTemplates
+		iamConfig.AdditionalStatements = additionalStatements
+		iamService = newTestService(iamConfig)
+
+		expectNewRole(mockIAMClient, nil)
+	})
+
+	When("the statements are valid", func() {
+		BeforeEach(func() {
+			statements = `{"Sid": "ReadBucket", "Effect": "Allow", "Action": "s3:GetObject", "Resource": "arn:aws:s3:::bucket/*"}`
+		})
+
+		It("adds them to the rendered policy", func() {
+			mockIAMClient.EXPECT().GetRolePolicy(context.TODO(), gomock.Any()).Return(nil, &awsiamtypes.NoSuchEntityException{})
+			mockIAMClient.EXPECT().PutRolePolicy(context.TODO(), gomock.Any()).DoAndReturn(func(ctx context.Context, input *awsiam.PutRolePolicyInput, optFns ...func(*awsiam.Options)) (*awsiam.PutRolePolicyOutput, error) {
+				var policy struct {
+					Statement []map[string]any
+				}
+				Expect(json.Unmarshal([]byte(*input.PolicyDocument), &policy)).To(Succeed())
+				Expect(len(policy.Statement)).To(BeNumerically(">", 1))
+				Expect(policy.Statement[len(policy.Statement)-1]).To(HaveKeyWithValue("Sid", "ReadBucket"))
+				Expect(*input.PolicyDocument).To(ContainSubstring(`"s3:GetObjectAcl"`))
+				return &awsiam.PutRolePolicyOutput{}, nil
+			})
+
+			err := iamService.ReconcileRole()
+			Expect(err).To(BeNil())
+		})
+	})
+
+	When("a statement ID is already used by the policy", func() {
+		BeforeEach(func() {
+			policyTemplates, err = iam.ParsePolicyTemplates(map[string]string{
+				"bastion.inline-policy": `{"Version": "2012-10-17", "Statement": [{"Sid": "ReadBucket", "Effect": "Allow", "Action": "ec2:DescribeInstances", "Resource": "*"}]}`,
+			})
+			Expect(err).NotTo(HaveOccurred())
+			statements = `{"Sid": "ReadBucket", "Effect": "Allow", "Action": "s3:GetObject", "Resource": "arn:aws:s3:::bucket/*"}`
+		})
+
+		It("returns an error", func() {
+			err := iamService.ReconcileRole()
+			Expect(iam.IsInvalidAdditionalStatements(err)).To(BeTrue())
+			Expect(err.Error()).To(ContainSubstring(`"ReadBucket"`))
+		})
+	})
+
+	When("a statement repeats one of the policy", func() {
+		BeforeEach(func() {
+			policyTemplates, err = iam.ParsePolicyTemplates(map[string]string{
+				"bastion.inline-policy": `{"Version": "2012-10-17", "Statement": [{"Effect": "Allow", "Action": "ec2:DescribeInstances", "Resource": "*"}]}`,
+			})
+			Expect(err).NotTo(HaveOccurred())
+			statements = `{"Sid": "DescribeInstances", "Effect": "Allow", "Action": "ec2:DescribeInstances", "Resource": "*"}`
+		})
+
+		It("returns an error", func() {
+			err := iamService.ReconcileRole()
+			Expect(iam.IsInvalidAdditionalStatements(err)).To(BeTrue())
+			Expect(err.Error()).To(ContainSubstring(`"DescribeInstances" repeats`))
+		})
+	})
+
+	When("the policy gets too large", func() {
+		BeforeEach(func() {
+			statements = fmt.Sprintf(`{"Sid": "ReadBucket", "Effect": "Allow", "Action": "s3:GetObject", "Resource": "arn:aws:s3:::%s"}`, strings.Repeat("a", 11000))
+		})
+
+		It("returns an error", func() {
+			err := iamService.ReconcileRole()
+			Expect(iam.IsInvalidAdditionalStatements(err)).To(BeTrue())
+			Expect(err.Error()).To(ContainSubstring("exceeding the limit"))
+		})
+	})
+})
+
+var _ = Describe("ReconcileRole policy comparison", func() {
+	var (
+		mockIAMClient *mocks.MockIAMClient
+		iamService    *iam.IAMService
+	)
+
+	// Equivalent to the built-in trust policy, but with lists instead of single values.
+	const trustPolicy = `{"Version": "2012-10-17", "Statement": {"Action": ["sts:AssumeRole"], "Principal": {"Service": ["ec2.amazonaws.com"]}, "Effect": "Allow"}}`
+
+	BeforeEach(func() {
+		mockIAMClient = newMockIAMClient()
+
+		iamService = newTestService(testConfig(iam.BastionRole, mockIAMClient))
+
+		role := existingRole(bastionRoleDescription)
+		role.AssumeRolePolicyDocument = aws.String(url.QueryEscape(trustPolicy))
+		expectExistingRole(mockIAMClient, role)
+		expectNoManagedPolicies(mockIAMClient)
+	})
+
+	When("the policies only differ in representation", func() {
+		BeforeEach(func() {
+			// Reordered and differently cased actions, a single resource instead of a list.
+			inlinePolicy := `{
+  "Version": "2012-10-17",
+  "Statement": [{
+    "Effect": "Allow",
+    "Resource": "arn:aws:s3:::*-capa-*",
+    "Action": ["s3:GetObjectVersion", "s3:GetObjectAcl", "S3:GetObject", "s3:GetBucket", "s3:HeadObject", "s3:HeadBucket", "s3:getobject"]
+  }]
+}`
+			mockIAMClient.EXPECT().GetRolePolicy(context.TODO(), gomock.Any()).Return(&awsiam.GetRolePolicyOutput{
+				PolicyDocument: aws.String(url.QueryEscape(inlinePolicy)),
+			}, nil)
+		})
+
+		It("does not update them", func() {
+			err := iamService.ReconcileRole()
+			Expect(err).To(BeNil())
+		})
+	})
+
+	When("the inline policy lacks an action", func() {
+		BeforeEach(func() {
+			inlinePolicy := `{"Version": "2012-10-17", "Statement": [{"Effect": "Allow", "Resource": "arn:aws:s3:::*-capa-*", "Action": ["s3:GetObject"]}]}`
+			mockIAMClient.EXPECT().GetRolePolicy(context.TODO(), gomock.Any()).Return(&awsiam.GetRolePolicyOutput{
+				PolicyDocument: aws.String(url.QueryEscape(inlinePolicy)),
+			}, nil)
+		})
+
+		It("updates it", func() {
+			mockIAMClient.EXPECT().PutRolePolicy(context.TODO(), gomock.Any()).Return(&awsiam.PutRolePolicyOutput{}, nil)
+
+			err := iamService.ReconcileRole()
+			Expect(err).To(BeNil())
+		})
+	})
+
+	When("updating the inline policy fails", func() {
+		const previousPolicy = `{"Version": "2012-10-17", "Statement": [{"Effect": "Allow", "Resource": "arn:aws:s3:::*-capa-*", "Action": ["s3:GetObject"]}]}`
+
+		It("restores the previous policy if it got lost", func() {
+			gomock.InOrder(
+				mockIAMClient.EXPECT().GetRolePolicy(context.TODO(), gomock.Any()).Return(&awsiam.GetRolePolicyOutput{
+					PolicyDocument: aws.String(url.QueryEscape(previousPolicy)),
+				}, nil),
+				mockIAMClient.EXPECT().PutRolePolicy(context.TODO(), gomock.Any()).Return(nil, errors.New("throttled")),
+				mockIAMClient.EXPECT().GetRolePolicy(context.TODO(), gomock.Any()).Return(nil, &awsiamtypes.NoSuchEntityException{}),
+				mockIAMClient.EXPECT().PutRolePolicy(context.TODO(), &awsiam.PutRolePolicyInput{
+					PolicyName:     aws.String("bastion-test-cluster-policy"),
+					PolicyDocument: aws.String(previousPolicy),
+					RoleName:       aws.String("test-role"),
+				}).Return(&awsiam.PutRolePolicyOutput{}, nil),
+			)
+
+			err := iamService.ReconcileRole()
+			Expect(err).To(MatchError("throttled"))
+		})
+
+		It("keeps the previous policy if it is still in place", func() {
+			gomock.InOrder(
+				mockIAMClient.EXPECT().GetRolePolicy(context.TODO(), gomock.Any()).Return(&awsiam.GetRolePolicyOutput{
+					PolicyDocument: aws.String(url.QueryEscape(previousPolicy)),
+				}, nil),
+				mockIAMClient.EXPECT().PutRolePolicy(context.TODO(), gomock.Any()).Return(nil, errors.New("throttled")),
+				mockIAMClient.EXPECT().GetRolePolicy(context.TODO(), gomock.Any()).Return(&awsiam.GetRolePolicyOutput{
+					PolicyDocument: aws.String(url.QueryEscape(previousPolicy)),
+				}, nil),
+			)
+
+			err := iamService.ReconcileRole()
+			Expect(err).To(MatchError("throttled"))
+		})
+	})
+})
+
+var _ = Describe("ReconcileRolesForIRSA inline policy names", func() {
+	var (
+		mockIAMClient *mocks.MockIAMClient
+		iamService    *iam.IAMService
+		calls         []string
+	)
+
+	const certManagerRoleName = "test-cluster-CertManager-Role"
+
+	BeforeEach(func() {
+		mockIAMClient = newMockIAMClient()
+		calls = nil
+
+		iamConfig := testConfig(iam.ControlPlaneRole, mockIAMClient)
+		iamConfig.FeatureGates = map[string]bool{iam.FeatureGateKarpenter: true}
+		iamConfig.Karpenter = iam.KarpenterConfig{NodeRoleNames: []string{"test-cluster-nodes"}}
+		iamService = newTestService(iamConfig)
+
+		role := existingRole("")
+		role.Arn = aws.String("arn:aws:iam::012345678901:role/test-role")
+		expectExistingRole(mockIAMClient, role)
+		expectNoManagedPolicies(mockIAMClient)
+		mockIAMClient.EXPECT().UpdateRole(context.TODO(), gomock.Any()).Return(&awsiam.UpdateRoleOutput{}, nil).AnyTimes()
+		mockIAMClient.EXPECT().GetRolePolicy(context.TODO(), gomock.Any()).Return(nil, &awsiamtypes.NoSuchEntityException{}).AnyTimes()
+		mockIAMClient.EXPECT().PutRolePolicy(context.TODO(), gomock.Any()).DoAndReturn(func(_ context.Context, in *awsiam.PutRolePolicyInput, _ ...func(*awsiam.Options)) (*awsiam.PutRolePolicyOutput, error) {
+			if aws.ToString(in.RoleName) == certManagerRoleName {
+				calls = append(calls, "put "+aws.ToString(in.PolicyName))
+			}
+			return &awsiam.PutRolePolicyOutput{}, nil
+		}).AnyTimes()
+		mockIAMClient.EXPECT().ListRolePolicies(context.TODO(), gomock.Any()).Return(&awsiam.ListRolePoliciesOutput{
+			PolicyNames: []string{"control-plane-test-cluster-policy"},
+		}, nil).AnyTimes()
+		mockIAMClient.EXPECT().DeleteRolePolicy(context.TODO(), gomock.Any()).DoAndReturn(func(_ context.Context, in *awsiam.DeleteRolePolicyInput, _ ...func(*awsiam.Options)) (*awsiam.DeleteRolePolicyOutput, error) {
+			if aws.ToString(in.RoleName) == certManagerRoleName {
+				calls = append(calls, "delete "+aws.ToString(in.PolicyName))
+			}
+			return &awsiam.DeleteRolePolicyOutput{}, nil
+		}).AnyTimes()
+	})
+
+	It("names the policy after the role type and removes the legacy policy afterwards", func() {
+		err := iamService.ReconcileRolesForIRSA("012345678901", []string{"irsa.test.gaws.gigantic.io"})
+		Expect(err).To(BeNil())
+		Expect(calls).To(Equal([]string{
+			"put cert-manager-role-test-cluster-policy",
+			"delete control-plane-test-cluster-policy",
+		}))
+	})
+})
+
+var _ = Describe("RoleDefinitions", func() {
+	It("has a unique definition per role type", func() {
+		seen := map[string]bool{}
+		for _, d := range iam.RoleDefinitions() {
+			Expect(seen).NotTo(HaveKey(d.Type))
+			seen[d.Type] = true
+		}
+	})
+
+	DescribeTable("definition",
+		func(roleType string, main bool, trustKind iam.TrustKind, expectedRoleName string, expectedServiceAccount string) {
+			var expectedServiceAccounts []iam.ServiceAccount
+			if expectedServiceAccount != "" {
+				var err error
+				expectedServiceAccounts, err = iam.ParseServiceAccounts(expectedServiceAccount)
+				Expect(err).To(BeNil())
+			}
+
+			d, ok := iam.GetRoleDefinition(roleType)
+			Expect(ok).To(BeTrue())
+			Expect(d.Main).To(Equal(main))
+			Expect(d.TrustKind).To(Equal(trustKind))
+			Expect(d.Description).NotTo(BeEmpty())
+			Expect(d.TrustPolicyTemplate).NotTo(BeEmpty())
+			Expect(d.PolicyTemplate).NotTo(BeEmpty())
+			if expectedRoleName != "" {
+				Expect(d.RoleName("test-cluster")).To(Equal(expectedRoleName))
+			}
+			Expect(d.ServiceAccounts).To(Equal(expectedServiceAccounts))
+		},
+		Entry("bastion", iam.BastionRole, true, iam.TrustKindEC2, "", ""),
+		Entry("control plane", iam.ControlPlaneRole, true, iam.TrustKindEC2, "", ""),
+		Entry("nodes", iam.NodesRole, true, iam.TrustKindEC2, "", ""),
+		Entry("IRSA", iam.IRSARole, true, iam.TrustKindIRSA, "", "kube-system:external-dns"),
+		Entry("Route53", iam.Route53Role, false, iam.TrustKindIRSA, "test-cluster-Route53Manager-Role", "*:*external-dns*"),
+		Entry("cert-manager", iam.CertManagerRole, false, iam.TrustKindIRSA, "test-cluster-CertManager-Role", "kube-system:cert-manager-app"),
+		Entry("ALB controller", iam.ALBConrollerRole, false, iam.TrustKindIRSA, "test-cluster-ALBController-Role", "*:aws-load-balancer-controller"),
+		Entry("EBS CSI driver", iam.EBSCSIDriverRole, false, iam.TrustKindIRSA, "test-cluster-ebs-csi-driver-role", "kube-system:ebs-csi-controller-sa"),
+		Entry("EFS CSI driver", iam.EFSCSIDriverRole, false, iam.TrustKindIRSA, "test-cluster-efs-csi-driver-role", "kube-system:efs-csi-sa"),
+		Entry("cluster autoscaler", iam.ClusterAutoscalerRole, false, iam.TrustKindIRSA, "test-cluster-cluster-autoscaler-role", "kube-system:cluster-autoscaler"),
+		Entry("Karpenter controller", iam.KarpenterControllerRole, false, iam.TrustKindIRSA, "test-cluster-karpenter-controller-role", "kube-system:karpenter"),
+	)
+
+	DescribeTable("release gating",
+		func(roleType string, release string, expectedManagedByCrossplane bool) {
+			d, ok := iam.GetRoleDefinition(roleType)
+			Expect(ok).To(BeTrue())
+			managedByCrossplane := d.CrossplaneRelease != nil && !semver.MustParse(release).LessThan(d.CrossplaneRelease)
+			Expect(managedByCrossplane).To(Equal(expectedManagedByCrossplane))
+		},
+		Entry("control plane before Crossplane", iam.ControlPlaneRole, "33.0.0", false),
+		Entry("control plane with Crossplane", iam.ControlPlaneRole, "34.0.0", true),
+		Entry("nodes with Crossplane", iam.NodesRole, "34.1.0", true),
+		Entry("bastion is never managed by Crossplane", iam.BastionRole, "34.0.0", false),
+		Entry("IRSA roles are never managed by Crossplane", iam.CertManagerRole, "34.0.0", false),
+	)
+
+	It("rejects role types without a main definition", func() {
+		_, err := iam.New(testConfig(iam.CertManagerRole, nil))
+		Expect(err).To(MatchError(ContainSubstring("invalid RoleType")))
+	})
+
+	It("validates role types", func() {
+		Expect(iam.ValidateRoleTypes([]string{iam.BastionRole, iam.CertManagerRole})).To(Succeed())
+		Expect(iam.ValidateRoleTypes([]string{"bastoin"})).To(MatchError(ContainSubstring(`unknown role type "bastoin"`)))
+	})
+})
+
+var _ = Describe("ReconcileRolesForIRSA disabled roles", func() {
+	var (
+		mockIAMClient *mocks.MockIAMClient
+		iamConfig     iam.IAMServiceConfig
+	)
+
+	BeforeEach(func() {
+		mockIAMClient = newMockIAMClient()
+
+		iamConfig = testConfig(iam.ControlPlaneRole, mockIAMClient)
+		iamConfig.IRSARoleTypes = []string{}
+	})
+
+	It("deletes operator-owned roles and keeps all others", func() {
+		mockIAMClient.EXPECT().GetRole(context.TODO(), &awsiam.GetRoleInput{
+			RoleName: aws.String("test-cluster-CertManager-Role"),
+		}).Return(&awsiam.GetRoleOutput{Role: &awsiamtypes.Role{Tags: ownedTags}}, nil).Times(2)
+		mockIAMClient.EXPECT().GetRole(context.TODO(), &awsiam.GetRoleInput{
+			RoleName: aws.String("test-cluster-ALBController-Role"),
+		}).Return(&awsiam.GetRoleOutput{Role: &awsiamtypes.Role{}}, nil)
+		mockIAMClient.EXPECT().GetRole(context.TODO(), gomock.Any()).Return(nil, &awsiamtypes.NoSuchEntityException{}).Times(5)
+
+		mockIAMClient.EXPECT().ListAttachedRolePolicies(context.TODO(), &awsiam.ListAttachedRolePoliciesInput{
+			RoleName: aws.String("test-cluster-CertManager-Role"),
+		}).Return(&awsiam.ListAttachedRolePoliciesOutput{}, nil)
+		mockIAMClient.EXPECT().ListRolePolicies(context.TODO(), &awsiam.ListRolePoliciesInput{
+			RoleName: aws.String("test-cluster-CertManager-Role"),
+		}).Return(&awsiam.ListRolePoliciesOutput{PolicyNames: []string{"cert-manager-role-test-cluster-policy"}}, nil)
+		mockIAMClient.EXPECT().DeleteRolePolicy(context.TODO(), &awsiam.DeleteRolePolicyInput{
+			RoleName:   aws.String("test-cluster-CertManager-Role"),
+			PolicyName: aws.String("cert-manager-role-test-cluster-policy"),
+		}).Return(&awsiam.DeleteRolePolicyOutput{}, nil)
+		mockIAMClient.EXPECT().RemoveRoleFromInstanceProfile(context.TODO(), &awsiam.RemoveRoleFromInstanceProfileInput{
+			InstanceProfileName: aws.String("test-cluster-CertManager-Role"),
+			RoleName:            aws.String("test-cluster-CertManager-Role"),
+		}).Return(&awsiam.RemoveRoleFromInstanceProfileOutput{}, nil)
+		mockIAMClient.EXPECT().DeleteInstanceProfile(context.TODO(), &awsiam.DeleteInstanceProfileInput{
+			InstanceProfileName: aws.String("test-cluster-CertManager-Role"),
+		}).Return(&awsiam.DeleteInstanceProfileOutput{}, nil)
+		mockIAMClient.EXPECT().DeleteRole(context.TODO(), &awsiam.DeleteRoleInput{
+			RoleName: aws.String("test-cluster-CertManager-Role"),
+		}).Return(&awsiam.DeleteRoleOutput{}, nil)
+
+		err := newTestService(iamConfig).ReconcileRolesForIRSA("012345678901", []string{"irsa.test.gaws.gigantic.io"})
+		Expect(err).To(BeNil())
+	})
+
+	It("rejects unknown role types", func() {
+		iamConfig.IRSARoleTypes = []string{iam.BastionRole}
+		_, err := iam.New(iamConfig)
+		Expect(err).To(MatchError(ContainSubstring("invalid IRSARoleTypes")))
+	})
+})
+
+var _ = Describe("ReconcileRolesForIRSA service accounts", func() {
+	var (
+		mockIAMClient *mocks.MockIAMClient
+		iamService    *iam.IAMService
+		trustPolicy   string
+	)
+
+	BeforeEach(func() {
+		mockIAMClient = newMockIAMClient()
+		trustPolicy = ""
+
+		iamConfig := testConfig(iam.ControlPlaneRole, mockIAMClient)
+		iamConfig.IRSARoleTypes = []string{iam.CertManagerRole}
+		iamConfig.ServiceAccounts = map[string][]iam.ServiceAccount{
+			iam.CertManagerRole: {
+				{Namespace: "cert-manager", Name: "cert-manager"},
+				{Namespace: "kube-system", Name: "cert-manager-app"},
+			},
+		}
+		iamService = newTestService(iamConfig)
+
+		expectNewRole(mockIAMClient, func(in *awsiam.CreateRoleInput) {
+			trustPolicy = aws.ToString(in.AssumeRolePolicyDocument)
+		})
+		// the roles of the other role types do not exist
+		mockIAMClient.EXPECT().GetRole(context.TODO(), gomock.Any()).Return(nil, &awsiamtypes.NoSuchEntityException{}).AnyTimes()
+		mockIAMClient.EXPECT().GetRolePolicy(context.TODO(), gomock.Any()).Return(nil, &awsiamtypes.NoSuchEntityException{})
+		mockIAMClient.EXPECT().PutRolePolicy(context.TODO(), gomock.Any()).Return(&awsiam.PutRolePolicyOutput{}, nil)
+		mockIAMClient.EXPECT().ListRolePolicies(context.TODO(), gomock.Any()).Return(&awsiam.ListRolePoliciesOutput{}, nil)
+	})
+
+	It("renders one condition entry per service account", func() {
+		err := iamService.ReconcileRolesForIRSA("012345678901", []string{"irsa.test.gaws.gigantic.io"})
+		Expect(err).To(BeNil())
+
+		var document struct {
+			Statement []struct {
+				Condition map[string]map[string][]string
+			}
+		}
+		Expect(json.Unmarshal([]byte(trustPolicy), &document)).To(Succeed())
+		Expect(document.Statement).To(HaveLen(1))
+		Expect(document.Statement[0].Condition["StringEquals"]["irsa.test.gaws.gigantic.io:sub"]).To(Equal([]string{
+			"system:serviceaccount:cert-manager:cert-manager",
+			"system:serviceaccount:kube-system:cert-manager-app",
+		}))
+	})
+})
+
+var _ = DescribeTable("ParseServiceAccounts",
+	func(value string, expected []iam.ServiceAccount, expectError bool) {
+		serviceAccounts, err := iam.ParseServiceAccounts(value)
+		if expectError {
+			Expect(err).NotTo(BeNil())
+			return
+		}
+		Expect(err).To(BeNil())
+		Expect(serviceAccounts).To(Equal(expected))
+	},
+	Entry("single", "kube-system:external-dns", []iam.ServiceAccount{{Namespace: "kube-system", Name: "external-dns"}}, false),
+	Entry("several with spaces", "a:b, c:d", []iam.ServiceAccount{{Namespace: "a", Name: "b"}, {Namespace: "c", Name: "d"}}, false),
+	Entry("missing namespace", "external-dns", nil, true),
+	Entry("empty name", "kube-system:", nil, true),
+	Entry("quote", `kube-system:a"b`, nil, true),
+	Entry("empty", "", nil, true),
+)
+
+var _ = Describe("ReconcileRolesForIRSA Karpenter controller role", func() {
+	var (
+		mockIAMClient *mocks.MockIAMClient
+		iamConfig     iam.IAMServiceConfig
+		policy        string
+		nodeRoleARNs  map[string]string
+	)
+
+	type statement struct {
+		Sid      string
+		Action   any
+		Resource any
+	}
+
+	findStatement := func(sid string) *statement {
+		var document struct {
+			Statement []statement
+		}
+		Expect(json.Unmarshal([]byte(policy), &document)).To(Succeed())
+		for i := range document.Statement {
+			if document.Statement[i].Sid == sid {
+				return &document.Statement[i]
+			}
+		}
+		return nil
+	}
+
+	BeforeEach(func() {
+		mockIAMClient = newMockIAMClient()
+		policy = ""
+		nodeRoleARNs = map[string]string{}
+
+		iamConfig = testConfig(iam.ControlPlaneRole, mockIAMClient)
+		iamConfig.Region = "eu-west-1"
+		iamConfig.IRSARoleTypes = []string{iam.KarpenterControllerRole}
+		iamConfig.FeatureGates = map[string]bool{iam.FeatureGateKarpenter: true}
+		iamConfig.Karpenter = iam.KarpenterConfig{
+			NodeRoleNames: []string{"test-cluster-karpenter-a", "test-cluster-karpenter-b"},
+		}
+
+		// only the node roles in nodeRoleARNs exist
+		mockIAMClient.EXPECT().GetRole(context.TODO(), gomock.Any()).DoAndReturn(func(_ context.Context, in *awsiam.GetRoleInput, _ ...func(*awsiam.Options)) (*awsiam.GetRoleOutput, error) {
+			arn, ok := nodeRoleARNs[aws.ToString(in.RoleName)]
+			if !ok {
+				return nil, &awsiamtypes.NoSuchEntityException{}
+			}
+			return &awsiam.GetRoleOutput{Role: &awsiamtypes.Role{RoleName: in.RoleName, Arn: aws.String(arn)}}, nil
+		}).AnyTimes()
+		mockIAMClient.EXPECT().CreateRole(context.TODO(), gomock.Any()).Return(&awsiam.CreateRoleOutput{}, nil).AnyTimes()
+		mockIAMClient.EXPECT().GetInstanceProfile(context.TODO(), gomock.Any()).Return(nil, &awsiamtypes.NoSuchEntityException{}).AnyTimes()
+		mockIAMClient.EXPECT().CreateInstanceProfile(context.TODO(), gomock.Any()).Return(&awsiam.CreateInstanceProfileOutput{}, nil).AnyTimes()
+		mockIAMClient.EXPECT().AddRoleToInstanceProfile(context.TODO(), gomock.Any()).Return(&awsiam.AddRoleToInstanceProfileOutput{}, nil).AnyTimes()
+		mockIAMClient.EXPECT().GetRolePolicy(context.TODO(), gomock.Any()).Return(nil, &awsiamtypes.NoSuchEntityException{}).AnyTimes()
+		mockIAMClient.EXPECT().ListRolePolicies(context.TODO(), gomock.Any()).Return(&awsiam.ListRolePoliciesOutput{}, nil).AnyTimes()
+		mockIAMClient.EXPECT().PutRolePolicy(context.TODO(), gomock.Any()).DoAndReturn(func(_ context.Context, in *awsiam.PutRolePolicyInput, _ ...func(*awsiam.Options)) (*awsiam.PutRolePolicyOutput, error) {
+			Expect(aws.ToString(in.RoleName)).To(Equal("test-cluster-karpenter-controller-role"))
+			policy = aws.ToString(in.PolicyDocument)
+			return &awsiam.PutRolePolicyOutput{}, nil
+		}).AnyTimes()
+	})
+
+	It("scopes PassRole to the node roles of the cluster", func() {
+		iamService := newTestService(iamConfig)
+
+		err := iamService.ReconcileRolesForIRSA("012345678901", []string{"irsa.test.gaws.gigantic.io"})
+		Expect(err).To(BeNil())
+
+		Expect(isValidJSON(policy)).To(BeTrue())
+		passRole := findStatement("AllowPassingInstanceRole")
+		Expect(passRole).NotTo(BeNil())
+		Expect(passRole.Resource).To(ConsistOf(
+			"arn:aws:iam::012345678901:role/test-cluster-karpenter-a",
+			"arn:aws:iam::012345678901:role/test-cluster-karpenter-b",
+		))
+		Expect(findStatement("AllowInterruptionQueueActions")).To(BeNil())
+	})
+
+	It("uses the path of existing node roles", func() {
+		nodeRoleARNs["test-cluster-karpenter-a"] = "arn:aws:iam::012345678901:role/karpenter/test-cluster-karpenter-a"
+		iamService := newTestService(iamConfig)
+		err := iamService.ReconcileRolesForIRSA("012345678901", []string{"irsa.test.gaws.gigantic.io"})
+		Expect(err).To(BeNil())
+
+		passRole := findStatement("AllowPassingInstanceRole")
+		Expect(passRole).NotTo(BeNil())
+		Expect(passRole.Resource).To(ConsistOf(
+			"arn:aws:iam::012345678901:role/karpenter/test-cluster-karpenter-a",
+			"arn:aws:iam::012345678901:role/test-cluster-karpenter-b",
+		))
+	})
+
+	It("grants access to the interruption queue of the cluster", func() {
+		iamConfig.Karpenter.InterruptionQueueARN = "arn:aws:sqs:eu-west-1:012345678901:test-cluster-karpenter"
+		iamService := newTestService(iamConfig)
+
+		err := iamService.ReconcileRolesForIRSA("012345678901", []string{"irsa.test.gaws.gigantic.io"})
+		Expect(err).To(BeNil())
+
+		Expect(isValidJSON(policy)).To(BeTrue())
+		queue := findStatement("AllowInterruptionQueueActions")
+		Expect(queue).NotTo(BeNil())
+		Expect(queue.Resource).To(Equal("arn:aws:sqs:eu-west-1:012345678901:test-cluster-karpenter"))
+	})
+
+	It("rejects an interruption queue ARN of another service", func() {
+		iamConfig.Karpenter.InterruptionQueueARN = "arn:aws:sns:eu-west-1:012345678901:test-cluster-karpenter"
+		_, err := iam.New(iamConfig)
+		Expect(err).To(MatchError(ContainSubstring("not the ARN of an SQS queue")))
+	})
+
+	It("is not created without the feature gate", func() {
+		iamConfig.FeatureGates = nil
+		iamService := newTestService(iamConfig)
+
+		err := iamService.ReconcileRolesForIRSA("012345678901", []string{"irsa.test.gaws.gigantic.io"})
+		Expect(err).To(BeNil())
+		Expect(policy).To(BeEmpty())
+	})
+})
+
+var _ = Describe("ReconcileRolesForIRSA Pod Identity", func() {
+	const roleARN = "arn:aws:iam::012345678901:role/test-cluster-CertManager-Role"
+
+	var (
+		mockIAMClient *mocks.MockIAMClient
+		mockEKSClient *mocks.MockEKSClient
+		iamConfig     iam.IAMServiceConfig
+		trustPolicy   string
+		associations  []ekstypes.PodIdentityAssociation
+	)
+
+	BeforeEach(func() {
+		mockIAMClient = newMockIAMClient()
+		mockEKSClient = newMockEKSClient()
+		trustPolicy = ""
+		associations = nil
+
+		iamConfig = podIdentityConfig(iam.CertManagerRole, mockIAMClient, mockEKSClient)
+		iamConfig.ServiceAccountTrust = []iam.TrustKind{iam.TrustKindIRSA, iam.TrustKindPodIdentity}
+
+		created := false
+		mockIAMClient.EXPECT().GetRole(context.TODO(), gomock.Any()).DoAndReturn(func(_ context.Context, in *awsiam.GetRoleInput, _ ...func(*awsiam.Options)) (*awsiam.GetRoleOutput, error) {
+			if !created {
+				return nil, &awsiamtypes.NoSuchEntityException{}
+			}
+			return &awsiam.GetRoleOutput{Role: &awsiamtypes.Role{RoleName: in.RoleName, Arn: aws.String(roleARN)}}, nil
+		}).AnyTimes()
+		mockIAMClient.EXPECT().CreateRole(context.TODO(), gomock.Any()).DoAndReturn(func(_ context.Context, in *awsiam.CreateRoleInput, _ ...func(*awsiam.Options)) (*awsiam.CreateRoleOutput, error) {
+			created = true
+			trustPolicy = aws.ToString(in.AssumeRolePolicyDocument)
+			return &awsiam.CreateRoleOutput{}, nil
+		})
+		mockIAMClient.EXPECT().GetInstanceProfile(context.TODO(), gomock.Any()).Return(nil, &awsiamtypes.NoSuchEntityException{})
+		mockIAMClient.EXPECT().CreateInstanceProfile(context.TODO(), gomock.Any()).Return(&awsiam.CreateInstanceProfileOutput{}, nil)
+		mockIAMClient.EXPECT().AddRoleToInstanceProfile(context.TODO(), gomock.Any()).Return(&awsiam.AddRoleToInstanceProfileOutput{}, nil)
+		mockIAMClient.EXPECT().GetRolePolicy(context.TODO(), gomock.Any()).Return(nil, &awsiamtypes.NoSuchEntityException{})
+		mockIAMClient.EXPECT().PutRolePolicy(context.TODO(), gomock.Any()).Return(&awsiam.PutRolePolicyOutput{}, nil)
+
+		mockEKSClient.EXPECT().ListPodIdentityAssociations(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, in *awseks.ListPodIdentityAssociationsInput, _ ...func(*awseks.Options)) (*awseks.ListPodIdentityAssociationsOutput, error) {
+			Expect(aws.ToString(in.ClusterName)).To(Equal("test-eks-cluster"))
+			var summaries []ekstypes.PodIdentityAssociationSummary
+			for _, a := range associations {
+				summaries = append(summaries, ekstypes.PodIdentityAssociationSummary{AssociationId: a.AssociationId})
+			}
+			return &awseks.ListPodIdentityAssociationsOutput{Associations: summaries}, nil
+		}).AnyTimes()
+		mockEKSClient.EXPECT().DescribePodIdentityAssociation(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, in *awseks.DescribePodIdentityAssociationInput, _ ...func(*awseks.Options)) (*awseks.DescribePodIdentityAssociationOutput, error) {
+			for i := range associations {
+				if aws.ToString(associations[i].AssociationId) == aws.ToString(in.AssociationId) {
+					return &awseks.DescribePodIdentityAssociationOutput{Association: &associations[i]}, nil
+				}
+			}
+			return nil, &ekstypes.ResourceNotFoundException{}
+		}).AnyTimes()
+	})
+
+	It("trusts both the OIDC provider and EKS Pod Identity and associates the service account", func() {
+		mockIAMClient.EXPECT().ListRolePolicies(context.TODO(), gomock.Any()).Return(&awsiam.ListRolePoliciesOutput{}, nil)
+		associations = []ekstypes.PodIdentityAssociation{
+			{
+				AssociationId:  aws.String("a-owned"),
+				Namespace:      aws.String("kube-system"),
+				ServiceAccount: aws.String("old-sa"),
+				RoleArn:        aws.String(roleARN),
+				Tags:           map[string]string{iam.IAMControllerOwnedTag: ""},
+			},
+			{
+				AssociationId:  aws.String("a-foreign"),
+				Namespace:      aws.String("default"),
+				ServiceAccount: aws.String("app"),
+				RoleArn:        aws.String("arn:aws:iam::012345678901:role/app"),
+			},
+		}
+		mockEKSClient.EXPECT().CreatePodIdentityAssociation(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, in *awseks.CreatePodIdentityAssociationInput, _ ...func(*awseks.Options)) (*awseks.CreatePodIdentityAssociationOutput, error) {
+			Expect(aws.ToString(in.ClusterName)).To(Equal("test-eks-cluster"))
+			Expect(aws.ToString(in.Namespace)).To(Equal("kube-system"))
+			Expect(aws.ToString(in.ServiceAccount)).To(Equal("cert-manager-app"))
+			Expect(aws.ToString(in.RoleArn)).To(Equal(roleARN))
+			Expect(in.Tags).To(HaveKey(iam.IAMControllerOwnedTag))
+			return &awseks.CreatePodIdentityAssociationOutput{}, nil
+		})
+		mockEKSClient.EXPECT().DeletePodIdentityAssociation(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, in *awseks.DeletePodIdentityAssociationInput, _ ...func(*awseks.Options)) (*awseks.DeletePodIdentityAssociationOutput, error) {
+			Expect(aws.ToString(in.AssociationId)).To(Equal("a-owned"))
+			return &awseks.DeletePodIdentityAssociationOutput{}, nil
+		})
+
+		iamService := newTestService(iamConfig)
+		err := iamService.ReconcileRolesForIRSA("012345678901", []string{"irsa.test.gaws.gigantic.io"})
+		Expect(err).To(BeNil())
+
+		var document struct {
+			Statement []struct {
+				Principal map[string]string
+				Action    any
+			}
+		}
+		Expect(json.Unmarshal([]byte(trustPolicy), &document)).To(Succeed())
+		Expect(document.Statement).To(HaveLen(2))
+		Expect(document.Statement[0].Principal).To(HaveKeyWithValue("Federated", "arn:aws:iam::012345678901:oidc-provider/irsa.test.gaws.gigantic.io"))
+		Expect(document.Statement[1].Principal).To(HaveKeyWithValue("Service", "pods.eks.amazonaws.com"))
+		Expect(document.Statement[1].Action).To(ConsistOf("sts:AssumeRole", "sts:TagSession"))
+	})
+
+	It("only trusts EKS Pod Identity without IRSA and needs no trust domains", func() {
+		iamConfig.ServiceAccountTrust = []iam.TrustKind{iam.TrustKindPodIdentity}
+		mockIAMClient.EXPECT().ListRolePolicies(context.TODO(), gomock.Any()).Return(&awsiam.ListRolePoliciesOutput{}, nil)
+		mockEKSClient.EXPECT().CreatePodIdentityAssociation(gomock.Any(), gomock.Any()).Return(&awseks.CreatePodIdentityAssociationOutput{}, nil)
+
+		iamService := newTestService(iamConfig)
+		err := iamService.ReconcileRolesForIRSA("012345678901", nil)
+		Expect(err).To(BeNil())
+
+		Expect(isValidJSON(trustPolicy)).To(BeTrue())
+		Expect(trustPolicy).NotTo(ContainSubstring("oidc-provider"))
+		Expect(trustPolicy).To(ContainSubstring(`"aws:SourceArn": "arn:aws:eks:eu-west-1:012345678901:cluster/test-eks-cluster"`))
+	})
+
+	It("does not take over an association of the service account with another role", func() {
+		associations = []ekstypes.PodIdentityAssociation{
+			{
+				AssociationId:  aws.String("a-foreign"),
+				Namespace:      aws.String("kube-system"),
+				ServiceAccount: aws.String("cert-manager-app"),
+				RoleArn:        aws.String("arn:aws:iam::012345678901:role/other"),
+			},
+		}
+		mockIAMClient.EXPECT().ListRolePolicies(context.TODO(), gomock.Any()).Return(&awsiam.ListRolePoliciesOutput{}, nil)
+
+		iamService := newTestService(iamConfig)
+		err := iamService.ReconcileRolesForIRSA("012345678901", []string{"irsa.test.gaws.gigantic.io"})
+		Expect(iam.IsPodIdentityAssociationConflict(err)).To(BeTrue())
+	})
+})
+
+var _ = Describe("Pod Identity without the EKS cluster or exact service accounts", func() {
+	var (
+		mockIAMClient *mocks.MockIAMClient
+		mockEKSClient *mocks.MockEKSClient
+		iamConfig     iam.IAMServiceConfig
+	)
+
+	BeforeEach(func() {
+		mockIAMClient = newMockIAMClient()
+		mockEKSClient = newMockEKSClient()
+
+		iamConfig = podIdentityConfig(iam.Route53Role, mockIAMClient, mockEKSClient)
+
+		mockIAMClient.EXPECT().GetRole(context.TODO(), gomock.Any()).Return(nil, &awsiamtypes.NoSuchEntityException{}).AnyTimes()
+	})
+
+	It("deletes the roles of a cluster whose EKS cluster is already gone", func() {
+		iamConfig.ClusterIsBeingDeleted = true
+		mockEKSClient.EXPECT().ListPodIdentityAssociations(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, &ekstypes.ResourceNotFoundException{})
+
+		iamService := newTestService(iamConfig)
+		err := iamService.DeleteRolesForIRSA()
+		Expect(err).To(BeNil())
+	})
+
+	It("rejects service accounts with wildcards without IRSA", func() {
+		iamService := newTestService(iamConfig)
+		err := iamService.ReconcileRolesForIRSA("012345678901", nil)
+		Expect(iam.IsInvalidServiceAccounts(err)).To(BeTrue())
+		Expect(iam.ClassifyError(err)).To(Equal(iam.ErrorClassInvalidInput))
+	})
+})
+
+var _ = Describe("New with Pod Identity", func() {
+	It("rejects Pod Identity without an EKS cluster name", func() {
+		iamConfig := podIdentityConfig(iam.CertManagerRole, nil, nil)
+		iamConfig.EKSClusterName = ""
+		_, err := iam.New(iamConfig)
+		Expect(err).To(MatchError(ContainSubstring("EKSClusterName")))
+	})
+})
+
+var _ = DescribeTable("ParseServiceAccountTrust",
+	func(value string, expected []iam.TrustKind, expectError bool) {
+		kinds, err := iam.ParseServiceAccountTrust(value)
+		if expectError {
+			Expect(err).NotTo(BeNil())
+			return
+		}
+		Expect(err).To(BeNil())
+		Expect(kinds).To(Equal(expected))
+	},
+	Entry("IRSA", "irsa", []iam.TrustKind{iam.TrustKindIRSA}, false),
+	Entry("Pod Identity", "pod-identity", []iam.TrustKind{iam.TrustKindPodIdentity}, false),
+	Entry("both", "irsa, pod-identity", []iam.TrustKind{iam.TrustKindIRSA, iam.TrustKindPodIdentity}, false),
+	Entry("EC2", "ec2", nil, true),
+	Entry("empty", "", nil, true),
+)
+
+var _ = Describe("ReconcileOIDCProviders", func() {
+	const (
+		providerARN = "arn:aws:iam::012345678901:oidc-provider/irsa.test.gaws.gigantic.io"
+		thumbprint  = "9e99a48a9960b14926bb7f3b02e22da2b0ab7280"
+	)
+
+	var (
+		mockIAMClient *mocks.MockIAMClient
+		iamConfig     iam.IAMServiceConfig
+	)
+
+	BeforeEach(func() {
+		mockIAMClient = newMockIAMClient()
+
+		iamConfig = testConfig(iam.ControlPlaneRole, mockIAMClient)
+		iamConfig.Region = "eu-west-1"
+		iamConfig.ManageOIDCProviders = true
+		iamConfig.OIDCProviderThumbprints = []string{thumbprint}
+	})
+
+	It("returns a missing provider instead of creating it if providers are not managed", func() {
+		iamConfig.ManageOIDCProviders = false
+		mockIAMClient.EXPECT().GetOpenIDConnectProvider(context.TODO(), &awsiam.GetOpenIDConnectProviderInput{
+			OpenIDConnectProviderArn: aws.String(providerARN),
+		}).Return(nil, &awsiamtypes.NoSuchEntityException{})
+
+		iamService := newTestService(iamConfig)
+		missing, err := iamService.ReconcileOIDCProviders("012345678901", []string{"irsa.test.gaws.gigantic.io"})
+		Expect(err).To(BeNil())
+		Expect(missing).To(Equal([]string{"irsa.test.gaws.gigantic.io"}))
+	})
+
+	It("creates a missing provider", func() {
+		mockIAMClient.EXPECT().GetOpenIDConnectProvider(context.TODO(), gomock.Any()).Return(nil, &awsiamtypes.NoSuchEntityException{})
+		mockIAMClient.EXPECT().CreateOpenIDConnectProvider(context.TODO(), &awsiam.CreateOpenIDConnectProviderInput{
+			Url:            aws.String("https://irsa.test.gaws.gigantic.io"),
+			ClientIDList:   []string{"sts.amazonaws.com"},
+			ThumbprintList: []string{thumbprint},
+			Tags:           ownedTags,
+		}).Return(&awsiam.CreateOpenIDConnectProviderOutput{}, nil)
+
+		iamService := newTestService(iamConfig)
+		missing, err := iamService.ReconcileOIDCProviders("012345678901", []string{"irsa.test.gaws.gigantic.io"})
+		Expect(err).To(BeNil())
+		Expect(missing).To(BeEmpty())
+	})
+
+	It("reconciles client IDs and thumbprints of an owned provider", func() {
+		mockIAMClient.EXPECT().GetOpenIDConnectProvider(context.TODO(), gomock.Any()).Return(&awsiam.GetOpenIDConnectProviderOutput{
+			ClientIDList:   []string{"other"},
+			ThumbprintList: []string{"0000000000000000000000000000000000000000"},
+			Tags:           ownedTags,
+		}, nil)
+		mockIAMClient.EXPECT().RemoveClientIDFromOpenIDConnectProvider(context.TODO(), &awsiam.RemoveClientIDFromOpenIDConnectProviderInput{
+			OpenIDConnectProviderArn: aws.String(providerARN),
+			ClientID:                 aws.String("other"),
+		}).Return(&awsiam.RemoveClientIDFromOpenIDConnectProviderOutput{}, nil)
+		mockIAMClient.EXPECT().AddClientIDToOpenIDConnectProvider(context.TODO(), &awsiam.AddClientIDToOpenIDConnectProviderInput{
+			OpenIDConnectProviderArn: aws.String(providerARN),
+			ClientID:                 aws.String("sts.amazonaws.com"),
+		}).Return(&awsiam.AddClientIDToOpenIDConnectProviderOutput{}, nil)
+		mockIAMClient.EXPECT().UpdateOpenIDConnectProviderThumbprint(context.TODO(), &awsiam.UpdateOpenIDConnectProviderThumbprintInput{
+			OpenIDConnectProviderArn: aws.String(providerARN),
+			ThumbprintList:           []string{thumbprint},
+		}).Return(&awsiam.UpdateOpenIDConnectProviderThumbprintOutput{}, nil)
+
+		iamService := newTestService(iamConfig)
+		missing, err := iamService.ReconcileOIDCProviders("012345678901", []string{"irsa.test.gaws.gigantic.io"})
+		Expect(err).To(BeNil())
+		Expect(missing).To(BeEmpty())
+	})
+
+	It("leaves a provider created by others alone", func() {
+		mockIAMClient.EXPECT().GetOpenIDConnectProvider(context.TODO(), gomock.Any()).Return(&awsiam.GetOpenIDConnectProviderOutput{
+			ClientIDList: []string{"other"},
+		}, nil)
+
+		iamService := newTestService(iamConfig)
+		missing, err := iamService.ReconcileOIDCProviders("012345678901", []string{"irsa.test.gaws.gigantic.io"})
+		Expect(err).To(BeNil())
+		Expect(missing).To(BeEmpty())
+	})
+
+	It("deletes the owned providers of the cluster with the cluster", func() {
+		iamConfig.ClusterIsBeingDeleted = true
+		otherARN := "arn:aws:iam::012345678901:oidc-provider/irsa.other.gaws.gigantic.io"
+		foreignARN := "arn:aws:iam::012345678901:oidc-provider/oidc.example.com"
+		mockIAMClient.EXPECT().ListOpenIDConnectProviders(context.TODO(), gomock.Any()).Return(&awsiam.ListOpenIDConnectProvidersOutput{
+			OpenIDConnectProviderList: []awsiamtypes.OpenIDConnectProviderListEntry{
+				{Arn: aws.String(providerARN)},
+				{Arn: aws.String(otherARN)},
+				{Arn: aws.String(foreignARN)},
+			},
+		}, nil)
+		mockIAMClient.EXPECT().GetOpenIDConnectProvider(context.TODO(), &awsiam.GetOpenIDConnectProviderInput{OpenIDConnectProviderArn: aws.String(providerARN)}).Return(&awsiam.GetOpenIDConnectProviderOutput{Tags: ownedTags}, nil)
+		mockIAMClient.EXPECT().GetOpenIDConnectProvider(context.TODO(), &awsiam.GetOpenIDConnectProviderInput{OpenIDConnectProviderArn: aws.String(otherARN)}).Return(&awsiam.GetOpenIDConnectProviderOutput{
+			Tags: []awsiamtypes.Tag{
+				{Key: aws.String(iam.IAMControllerOwnedTag), Value: aws.String("")},
+				{Key: aws.String("sigs.k8s.io/cluster-api-provider-aws/cluster/other-cluster"), Value: aws.String("owned")},
+			},
+		}, nil)
+		mockIAMClient.EXPECT().GetOpenIDConnectProvider(context.TODO(), &awsiam.GetOpenIDConnectProviderInput{OpenIDConnectProviderArn: aws.String(foreignARN)}).Return(&awsiam.GetOpenIDConnectProviderOutput{}, nil)
+		mockIAMClient.EXPECT().DeleteOpenIDConnectProvider(context.TODO(), &awsiam.DeleteOpenIDConnectProviderInput{
+			OpenIDConnectProviderArn: aws.String(providerARN),
+		}).Return(&awsiam.DeleteOpenIDConnectProviderOutput{}, nil)
+
+		iamService := newTestService(iamConfig)
+		err := iamService.DeleteOIDCProviders()
+		Expect(err).To(BeNil())
+	})
+
+	It("rejects invalid thumbprints", func() {
+		iamConfig.OIDCProviderThumbprints = []string{"not-a-thumbprint"}
+		_, err := iam.New(iamConfig)
+		Expect(err).To(MatchError(ContainSubstring("invalid thumbprint")))
+	})
+})
+
+var _ = DescribeTable("ClassifyError",
+	func(err error, class iam.ErrorClass) {
+		Expect(iam.ClassifyError(err)).To(Equal(class))
+		// the class survives the wrapping of errors on their way to the controllers
+		Expect(iam.ClassifyError(fmt.Errorf("failed to reconcile role: %w", err))).To(Equal(class))
+	},
+	Entry("AccessDenied", &smithy.GenericAPIError{Code: "AccessDenied"}, iam.ErrorClassAccessDenied),
+	Entry("EKS AccessDeniedException", &ekstypes.AccessDeniedException{}, iam.ErrorClassAccessDenied),
+	Entry("LimitExceeded", &awsiamtypes.LimitExceededException{}, iam.ErrorClassLimitExceeded),
+	Entry("EKS ResourceLimitExceeded", &ekstypes.ResourceLimitExceededException{}, iam.ErrorClassLimitExceeded),
+	Entry("MalformedPolicyDocument", &awsiamtypes.MalformedPolicyDocumentException{}, iam.ErrorClassMalformedPolicy),
+	Entry("invalid policy template", invalidPolicyTemplateErr(), iam.ErrorClassMalformedPolicy),
+	Entry("Throttling", &smithy.GenericAPIError{Code: "Throttling"}, iam.ErrorClassThrottling),
+	Entry("EKS ThrottlingException", &ekstypes.ThrottlingException{}, iam.ErrorClassThrottling),
+	Entry("ConcurrentModification", &awsiamtypes.ConcurrentModificationException{}, iam.ErrorClassConcurrentModification),
+	Entry("DeleteConflict", &awsiamtypes.DeleteConflictException{}, iam.ErrorClassDeleteConflict),
+	Entry("InvalidInput", &awsiamtypes.InvalidInputException{}, iam.ErrorClassInvalidInput),
+	Entry("EKS InvalidParameter", &ekstypes.InvalidParameterException{}, iam.ErrorClassInvalidInput),
+	Entry("ExpiredToken", &smithy.GenericAPIError{Code: "ExpiredToken"}, iam.ErrorClassExpiredCredentials),
+	Entry("NoSuchEntity", &awsiamtypes.NoSuchEntityException{}, iam.ErrorClassUnknown),
+	Entry("other errors", errors.New("connection refused"), iam.ErrorClassUnknown),
+)
+
+var _ = Describe("ErrorClass behaviours", func() {
+	It("only requeues errors that persist until the cluster changes after a long time", func() {
+		for _, class := range []iam.ErrorClass{iam.ErrorClassMalformedPolicy, iam.ErrorClassInvalidInput} {
+			Expect(class.Behaviour().Retry).To(BeFalse(), string(class))
+			Expect(class.Behaviour().RequeueAfter).To(BeNumerically(">=", 10*time.Minute), string(class))
+			Expect(class.Behaviour().Report).To(BeTrue(), string(class))
+		}
+	})
+
+	It("backs off longer from quotas than from concurrent modifications", func() {
+		Expect(iam.ErrorClassLimitExceeded.Behaviour().RequeueAfter).To(BeNumerically(">", iam.ErrorClassConcurrentModification.Behaviour().RequeueAfter))
+		Expect(iam.ErrorClassConcurrentModification.Behaviour().RequeueAfter).To(BeNumerically(">", 0))
+	})
+
+	It("leaves unknown errors to the backoff of controller-runtime", func() {
+		Expect(iam.ErrorClassUnknown.Behaviour()).To(Equal(iam.ErrorBehaviour{Retry: true}))
+		Expect(iam.ErrorClass("Other").Behaviour()).To(Equal(iam.ErrorBehaviour{Retry: true}))
+	})
+})
+
+func invalidPolicyTemplateErr() error {
+	_, err := iam.ParsePolicyTemplates(map[string]string{"nodes.inline-policy": "{{ .ClusterName "})
+	return err
+}
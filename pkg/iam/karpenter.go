@@ -0,0 +1,64 @@
+package iam
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsarn "github.com/aws/aws-sdk-go-v2/aws/arn"
+	"github.com/aws/aws-sdk-go-v2/service/iam"
+
+	"github.com/giantswarm/capa-iam-operator/v3/pkg/partition"
+)
+
+// FeatureGateKarpenter enables the Karpenter controller role. It is enabled for clusters with KarpenterMachinePools.
+const FeatureGateKarpenter = "karpenter"
+
+// KarpenterConfig holds the cluster-specific inputs of the Karpenter controller role.
+type KarpenterConfig struct {
+	// NodeRoleNames are the roles of the nodes launched by Karpenter, which the controller may pass to EC2.
+	NodeRoleNames []string
+	// InterruptionQueueARN is the ARN of the SQS queue Karpenter receives interruption events from. Empty means
+	// interruption handling is not used.
+	InterruptionQueueARN string
+}
+
+// Validate returns an error if the interruption queue ARN is not the ARN of an SQS queue.
+func (c KarpenterConfig) Validate() error {
+	if c.InterruptionQueueARN == "" {
+		return nil
+	}
+	a, err := awsarn.Parse(c.InterruptionQueueARN)
+	if err != nil {
+		return fmt.Errorf("invalid interruption queue ARN %q: %w", c.InterruptionQueueARN, err)
+	}
+	if a.Service != "sqs" {
+		return fmt.Errorf("interruption queue ARN %q is not the ARN of an SQS queue", c.InterruptionQueueARN)
+	}
+	return nil
+}
+
+// karpenterNodeRoleARNs returns the ARNs of the Karpenter node roles. The ARNs contain the path of the roles, which may
+// differ from the configured path of the nodes role, so they are looked up. A role that does not exist yet is expected
+// at the configured path, and the next reconciliation corrects the ARN if the role is created elsewhere.
+func (s *IAMService) karpenterNodeRoleARNs(awsAccountID string) ([]string, error) {
+	var arns []string
+	for _, roleName := range s.karpenter.NodeRoleNames {
+		l := s.log.WithValues("role_name", roleName)
+
+		role, err := s.iamClient.GetRole(context.TODO(), &iam.GetRoleInput{
+			RoleName: aws.String(roleName),
+		})
+		if IsNotFound(err) {
+			l.Info("Karpenter node role does not exist yet, assuming the path of the nodes role")
+			arns = append(arns, fmt.Sprintf("arn:%s:iam::%s:role%s%s", partition.ForRegion(s.region).Name, awsAccountID, s.roleSettings(NodesRole).Path, roleName))
+			continue
+		}
+		if err != nil {
+			l.Error(err, "failed to fetch Karpenter node role")
+			return nil, err
+		}
+		arns = append(arns, aws.ToString(role.Role.Arn))
+	}
+	return arns, nil
+}
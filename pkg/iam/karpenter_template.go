@@ -0,0 +1,192 @@
+package iam
+
+const karpenterControllerPolicyTemplate = `{
+  "Version": "2012-10-17",
+  "Statement": [
+    {
+      "Sid": "AllowScopedEC2InstanceAccessActions",
+      "Effect": "Allow",
+      "Resource": [
+        "arn:{{ .AWSPartition }}:ec2:{{ .Region }}::image/*",
+        "arn:{{ .AWSPartition }}:ec2:{{ .Region }}::snapshot/*",
+        "arn:{{ .AWSPartition }}:ec2:{{ .Region }}:*:security-group/*",
+        "arn:{{ .AWSPartition }}:ec2:{{ .Region }}:*:subnet/*",
+        "arn:{{ .AWSPartition }}:ec2:{{ .Region }}:*:capacity-reservation/*"
+      ],
+      "Action": [
+        "ec2:RunInstances",
+        "ec2:CreateFleet"
+      ]
+    },
+    {
+      "Sid": "AllowScopedEC2LaunchTemplateAccessActions",
+      "Effect": "Allow",
+      "Resource": "arn:{{ .AWSPartition }}:ec2:{{ .Region }}:*:launch-template/*",
+      "Action": [
+        "ec2:RunInstances",
+        "ec2:CreateFleet"
+      ],
+      "Condition": {
+        "StringEquals": {
+          "aws:ResourceTag/kubernetes.io/cluster/{{ .ClusterName }}": "owned"
+        },
+        "StringLike": {
+          "aws:ResourceTag/karpenter.sh/nodepool": "*"
+        }
+      }
+    },
+    {
+      "Sid": "AllowScopedEC2InstanceActionsWithTags",
+      "Effect": "Allow",
+      "Resource": [
+        "arn:{{ .AWSPartition }}:ec2:{{ .Region }}:*:fleet/*",
+        "arn:{{ .AWSPartition }}:ec2:{{ .Region }}:*:instance/*",
+        "arn:{{ .AWSPartition }}:ec2:{{ .Region }}:*:volume/*",
+        "arn:{{ .AWSPartition }}:ec2:{{ .Region }}:*:network-interface/*",
+        "arn:{{ .AWSPartition }}:ec2:{{ .Region }}:*:launch-template/*",
+        "arn:{{ .AWSPartition }}:ec2:{{ .Region }}:*:spot-instances-request/*"
+      ],
+      "Action": [
+        "ec2:RunInstances",
+        "ec2:CreateFleet",
+        "ec2:CreateLaunchTemplate"
+      ],
+      "Condition": {
+        "StringEquals": {
+          "aws:RequestTag/kubernetes.io/cluster/{{ .ClusterName }}": "owned"
+        },
+        "StringLike": {
+          "aws:RequestTag/karpenter.sh/nodepool": "*"
+        }
+      }
+    },
+    {
+      "Sid": "AllowScopedResourceCreationTagging",
+      "Effect": "Allow",
+      "Resource": [
+        "arn:{{ .AWSPartition }}:ec2:{{ .Region }}:*:fleet/*",
+        "arn:{{ .AWSPartition }}:ec2:{{ .Region }}:*:instance/*",
+        "arn:{{ .AWSPartition }}:ec2:{{ .Region }}:*:volume/*",
+        "arn:{{ .AWSPartition }}:ec2:{{ .Region }}:*:network-interface/*",
+        "arn:{{ .AWSPartition }}:ec2:{{ .Region }}:*:launch-template/*",
+        "arn:{{ .AWSPartition }}:ec2:{{ .Region }}:*:spot-instances-request/*"
+      ],
+      "Action": "ec2:CreateTags",
+      "Condition": {
+        "StringEquals": {
+          "aws:RequestTag/kubernetes.io/cluster/{{ .ClusterName }}": "owned",
+          "ec2:CreateAction": [
+            "RunInstances",
+            "CreateFleet",
+            "CreateLaunchTemplate"
+          ]
+        },
+        "StringLike": {
+          "aws:RequestTag/karpenter.sh/nodepool": "*"
+        }
+      }
+    },
+    {
+      "Sid": "AllowScopedResourceTagging",
+      "Effect": "Allow",
+      "Resource": "arn:{{ .AWSPartition }}:ec2:{{ .Region }}:*:instance/*",
+      "Action": "ec2:CreateTags",
+      "Condition": {
+        "StringEquals": {
+          "aws:ResourceTag/kubernetes.io/cluster/{{ .ClusterName }}": "owned"
+        },
+        "StringLike": {
+          "aws:ResourceTag/karpenter.sh/nodepool": "*"
+        }
+      }
+    },
+    {
+      "Sid": "AllowScopedDeletion",
+      "Effect": "Allow",
+      "Resource": [
+        "arn:{{ .AWSPartition }}:ec2:{{ .Region }}:*:instance/*",
+        "arn:{{ .AWSPartition }}:ec2:{{ .Region }}:*:launch-template/*"
+      ],
+      "Action": [
+        "ec2:TerminateInstances",
+        "ec2:DeleteLaunchTemplate"
+      ],
+      "Condition": {
+        "StringEquals": {
+          "aws:ResourceTag/kubernetes.io/cluster/{{ .ClusterName }}": "owned"
+        },
+        "StringLike": {
+          "aws:ResourceTag/karpenter.sh/nodepool": "*"
+        }
+      }
+    },
+    {
+      "Sid": "AllowRegionalReadActions",
+      "Effect": "Allow",
+      "Resource": "*",
+      "Action": [
+        "ec2:DescribeAvailabilityZones",
+        "ec2:DescribeCapacityReservations",
+        "ec2:DescribeImages",
+        "ec2:DescribeInstances",
+        "ec2:DescribeInstanceTypeOfferings",
+        "ec2:DescribeInstanceTypes",
+        "ec2:DescribeLaunchTemplates",
+        "ec2:DescribeSecurityGroups",
+        "ec2:DescribeSpotPriceHistory",
+        "ec2:DescribeSubnets"
+      ],
+      "Condition": {
+        "StringEquals": {
+          "aws:RequestedRegion": "{{ .Region }}"
+        }
+      }
+    },
+    {
+      "Sid": "AllowSSMReadActions",
+      "Effect": "Allow",
+      "Resource": "arn:{{ .AWSPartition }}:ssm:{{ .Region }}::parameter/aws/service/*",
+      "Action": "ssm:GetParameter"
+    },
+    {
+      "Sid": "AllowPricingReadActions",
+      "Effect": "Allow",
+      "Resource": "*",
+      "Action": "pricing:GetProducts"
+    },
+    {{- if .InterruptionQueueARN }}
+    {
+      "Sid": "AllowInterruptionQueueActions",
+      "Effect": "Allow",
+      "Resource": "{{ .InterruptionQueueARN }}",
+      "Action": [
+        "sqs:DeleteMessage",
+        "sqs:GetQueueUrl",
+        "sqs:ReceiveMessage"
+      ]
+    },
+    {{- end }}
+    {
+      "Sid": "AllowPassingInstanceRole",
+      "Effect": "Allow",
+      "Resource": [
+        {{- range $index, $arn := .NodeRoleARNs }}
+        {{ if gt $index 0 }},{{ end }}"{{ $arn }}"
+        {{- end }}
+      ],
+      "Action": "iam:PassRole",
+      "Condition": {
+        "StringEquals": {
+          "iam:PassedToService": "{{ .EC2ServiceDomain }}"
+        }
+      }
+    },
+    {
+      "Sid": "AllowInstanceProfileReadActions",
+      "Effect": "Allow",
+      "Resource": "*",
+      "Action": "iam:GetInstanceProfile"
+    }
+  ]
+}
+`
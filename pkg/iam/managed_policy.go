@@ -0,0 +1,327 @@
+package iam
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsarn "github.com/aws/aws-sdk-go-v2/aws/arn"
+	"github.com/aws/aws-sdk-go-v2/service/iam"
+	iamtypes "github.com/aws/aws-sdk-go-v2/service/iam/types"
+	"github.com/giantswarm/microerror"
+)
+
+// maxPolicyVersions is the maximum number of versions IAM keeps for a customer-managed policy.
+const maxPolicyVersions = 5
+
+// usesManagedPolicy returns true if the permissions of roles of the given type are kept in a customer-managed policy
+// instead of an inline policy.
+func (s *IAMService) usesManagedPolicy(roleType string) bool {
+	return slices.Contains(s.managedPolicyRoleTypes, roleType)
+}
+
+// managedPolicyName returns the name of the customer-managed policy of a role. Unlike inline policies, managed policies
+// live in a global namespace, so the name is derived from the role name which is unique within the account.
+func managedPolicyName(roleName string) string {
+	return fmt.Sprintf("%s-policy", roleName)
+}
+
+// attachManagedPolicy will create or update the customer-managed policy of the role and attach it to the role. An
+// existing inline policy is only removed once the managed policy is attached, so the role never lacks permissions.
+func (s *IAMService) attachManagedPolicy(roleName string, roleType string, params any) error {
+	l := s.log.WithValues("role_name", roleName)
+
+	policyDocument, err := s.generateInlinePolicyDocument(roleType, params)
+	if err != nil {
+		l.Error(err, "failed to generate managed policy document from template for IAM role")
+		return err
+	}
+
+	role, err := s.iamClient.GetRole(context.TODO(), &iam.GetRoleInput{
+		RoleName: aws.String(roleName),
+	})
+	if err != nil {
+		l.Error(err, "failed to fetch IAM role")
+		return err
+	}
+	roleARN, err := awsarn.Parse(aws.ToString(role.Role.Arn))
+	if err != nil {
+		l.Error(err, "failed to parse IAM role ARN")
+		return err
+	}
+	policyARN := fmt.Sprintf("arn:%s:iam::%s:policy/%s", roleARN.Partition, roleARN.AccountID, managedPolicyName(roleName))
+	l = l.WithValues("policy_arn", policyARN)
+
+	err = s.ensureManagedPolicy(policyARN, roleName, policyDocument)
+	if err != nil {
+		return err
+	}
+
+	attached, err := s.iamClient.ListAttachedRolePolicies(context.TODO(), &iam.ListAttachedRolePoliciesInput{
+		RoleName: aws.String(roleName),
+	})
+	if err != nil {
+		l.Error(err, "failed to list attached policies of IAM role")
+		return err
+	}
+	if !slices.ContainsFunc(attached.AttachedPolicies, func(p iamtypes.AttachedPolicy) bool { return aws.ToString(p.PolicyArn) == policyARN }) {
+		_, err = s.iamClient.AttachRolePolicy(context.TODO(), &iam.AttachRolePolicyInput{
+			PolicyArn: aws.String(policyARN),
+			RoleName:  aws.String(roleName),
+		})
+		if err != nil {
+			l.Error(err, "failed to attach managed policy to IAM role")
+			return err
+		}
+		l.Info("successfully attached managed policy to IAM role")
+	}
+
+	// migrate from the inline policy, now that the managed policy grants the same permissions
+	inlinePolicies, err := s.iamClient.ListRolePolicies(context.TODO(), &iam.ListRolePoliciesInput{
+		RoleName: aws.String(roleName),
+	})
+	if err != nil {
+		l.Error(err, "failed to list inline policies of IAM role")
+		return err
+	}
+	// older versions named the inline policy of every role after the main role type of the service
+	for _, inlinePolicyName := range slices.Compact([]string{policyName(roleType, s.clusterName), policyName(s.roleType, s.clusterName)}) {
+		if !slices.Contains(inlinePolicies.PolicyNames, inlinePolicyName) {
+			continue
+		}
+		_, err = s.iamClient.DeleteRolePolicy(context.TODO(), &iam.DeleteRolePolicyInput{
+			PolicyName: aws.String(inlinePolicyName),
+			RoleName:   aws.String(roleName),
+		})
+		if err != nil && !IsNotFound(err) {
+			l.Error(err, "failed to delete inline policy from IAM role")
+			return err
+		}
+		l.Info("migrated inline policy of IAM role to managed policy", "policy_name", inlinePolicyName)
+	}
+
+	return nil
+}
+
+// detachManagedPolicy detaches the operator-owned managed policy from a role whose role type no longer uses one and
+// deletes it. Managed policies of others are left alone.
+func (s *IAMService) detachManagedPolicy(roleName string) error {
+	l := s.log.WithValues("role_name", roleName)
+
+	attached, err := s.iamClient.ListAttachedRolePolicies(context.TODO(), &iam.ListAttachedRolePoliciesInput{
+		RoleName: aws.String(roleName),
+	})
+	if IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		l.Error(err, "failed to list attached policies of IAM role")
+		return err
+	}
+
+	for _, p := range attached.AttachedPolicies {
+		if aws.ToString(p.PolicyName) != managedPolicyName(roleName) {
+			continue
+		}
+		l := l.WithValues("policy_arn", aws.ToString(p.PolicyArn))
+
+		policy, err := s.iamClient.GetPolicy(context.TODO(), &iam.GetPolicyInput{
+			PolicyArn: p.PolicyArn,
+		})
+		if IsNotFound(err) {
+			continue
+		}
+		if err != nil {
+			l.Error(err, "failed to fetch managed policy")
+			return err
+		}
+		if !hasTag(policy.Policy.Tags, IAMControllerOwnedTag) {
+			l.Info("managed policy is not owned by the operator, not detaching it")
+			continue
+		}
+
+		_, err = s.iamClient.DetachRolePolicy(context.TODO(), &iam.DetachRolePolicyInput{
+			PolicyArn: p.PolicyArn,
+			RoleName:  aws.String(roleName),
+		})
+		if err != nil && !IsNotFound(err) {
+			l.Error(err, "failed to detach managed policy from IAM role")
+			return err
+		}
+		l.Info("detached managed policy from IAM role, as its permissions are kept in an inline policy now")
+
+		err = s.deleteManagedPolicy(aws.ToString(p.PolicyArn))
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ensureManagedPolicy creates the managed policy or adds a new default version to it if its document differs from the
+// expected one. A policy of the same name that was not created by the operator is reported as a conflict instead of
+// being changed.
+func (s *IAMService) ensureManagedPolicy(policyARN string, roleName string, policyDocument string) error {
+	l := s.log.WithValues("role_name", roleName, "policy_arn", policyARN)
+
+	policy, err := s.iamClient.GetPolicy(context.TODO(), &iam.GetPolicyInput{
+		PolicyArn: aws.String(policyARN),
+	})
+	if IsNotFound(err) {
+		_, err = s.iamClient.CreatePolicy(context.TODO(), &iam.CreatePolicyInput{
+			PolicyName:     aws.String(managedPolicyName(roleName)),
+			PolicyDocument: aws.String(policyDocument),
+			Tags:           s.desiredTags(),
+		})
+		if err != nil {
+			l.Error(err, "failed to create managed policy")
+			return err
+		}
+		l.Info("successfully created managed policy")
+		return nil
+	}
+	if err != nil {
+		l.Error(err, "failed to fetch managed policy")
+		return err
+	}
+	// policy names are unique per account, so the policy may have been created by someone else
+	if !hasTag(policy.Policy.Tags, IAMControllerOwnedTag) {
+		err = microerror.Maskf(managedPolicyConflictError, "managed policy %q is not owned by the operator", policyARN)
+		l.Error(err, "managed policy exists but was not created by the operator")
+		return err
+	}
+
+	version, err := s.iamClient.GetPolicyVersion(context.TODO(), &iam.GetPolicyVersionInput{
+		PolicyArn: aws.String(policyARN),
+		VersionId: policy.Policy.DefaultVersionId,
+	})
+	if err != nil {
+		l.Error(err, "failed to fetch default version of managed policy")
+		return err
+	}
+
+	isEqual, err := areEqualPolicy(aws.ToString(version.PolicyVersion.Document), policyDocument)
+	if err != nil {
+		l.Error(err, "failed to compare managed policy documents")
+		return err
+	}
+	if isEqual {
+		l.Info("managed policy for IAM role is up to date, skipping")
+		return nil
+	}
+
+	err = s.pruneManagedPolicyVersions(policyARN)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.iamClient.CreatePolicyVersion(context.TODO(), &iam.CreatePolicyVersionInput{
+		PolicyArn:      aws.String(policyARN),
+		PolicyDocument: aws.String(policyDocument),
+		SetAsDefault:   true,
+	})
+	if err != nil {
+		l.Error(err, "failed to create new version of managed policy")
+		return err
+	}
+	l.Info("successfully updated managed policy")
+
+	return nil
+}
+
+// pruneManagedPolicyVersions deletes the oldest non-default version of the policy if there is no room for another
+// version.
+func (s *IAMService) pruneManagedPolicyVersions(policyARN string) error {
+	l := s.log.WithValues("policy_arn", policyARN)
+
+	versions, err := s.iamClient.ListPolicyVersions(context.TODO(), &iam.ListPolicyVersionsInput{
+		PolicyArn: aws.String(policyARN),
+	})
+	if err != nil {
+		l.Error(err, "failed to list versions of managed policy")
+		return err
+	}
+	if len(versions.Versions) < maxPolicyVersions {
+		return nil
+	}
+
+	var oldest *iamtypes.PolicyVersion
+	for i, v := range versions.Versions {
+		if v.IsDefaultVersion {
+			continue
+		}
+		if oldest == nil || (v.CreateDate != nil && oldest.CreateDate != nil && v.CreateDate.Before(*oldest.CreateDate)) {
+			oldest = &versions.Versions[i]
+		}
+	}
+	if oldest == nil {
+		return nil
+	}
+
+	_, err = s.iamClient.DeletePolicyVersion(context.TODO(), &iam.DeletePolicyVersionInput{
+		PolicyArn: aws.String(policyARN),
+		VersionId: oldest.VersionId,
+	})
+	if err != nil {
+		l.Error(err, "failed to delete old version of managed policy")
+		return err
+	}
+	l.Info("deleted old version of managed policy", "version_id", aws.ToString(oldest.VersionId))
+
+	return nil
+}
+
+// deleteManagedPolicy deletes an operator-owned managed policy including all of its versions. It must be detached from
+// all roles before.
+func (s *IAMService) deleteManagedPolicy(policyARN string) error {
+	l := s.log.WithValues("policy_arn", policyARN)
+
+	policy, err := s.iamClient.GetPolicy(context.TODO(), &iam.GetPolicyInput{
+		PolicyArn: aws.String(policyARN),
+	})
+	if IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		l.Error(err, "failed to fetch managed policy")
+		return err
+	}
+	if !hasTag(policy.Policy.Tags, IAMControllerOwnedTag) {
+		l.Info("managed policy is not owned by the operator, not deleting it")
+		return nil
+	}
+
+	versions, err := s.iamClient.ListPolicyVersions(context.TODO(), &iam.ListPolicyVersionsInput{
+		PolicyArn: aws.String(policyARN),
+	})
+	if err != nil {
+		l.Error(err, "failed to list versions of managed policy")
+		return err
+	}
+	for _, v := range versions.Versions {
+		if v.IsDefaultVersion {
+			continue
+		}
+		_, err = s.iamClient.DeletePolicyVersion(context.TODO(), &iam.DeletePolicyVersionInput{
+			PolicyArn: aws.String(policyARN),
+			VersionId: v.VersionId,
+		})
+		if err != nil && !IsNotFound(err) {
+			l.Error(err, "failed to delete version of managed policy")
+			return err
+		}
+	}
+
+	_, err = s.iamClient.DeletePolicy(context.TODO(), &iam.DeletePolicyInput{
+		PolicyArn: aws.String(policyARN),
+	})
+	if err != nil && !IsNotFound(err) {
+		l.Error(err, "failed to delete managed policy")
+		return err
+	}
+	l.Info("deleted managed policy")
+
+	return nil
+}
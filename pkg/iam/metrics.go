@@ -0,0 +1,31 @@
+package iam
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+const metricsNamespace = "capa_iam_operator"
+
+var trustPolicyDriftTotal = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "trust_policy_drift_total",
+		Help:      "Number of times the trust policy of an IAM role was found to differ from the expected one and was restored.",
+	},
+	[]string{"cluster_name", "role_type", "role_name"},
+)
+
+var oidcProviderMissing = prometheus.NewGaugeVec(
+	prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "oidc_provider_missing",
+		Help:      "Whether the IAM OIDC provider of an IRSA trust domain of a cluster is missing (1) or exists (0).",
+	},
+	[]string{"cluster_name", "trust_domain"},
+)
+
+func init() {
+	metrics.Registry.MustRegister(trustPolicyDriftTotal)
+	metrics.Registry.MustRegister(oidcProviderMissing)
+}
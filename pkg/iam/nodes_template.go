@@ -182,7 +182,7 @@ const nodesFullPermissionsTemplate = `{
         "secretsmanager:GetSecretValue",
         "secretsmanager:DeleteSecret"
       ],
-      "Resource": "arn:*:secretsmanager:*:*:secret:aws.cluster.x-k8s.io/*",
+      "Resource": "arn:{{ .AWSPartition }}:secretsmanager:*:*:secret:aws.cluster.x-k8s.io/*",
       "Effect": "Allow"
     }
   ]
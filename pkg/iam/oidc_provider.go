@@ -0,0 +1,235 @@
+package iam
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/iam"
+	iamtypes "github.com/aws/aws-sdk-go-v2/service/iam/types"
+
+	"github.com/giantswarm/capa-iam-operator/v3/pkg/partition"
+)
+
+// maxOIDCProviderThumbprints is the maximum number of thumbprints IAM allows per OIDC provider.
+const maxOIDCProviderThumbprints = 5
+
+// ValidateOIDCProviderThumbprints returns an error if there are too many thumbprints or any of them is not a
+// hex-encoded SHA-1 hash.
+func ValidateOIDCProviderThumbprints(thumbprints []string) error {
+	if len(thumbprints) > maxOIDCProviderThumbprints {
+		return fmt.Errorf("got %d thumbprints, IAM allows at most %d", len(thumbprints), maxOIDCProviderThumbprints)
+	}
+	for _, thumbprint := range thumbprints {
+		if b, err := hex.DecodeString(thumbprint); err != nil || len(b) != 20 {
+			return fmt.Errorf("invalid thumbprint %q, expected 40 hexadecimal characters", thumbprint)
+		}
+	}
+	return nil
+}
+
+// oidcProviderARN returns the ARN of the IAM OIDC provider of an IRSA trust domain, as referenced by the trust
+// policies.
+func (s *IAMService) oidcProviderARN(awsAccountID string, irsaTrustDomain string) string {
+	return fmt.Sprintf("arn:%s:iam::%s:oidc-provider/%s", partition.ForRegion(s.region).Name, awsAccountID, irsaTrustDomain)
+}
+
+// ReconcileOIDCProviders checks that an IAM OIDC provider exists for every IRSA trust domain of the cluster. Missing
+// providers are created if the operator manages them and returned otherwise, so that they can be reported on the
+// cluster. Providers created by the operator are kept up to date; providers created by others are left alone.
+func (s *IAMService) ReconcileOIDCProviders(awsAccountID string, irsaTrustDomains []string) ([]string, error) {
+	s.log.Info("reconciling OIDC providers")
+
+	var missingTrustDomains []string
+	for _, irsaTrustDomain := range irsaTrustDomains {
+		missing, err := s.reconcileOIDCProvider(s.oidcProviderARN(awsAccountID, irsaTrustDomain), irsaTrustDomain)
+		if err != nil {
+			return nil, err
+		}
+		if missing {
+			missingTrustDomains = append(missingTrustDomains, irsaTrustDomain)
+		}
+	}
+
+	s.log.Info("finished reconciling OIDC providers")
+	return missingTrustDomains, nil
+}
+
+// reconcileOIDCProvider returns true if the provider does not exist and the operator does not manage providers.
+func (s *IAMService) reconcileOIDCProvider(providerARN string, irsaTrustDomain string) (bool, error) {
+	l := s.log.WithValues("trust_domain", irsaTrustDomain, "oidc_provider_arn", providerARN)
+	// the client ID is the audience of the service account tokens exchanged for role credentials
+	clientID := partition.ForRegion(s.region).STSAudience
+	missing := oidcProviderMissing.WithLabelValues(s.clusterName, irsaTrustDomain)
+
+	provider, err := s.iamClient.GetOpenIDConnectProvider(context.TODO(), &iam.GetOpenIDConnectProviderInput{
+		OpenIDConnectProviderArn: aws.String(providerARN),
+	})
+	if IsNotFound(err) {
+		if !s.manageOIDCProviders {
+			missing.Set(1)
+			l.Info("OIDC provider of IRSA trust domain does not exist, IRSA roles cannot be assumed through it")
+			return true, nil
+		}
+
+		_, err = s.iamClient.CreateOpenIDConnectProvider(context.TODO(), &iam.CreateOpenIDConnectProviderInput{
+			Url:            aws.String("https://" + irsaTrustDomain),
+			ClientIDList:   []string{clientID},
+			ThumbprintList: s.oidcProviderThumbprints,
+			Tags:           s.desiredTags(),
+		})
+		if err != nil {
+			missing.Set(1)
+			l.Error(err, "failed to create OIDC provider")
+			return false, err
+		}
+		missing.Set(0)
+		l.Info("successfully created OIDC provider")
+		return false, nil
+	}
+	if err != nil && !s.manageOIDCProviders {
+		// reporting missing providers must not keep the roles from being reconciled
+		l.Error(err, "failed to check whether OIDC provider exists")
+		return false, nil
+	}
+	if err != nil {
+		l.Error(err, "failed to fetch OIDC provider")
+		return false, err
+	}
+	missing.Set(0)
+
+	if !s.manageOIDCProviders || !hasTag(provider.Tags, IAMControllerOwnedTag) {
+		return false, nil
+	}
+
+	for _, existing := range provider.ClientIDList {
+		if existing == clientID {
+			continue
+		}
+		_, err = s.iamClient.RemoveClientIDFromOpenIDConnectProvider(context.TODO(), &iam.RemoveClientIDFromOpenIDConnectProviderInput{
+			OpenIDConnectProviderArn: aws.String(providerARN),
+			ClientID:                 aws.String(existing),
+		})
+		if err != nil {
+			l.Error(err, "failed to remove client ID from OIDC provider", "client_id", existing)
+			return false, err
+		}
+		l.Info("removed client ID from OIDC provider", "client_id", existing)
+	}
+	if !slices.Contains(provider.ClientIDList, clientID) {
+		_, err = s.iamClient.AddClientIDToOpenIDConnectProvider(context.TODO(), &iam.AddClientIDToOpenIDConnectProviderInput{
+			OpenIDConnectProviderArn: aws.String(providerARN),
+			ClientID:                 aws.String(clientID),
+		})
+		if err != nil {
+			l.Error(err, "failed to add client ID to OIDC provider", "client_id", clientID)
+			return false, err
+		}
+		l.Info("added client ID to OIDC provider", "client_id", clientID)
+	}
+
+	if len(s.oidcProviderThumbprints) > 0 && !equalThumbprints(provider.ThumbprintList, s.oidcProviderThumbprints) {
+		_, err = s.iamClient.UpdateOpenIDConnectProviderThumbprint(context.TODO(), &iam.UpdateOpenIDConnectProviderThumbprintInput{
+			OpenIDConnectProviderArn: aws.String(providerARN),
+			ThumbprintList:           s.oidcProviderThumbprints,
+		})
+		if err != nil {
+			l.Error(err, "failed to update thumbprints of OIDC provider")
+			return false, err
+		}
+		l.Info("updated thumbprints of OIDC provider")
+	}
+
+	toTag, toUntag := s.diffTags(provider.Tags, s.desiredTags())
+	if len(toTag) > 0 {
+		_, err = s.iamClient.TagOpenIDConnectProvider(context.TODO(), &iam.TagOpenIDConnectProviderInput{
+			OpenIDConnectProviderArn: aws.String(providerARN),
+			Tags:                     toTag,
+		})
+		if err != nil {
+			l.Error(err, "failed to tag OIDC provider")
+			return false, err
+		}
+		l.Info("updated tags of OIDC provider", "tags", tagKeys(toTag))
+	}
+	if len(toUntag) > 0 {
+		_, err = s.iamClient.UntagOpenIDConnectProvider(context.TODO(), &iam.UntagOpenIDConnectProviderInput{
+			OpenIDConnectProviderArn: aws.String(providerARN),
+			TagKeys:                  toUntag,
+		})
+		if err != nil {
+			l.Error(err, "failed to untag OIDC provider")
+			return false, err
+		}
+		l.Info("removed tags from OIDC provider", "tags", toUntag)
+	}
+
+	return false, nil
+}
+
+// DeleteOIDCProviders deletes the OIDC providers the operator created for the cluster. Like the IRSA roles, they are
+// only deleted together with the cluster.
+func (s *IAMService) DeleteOIDCProviders() error {
+	if !s.clusterIsBeingDeleted || !s.manageOIDCProviders {
+		return nil
+	}
+	s.log.Info("deleting OIDC providers")
+	defer oidcProviderMissing.DeletePartialMatch(map[string]string{"cluster_name": s.clusterName})
+
+	// the trust domains of a cluster may have changed since the providers were created, so they are found by their tags
+	o, err := s.iamClient.ListOpenIDConnectProviders(context.TODO(), &iam.ListOpenIDConnectProvidersInput{})
+	if err != nil {
+		s.log.Error(err, "failed to list OIDC providers")
+		return err
+	}
+
+	for _, entry := range o.OpenIDConnectProviderList {
+		l := s.log.WithValues("oidc_provider_arn", aws.ToString(entry.Arn))
+
+		provider, err := s.iamClient.GetOpenIDConnectProvider(context.TODO(), &iam.GetOpenIDConnectProviderInput{
+			OpenIDConnectProviderArn: entry.Arn,
+		})
+		if IsNotFound(err) {
+			continue
+		}
+		if err != nil {
+			l.Error(err, "failed to fetch OIDC provider")
+			return err
+		}
+		if !hasTag(provider.Tags, IAMControllerOwnedTag) || !hasTag(provider.Tags, fmt.Sprintf(ClusterIDTag, s.clusterName)) {
+			continue
+		}
+
+		_, err = s.iamClient.DeleteOpenIDConnectProvider(context.TODO(), &iam.DeleteOpenIDConnectProviderInput{
+			OpenIDConnectProviderArn: entry.Arn,
+		})
+		if err != nil && !IsNotFound(err) {
+			l.Error(err, "failed to delete OIDC provider")
+			return err
+		}
+		l.Info("deleted OIDC provider")
+	}
+
+	s.log.Info("finished deleting OIDC providers")
+	return nil
+}
+
+func hasTag(tags []iamtypes.Tag, key string) bool {
+	return slices.ContainsFunc(tags, func(t iamtypes.Tag) bool { return aws.ToString(t.Key) == key })
+}
+
+// equalThumbprints compares thumbprints regardless of their order and case.
+func equalThumbprints(a, b []string) bool {
+	normalize := func(thumbprints []string) []string {
+		normalized := make([]string, 0, len(thumbprints))
+		for _, thumbprint := range thumbprints {
+			normalized = append(normalized, strings.ToLower(thumbprint))
+		}
+		slices.Sort(normalized)
+		return slices.Compact(normalized)
+	}
+	return slices.Equal(normalize(a), normalize(b))
+}
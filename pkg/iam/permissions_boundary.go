@@ -0,0 +1,94 @@
+package iam
+
+import (
+	"context"
+	"slices"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/iam"
+	iamtypes "github.com/aws/aws-sdk-go-v2/service/iam/types"
+)
+
+// IAMControllerPermissionsBoundaryTag marks roles whose permissions boundary was set by this operator. A boundary is
+// only ever removed from roles carrying this tag, so boundaries attached by others are left alone.
+const IAMControllerPermissionsBoundaryTag = "capi-iam-controller/permissions-boundary"
+
+// reconcilePermissionsBoundary makes sure the permissions boundary of an existing role matches the configured one.
+func (s *IAMService) reconcilePermissionsBoundary(roleName string) error {
+	l := s.log.WithValues("role_name", roleName)
+
+	role, err := s.iamClient.GetRole(context.TODO(), &iam.GetRoleInput{
+		RoleName: aws.String(roleName),
+	})
+	if IsNotFound(err) {
+		l.Info("role doesn't exist. Skipping reconciliation of permissions boundary")
+		return nil
+	}
+	if err != nil {
+		l.Error(err, "failed to fetch IAM role")
+		return err
+	}
+
+	actual := ""
+	if role.Role.PermissionsBoundary != nil {
+		actual = aws.ToString(role.Role.PermissionsBoundary.PermissionsBoundaryArn)
+	}
+
+	if s.permissionsBoundary != "" {
+		if actual == s.permissionsBoundary {
+			return nil
+		}
+		_, err = s.iamClient.PutRolePermissionsBoundary(context.TODO(), &iam.PutRolePermissionsBoundaryInput{
+			RoleName:            aws.String(roleName),
+			PermissionsBoundary: aws.String(s.permissionsBoundary),
+		})
+		if err != nil {
+			l.Error(err, "failed to set permissions boundary of IAM role", "permissions_boundary", s.permissionsBoundary)
+			return err
+		}
+		l.Info("set permissions boundary of IAM role", "permissions_boundary", s.permissionsBoundary)
+		return nil
+	}
+
+	if !hasPermissionsBoundaryTag(role.Role.Tags) {
+		return nil
+	}
+
+	if actual != "" {
+		err = s.deletePermissionsBoundary(roleName)
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err = s.iamClient.UntagRole(context.TODO(), &iam.UntagRoleInput{
+		RoleName: aws.String(roleName),
+		TagKeys:  []string{IAMControllerPermissionsBoundaryTag},
+	})
+	if err != nil {
+		l.Error(err, "failed to untag IAM role")
+		return err
+	}
+
+	return nil
+}
+
+// deletePermissionsBoundary removes the permissions boundary from the role.
+func (s *IAMService) deletePermissionsBoundary(roleName string) error {
+	l := s.log.WithValues("role_name", roleName)
+
+	_, err := s.iamClient.DeleteRolePermissionsBoundary(context.TODO(), &iam.DeleteRolePermissionsBoundaryInput{
+		RoleName: aws.String(roleName),
+	})
+	if err != nil && !IsNotFound(err) {
+		l.Error(err, "failed to delete permissions boundary of IAM role")
+		return err
+	}
+	l.Info("deleted permissions boundary of IAM role")
+
+	return nil
+}
+
+func hasPermissionsBoundaryTag(tags []iamtypes.Tag) bool {
+	return slices.ContainsFunc(tags, func(t iamtypes.Tag) bool { return aws.ToString(t.Key) == IAMControllerPermissionsBoundaryTag })
+}
@@ -0,0 +1,218 @@
+package iam
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/eks"
+	ekstypes "github.com/aws/aws-sdk-go-v2/service/eks/types"
+	"github.com/aws/aws-sdk-go-v2/service/iam"
+	"github.com/giantswarm/microerror"
+)
+
+// podIdentityAssociation is an association of a service account with a role that the operator should create.
+type podIdentityAssociation struct {
+	ServiceAccount
+	RoleARN string
+}
+
+// ParseServiceAccountTrust parses a comma-separated list of the ways service accounts assume roles, e.g.
+// "irsa,pod-identity".
+func ParseServiceAccountTrust(value string) ([]TrustKind, error) {
+	kinds := []TrustKind{}
+	for _, item := range strings.Split(value, ",") {
+		kind := TrustKind(strings.TrimSpace(item))
+		if kind != "" && !slices.Contains(kinds, kind) {
+			kinds = append(kinds, kind)
+		}
+	}
+	if err := ValidateServiceAccountTrust(kinds); err != nil {
+		return nil, err
+	}
+	return kinds, nil
+}
+
+// ValidateServiceAccountTrust returns an error if the list is empty but not nil or contains anything but TrustKindIRSA
+// and TrustKindPodIdentity.
+func ValidateServiceAccountTrust(kinds []TrustKind) error {
+	if kinds != nil && len(kinds) == 0 {
+		return fmt.Errorf("expected %q, %q or both", TrustKindIRSA, TrustKindPodIdentity)
+	}
+	for _, kind := range kinds {
+		if kind != TrustKindIRSA && kind != TrustKindPodIdentity {
+			return fmt.Errorf("unknown service account trust %q, expected %q or %q", kind, TrustKindIRSA, TrustKindPodIdentity)
+		}
+	}
+	return nil
+}
+
+// usesServiceAccountTrust returns true if service accounts assume the IRSA roles of the cluster in the given way.
+func (s *IAMService) usesServiceAccountTrust(kind TrustKind) bool {
+	if s.serviceAccountTrust == nil {
+		return kind == TrustKindIRSA
+	}
+	return slices.Contains(s.serviceAccountTrust, kind)
+}
+
+// desiredPodIdentityAssociations returns the associations of the role with its service accounts. Service accounts with
+// wildcards are skipped, as Pod Identity associations need the exact namespace and name. They are only allowed if the
+// role is assumed through IRSA as well.
+func (s *IAMService) desiredPodIdentityAssociations(roleName string, serviceAccounts []ServiceAccount) ([]podIdentityAssociation, error) {
+	l := s.log.WithValues("role_name", roleName)
+
+	role, err := s.iamClient.GetRole(context.TODO(), &iam.GetRoleInput{
+		RoleName: aws.String(roleName),
+	})
+	if IsNotFound(err) {
+		l.Info("IAM role does not exist, not associating it with service accounts")
+		return nil, nil
+	}
+	if err != nil {
+		l.Error(err, "failed to fetch IAM role")
+		return nil, err
+	}
+
+	var associations []podIdentityAssociation
+	for _, sa := range serviceAccounts {
+		if strings.Contains(sa.String(), "*") {
+			l.Info("Pod Identity does not support wildcards, not associating service account with IAM role", "service_account", sa.String())
+			continue
+		}
+		associations = append(associations, podIdentityAssociation{ServiceAccount: sa, RoleARN: aws.ToString(role.Role.Arn)})
+	}
+
+	return associations, nil
+}
+
+// reconcilePodIdentityAssociations creates and updates the desired Pod Identity associations of the EKS cluster and
+// deletes the operator-owned associations that are no longer desired. An association of a service account that was
+// not created by the operator is reported as a conflict instead of being changed.
+func (s *IAMService) reconcilePodIdentityAssociations(desired []podIdentityAssociation) error {
+	l := s.log.WithValues("eks_cluster_name", s.eksClusterName)
+
+	existing, err := s.listPodIdentityAssociations()
+	if err != nil {
+		l.Error(err, "failed to list Pod Identity associations")
+		return err
+	}
+
+	for _, d := range desired {
+		al := l.WithValues("service_account", d.String(), "role_arn", d.RoleARN)
+
+		i := slices.IndexFunc(existing, func(a ekstypes.PodIdentityAssociation) bool { return associatesServiceAccount(a, d.ServiceAccount) })
+		if i < 0 {
+			_, err = s.eksClient.CreatePodIdentityAssociation(context.TODO(), &eks.CreatePodIdentityAssociationInput{
+				ClusterName:    aws.String(s.eksClusterName),
+				Namespace:      aws.String(d.Namespace),
+				ServiceAccount: aws.String(d.Name),
+				RoleArn:        aws.String(d.RoleARN),
+				Tags:           s.podIdentityAssociationTags(),
+			})
+			if err != nil {
+				al.Error(err, "failed to create Pod Identity association")
+				return err
+			}
+			al.Info("successfully created Pod Identity association")
+			continue
+		}
+
+		a := existing[i]
+		if aws.ToString(a.RoleArn) == d.RoleARN {
+			continue
+		}
+		if !isOwnedPodIdentityAssociation(a) {
+			err = microerror.Maskf(podIdentityAssociationConflictError, "service account %q is associated with role %q instead of %q", d.String(), aws.ToString(a.RoleArn), d.RoleARN)
+			al.Error(err, "Pod Identity association is not owned by the operator")
+			return err
+		}
+
+		_, err = s.eksClient.UpdatePodIdentityAssociation(context.TODO(), &eks.UpdatePodIdentityAssociationInput{
+			ClusterName:   aws.String(s.eksClusterName),
+			AssociationId: a.AssociationId,
+			RoleArn:       aws.String(d.RoleARN),
+		})
+		if err != nil {
+			al.Error(err, "failed to update Pod Identity association")
+			return err
+		}
+		al.Info("successfully updated Pod Identity association")
+	}
+
+	for _, a := range existing {
+		if !isOwnedPodIdentityAssociation(a) || slices.ContainsFunc(desired, func(d podIdentityAssociation) bool { return associatesServiceAccount(a, d.ServiceAccount) }) {
+			continue
+		}
+		al := l.WithValues("association_id", aws.ToString(a.AssociationId))
+
+		_, err = s.eksClient.DeletePodIdentityAssociation(context.TODO(), &eks.DeletePodIdentityAssociationInput{
+			ClusterName:   aws.String(s.eksClusterName),
+			AssociationId: a.AssociationId,
+		})
+		if err != nil && !isEKSNotFound(err) {
+			al.Error(err, "failed to delete Pod Identity association")
+			return err
+		}
+		al.Info("deleted Pod Identity association")
+	}
+
+	return nil
+}
+
+// listPodIdentityAssociations returns all Pod Identity associations of the EKS cluster, or none if the EKS cluster does
+// not exist. The list only holds summaries, so every association is described to learn its role and tags.
+func (s *IAMService) listPodIdentityAssociations() ([]ekstypes.PodIdentityAssociation, error) {
+	var associations []ekstypes.PodIdentityAssociation
+
+	paginator := eks.NewListPodIdentityAssociationsPaginator(s.eksClient, &eks.ListPodIdentityAssociationsInput{
+		ClusterName: aws.String(s.eksClusterName),
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(context.TODO())
+		if isEKSNotFound(err) {
+			// the EKS cluster is gone, and its associations with it
+			return nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		for _, summary := range page.Associations {
+			o, err := s.eksClient.DescribePodIdentityAssociation(context.TODO(), &eks.DescribePodIdentityAssociationInput{
+				ClusterName:   aws.String(s.eksClusterName),
+				AssociationId: summary.AssociationId,
+			})
+			if isEKSNotFound(err) {
+				continue
+			}
+			if err != nil {
+				return nil, err
+			}
+			if o.Association == nil {
+				return nil, errors.New("described Pod Identity association is empty")
+			}
+			associations = append(associations, *o.Association)
+		}
+	}
+
+	return associations, nil
+}
+
+// podIdentityAssociationTags returns the tags marking Pod Identity associations as created by the operator.
+func (s *IAMService) podIdentityAssociationTags() map[string]string {
+	return map[string]string{
+		IAMControllerOwnedTag:                    "",
+		fmt.Sprintf(ClusterIDTag, s.clusterName): "owned",
+	}
+}
+
+func isOwnedPodIdentityAssociation(a ekstypes.PodIdentityAssociation) bool {
+	_, ok := a.Tags[IAMControllerOwnedTag]
+	return ok
+}
+
+func associatesServiceAccount(a ekstypes.PodIdentityAssociation, sa ServiceAccount) bool {
+	return aws.ToString(a.Namespace) == sa.Namespace && aws.ToString(a.ServiceAccount) == sa.Name
+}
@@ -0,0 +1,28 @@
+package iam
+
+// trustIdentityPolicyPodIdentity allows EKS Pod Identity to assume the role on behalf of pods of the EKS cluster.
+const trustIdentityPolicyPodIdentity = `{
+  "Version": "2012-10-17",
+  "Statement": [
+    {
+      "Sid": "AllowEKSPodIdentity",
+      "Effect": "Allow",
+      "Principal": {
+        "Service": "pods.eks.amazonaws.com"
+      },
+      "Action": [
+        "sts:AssumeRole",
+        "sts:TagSession"
+      ],
+      "Condition": {
+        "StringEquals": {
+          "aws:SourceAccount": "{{ .AccountID }}"
+        },
+        "ArnEquals": {
+          "aws:SourceArn": "arn:{{ .AWSPartition }}:eks:{{ .Region }}:{{ .AccountID }}:cluster/{{ .EKSClusterName }}"
+        }
+      }
+    }
+  ]
+}
+`
@@ -0,0 +1,181 @@
+package iam
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// normalizePolicy parses a policy document into a canonical form, so that documents IAM treats as identical compare as
+// equal:
+//
+//   - Statement, Action, Resource, principal and condition values may be a single string or a list.
+//   - Actions are case-insensitive.
+//   - Actions, resources, principals and condition values are sets, as are the statements themselves.
+//   - Condition keys are case-insensitive and condition values may be given as strings, numbers or booleans.
+func normalizePolicy(document string) (map[string]any, error) {
+	var policy map[string]any
+	err := json.Unmarshal([]byte(document), &policy)
+	if err != nil {
+		return nil, err
+	}
+
+	statement, ok := policy["Statement"]
+	if !ok {
+		return policy, nil
+	}
+
+	var statements []any
+	switch s := statement.(type) {
+	case []any:
+		statements = s
+	case map[string]any:
+		statements = []any{s}
+	default:
+		return nil, fmt.Errorf("invalid Statement of type %T", statement)
+	}
+
+	normalized := make([]any, 0, len(statements))
+	keys := map[string]bool{}
+	for _, s := range statements {
+		m, ok := s.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("invalid statement of type %T", s)
+		}
+		n, err := normalizeStatement(m)
+		if err != nil {
+			return nil, err
+		}
+
+		// identical statements are redundant
+		key, err := json.Marshal(n)
+		if err != nil {
+			return nil, err
+		}
+		if keys[string(key)] {
+			continue
+		}
+		keys[string(key)] = true
+		normalized = append(normalized, n)
+	}
+
+	sort.Slice(normalized, func(i, j int) bool {
+		a, _ := json.Marshal(normalized[i])
+		b, _ := json.Marshal(normalized[j])
+		return string(a) < string(b)
+	})
+	policy["Statement"] = normalized
+
+	return policy, nil
+}
+
+func normalizeStatement(statement map[string]any) (map[string]any, error) {
+	normalized := map[string]any{}
+	for k, v := range statement {
+		var err error
+		switch k {
+		case "Action", "NotAction":
+			var actions []string
+			actions, err = stringSet(v)
+			for i := range actions {
+				actions[i] = strings.ToLower(actions[i])
+			}
+			normalized[k] = compactSet(actions)
+		case "Resource", "NotResource":
+			normalized[k], err = stringSet(v)
+		case "Principal", "NotPrincipal":
+			normalized[k], err = normalizePrincipal(v)
+		case "Condition":
+			normalized[k], err = normalizeCondition(v)
+		default:
+			normalized[k] = v
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s: %w", k, err)
+		}
+	}
+	return normalized, nil
+}
+
+func normalizePrincipal(v any) (any, error) {
+	// "Principal": "*" is not the same as "Principal": {"AWS": "*"}, so the wildcard is kept as is.
+	if s, ok := v.(string); ok {
+		return s, nil
+	}
+
+	m, ok := v.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("unexpected type %T", v)
+	}
+
+	normalized := map[string]any{}
+	for principalType, principals := range m {
+		set, err := stringSet(principals)
+		if err != nil {
+			return nil, err
+		}
+		normalized[principalType] = set
+	}
+	return normalized, nil
+}
+
+func normalizeCondition(v any) (any, error) {
+	m, ok := v.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("unexpected type %T", v)
+	}
+
+	normalized := map[string]any{}
+	for operator, block := range m {
+		keys, ok := block.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("unexpected type %T for condition operator %s", block, operator)
+		}
+
+		normalizedKeys := map[string]any{}
+		for conditionKey, values := range keys {
+			set, err := stringSet(values)
+			if err != nil {
+				return nil, err
+			}
+			conditionKey = strings.ToLower(conditionKey)
+			if existing, ok := normalizedKeys[conditionKey].([]string); ok {
+				set = compactSet(append(existing, set...))
+			}
+			normalizedKeys[conditionKey] = set
+		}
+		normalized[operator] = normalizedKeys
+	}
+	return normalized, nil
+}
+
+// stringSet converts a single value or a list of values to a sorted list of unique strings.
+func stringSet(v any) ([]string, error) {
+	var values []any
+	switch t := v.(type) {
+	case []any:
+		values = t
+	default:
+		values = []any{t}
+	}
+
+	set := make([]string, 0, len(values))
+	for _, value := range values {
+		switch t := value.(type) {
+		case string:
+			set = append(set, t)
+		case bool, float64:
+			set = append(set, fmt.Sprint(t))
+		default:
+			return nil, fmt.Errorf("unexpected value of type %T", value)
+		}
+	}
+	return compactSet(set), nil
+}
+
+func compactSet(set []string) []string {
+	slices.Sort(set)
+	return slices.Compact(set)
+}
@@ -0,0 +1,192 @@
+package iam
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/giantswarm/microerror"
+)
+
+const (
+	// InlinePolicyTemplateKeySuffix and TrustPolicyTemplateKeySuffix are appended to the role type to form the keys of
+	// a policy templates ConfigMap, e.g. "nodes.inline-policy".
+	InlinePolicyTemplateKeySuffix = ".inline-policy"
+	TrustPolicyTemplateKeySuffix  = ".trust-policy"
+)
+
+// PolicyTemplates holds policy templates by role type that replace the built-in ones. The templates are rendered with
+// the same parameters as the built-in templates.
+type PolicyTemplates struct {
+	Inline map[string]string
+	Trust  map[string]string
+}
+
+// ParsePolicyTemplates reads policy templates from the data of a ConfigMap. Every key must consist of a known role type
+// and one of the suffixes InlinePolicyTemplateKeySuffix or TrustPolicyTemplateKeySuffix, and every value must be a
+// valid template.
+func ParsePolicyTemplates(data map[string]string) (PolicyTemplates, error) {
+	templates := PolicyTemplates{
+		Inline: map[string]string{},
+		Trust:  map[string]string{},
+	}
+
+	keys := make([]string, 0, len(data))
+	for k := range data {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		var roleType string
+		var target map[string]string
+		switch {
+		case strings.HasSuffix(k, InlinePolicyTemplateKeySuffix):
+			roleType = strings.TrimSuffix(k, InlinePolicyTemplateKeySuffix)
+			target = templates.Inline
+		case strings.HasSuffix(k, TrustPolicyTemplateKeySuffix):
+			roleType = strings.TrimSuffix(k, TrustPolicyTemplateKeySuffix)
+			target = templates.Trust
+		default:
+			return PolicyTemplates{}, microerror.Maskf(invalidPolicyTemplateError, "key %q must end with %q or %q", k, InlinePolicyTemplateKeySuffix, TrustPolicyTemplateKeySuffix)
+		}
+
+		if getTrustPolicyTemplate(roleType) == "" {
+			return PolicyTemplates{}, microerror.Maskf(invalidPolicyTemplateError, "key %q refers to unknown role type %q", k, roleType)
+		}
+		if _, err := template.New(k).Parse(data[k]); err != nil {
+			return PolicyTemplates{}, microerror.Maskf(invalidPolicyTemplateError, "key %q: %s", k, err)
+		}
+
+		target[roleType] = data[k]
+	}
+
+	return templates, nil
+}
+
+// Merge returns the templates with the given overrides applied on top.
+func (t PolicyTemplates) Merge(overrides PolicyTemplates) PolicyTemplates {
+	merged := PolicyTemplates{
+		Inline: map[string]string{},
+		Trust:  map[string]string{},
+	}
+	for _, m := range []PolicyTemplates{t, overrides} {
+		for k, v := range m.Inline {
+			merged.Inline[k] = v
+		}
+		for k, v := range m.Trust {
+			merged.Trust[k] = v
+		}
+	}
+	return merged
+}
+
+// generateInlinePolicyDocument renders the inline policy of the given role type, preferring an override template over
+// the built-in one, and adds the additional statements configured for the cluster.
+func (s *IAMService) generateInlinePolicyDocument(roleType string, params any) (string, error) {
+	var policyDocument string
+	var err error
+	if tmpl, ok := s.policyTemplates.Inline[roleType]; ok {
+		policyDocument, err = generateOverridePolicyDocument(roleType+InlinePolicyTemplateKeySuffix, tmpl, params)
+	} else {
+		policyDocument, err = generatePolicyDocument(getInlinePolicyTemplate(roleType, s.objectLabels), params)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	statements := s.additionalStatements[roleType]
+	if len(statements) == 0 {
+		return policyDocument, nil
+	}
+
+	maxSize := maxInlinePolicySize
+	if s.usesManagedPolicy(roleType) {
+		maxSize = maxManagedPolicySize
+	}
+	return mergeStatements(policyDocument, statements, maxSize)
+}
+
+// generateTrustPolicyDocument renders the trust policy of the given role type, preferring an override template over the
+// built-in one. For IRSA roles of clusters using EKS Pod Identity, the Pod Identity statement is added to the IRSA trust
+// policy or replaces it if IRSA is not used.
+func (s *IAMService) generateTrustPolicyDocument(roleType string, params any) (string, error) {
+	if definition, _ := GetRoleDefinition(roleType); definition.TrustKind != TrustKindIRSA || !s.usesServiceAccountTrust(TrustKindPodIdentity) {
+		return s.generateRoleTrustPolicyDocument(roleType, params)
+	}
+
+	podIdentityPolicyDocument, err := generatePolicyDocument(trustIdentityPolicyPodIdentity, params)
+	if err != nil {
+		return "", err
+	}
+	if !s.usesServiceAccountTrust(TrustKindIRSA) {
+		return podIdentityPolicyDocument, nil
+	}
+
+	var podIdentityPolicy struct {
+		Statement []map[string]any
+	}
+	err = json.Unmarshal([]byte(podIdentityPolicyDocument), &podIdentityPolicy)
+	if err != nil {
+		return "", err
+	}
+	policyDocument, err := s.generateRoleTrustPolicyDocument(roleType, params)
+	if err != nil {
+		return "", err
+	}
+	return mergeStatements(policyDocument, podIdentityPolicy.Statement, maxTrustPolicySize)
+}
+
+// generateRoleTrustPolicyDocument renders the trust policy template of the given role type.
+func (s *IAMService) generateRoleTrustPolicyDocument(roleType string, params any) (string, error) {
+	if tmpl, ok := s.policyTemplates.Trust[roleType]; ok {
+		return generateOverridePolicyDocument(roleType+TrustPolicyTemplateKeySuffix, tmpl, params)
+	}
+	return generatePolicyDocument(getTrustPolicyTemplate(roleType), params)
+}
+
+func generateOverridePolicyDocument(key string, tmpl string, params any) (string, error) {
+	policyDocument, err := generatePolicyDocument(tmpl, params)
+	if err != nil {
+		return "", microerror.Maskf(invalidPolicyTemplateError, "template %q: %s", key, err)
+	}
+
+	err = validatePolicyDocument(policyDocument)
+	if err != nil {
+		return "", microerror.Maskf(invalidPolicyTemplateError, "template %q: %s", key, err)
+	}
+
+	return policyDocument, nil
+}
+
+// validatePolicyDocument checks the basic structure of a rendered policy document, so that a broken override is
+// reported instead of being sent to IAM.
+func validatePolicyDocument(policyDocument string) error {
+	var policy struct {
+		Version   string          `json:"Version"`
+		Statement json.RawMessage `json:"Statement"`
+	}
+	err := json.Unmarshal([]byte(policyDocument), &policy)
+	if err != nil {
+		return fmt.Errorf("rendered policy is not valid JSON: %w", err)
+	}
+	if policy.Version != "2012-10-17" {
+		return fmt.Errorf("rendered policy must have Version \"2012-10-17\", got %q", policy.Version)
+	}
+
+	var statements []json.RawMessage
+	if err = json.Unmarshal(policy.Statement, &statements); err != nil {
+		var statement map[string]any
+		if err = json.Unmarshal(policy.Statement, &statement); err != nil {
+			return fmt.Errorf("rendered policy must have a Statement object or list")
+		}
+		statements = []json.RawMessage{policy.Statement}
+	}
+	if len(statements) == 0 {
+		return fmt.Errorf("rendered policy must have at least one statement")
+	}
+
+	return nil
+}
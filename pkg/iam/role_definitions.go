@@ -0,0 +1,323 @@
+package iam
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/Masterminds/semver/v3"
+)
+
+// TrustKind is the kind of principal that assumes a role.
+type TrustKind string
+
+const (
+	// TrustKindEC2 roles are assumed by EC2 instances through the instance profile of the role.
+	TrustKindEC2 TrustKind = "ec2"
+	// TrustKindIRSA roles are assumed by Kubernetes service accounts through the OIDC provider of the cluster.
+	TrustKindIRSA TrustKind = "irsa"
+	// TrustKindPodIdentity roles are assumed by Kubernetes service accounts through EKS Pod Identity.
+	TrustKindPodIdentity TrustKind = "pod-identity"
+)
+
+// ServiceAccount identifies a Kubernetes service account that may assume a role.
+type ServiceAccount struct {
+	Namespace string
+	Name      string
+}
+
+// String returns the service account in the "<namespace>:<name>" form.
+func (sa ServiceAccount) String() string {
+	return sa.Namespace + ":" + sa.Name
+}
+
+// ParseServiceAccounts parses a comma-separated list of service accounts in the "<namespace>:<name>" form.
+func ParseServiceAccounts(value string) ([]ServiceAccount, error) {
+	var serviceAccounts []ServiceAccount
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		namespace, name, ok := strings.Cut(item, ":")
+		sa := ServiceAccount{Namespace: namespace, Name: name}
+		if !ok || sa.Validate() != nil {
+			return nil, fmt.Errorf("invalid service account %q, expected <namespace>:<name>", item)
+		}
+		serviceAccounts = append(serviceAccounts, sa)
+	}
+	if len(serviceAccounts) == 0 {
+		return nil, fmt.Errorf("no service accounts in %q", value)
+	}
+	return serviceAccounts, nil
+}
+
+// Validate returns an error if the namespace or the name of the service account is empty or contains characters
+// that would break the trust policy.
+func (sa ServiceAccount) Validate() error {
+	for _, v := range []string{sa.Namespace, sa.Name} {
+		if v == "" || strings.ContainsAny(v, ":,\"\\ \t\n") {
+			return fmt.Errorf("invalid service account %q", sa.String())
+		}
+	}
+	return nil
+}
+
+// RoleDefinition declares a role type managed by the operator. Adding a role type only requires adding a definition to
+// the registry, its templates and, for main roles, a controller creating an IAMService for it.
+type RoleDefinition struct {
+	// Type is the role type. It is used in policy names, finalizers and as key in the configuration of the operator.
+	Type string
+	// NamePattern is the format of the role name, taking the cluster name as only argument. It is not set for main
+	// roles, which are named after the instance profile of the reconciled object.
+	NamePattern string
+	// Description names the users of the role in its default description.
+	Description string
+	// TrustKind is the kind of principal that assumes the role.
+	TrustKind TrustKind
+	// TrustPolicyTemplate is the built-in template of the trust policy.
+	TrustPolicyTemplate string
+	// PolicyTemplate is the built-in template of the permissions policy.
+	PolicyTemplate string
+	// SelectPolicyTemplate, if set, picks the built-in template of the permissions policy based on the labels of the
+	// reconciled object instead of PolicyTemplate.
+	SelectPolicyTemplate func(objectLabels map[string]string) string
+	// ServiceAccounts are the service accounts that may assume IRSA and pod identity roles unless overridden for the
+	// cluster.
+	ServiceAccounts []ServiceAccount
+	// Main is true for role types an IAMService can be created for. All other roles are reconciled along with the
+	// main role of a cluster.
+	Main bool
+	// FeatureGate, if set, is the feature that must be enabled in IAMServiceConfig.FeatureGates for the role to be
+	// reconciled.
+	FeatureGate string
+	// CrossplaneRelease, if set, is the first release in which the role is managed by Crossplane. The operator neither
+	// reconciles nor deletes the role for clusters using this release or a newer one.
+	CrossplaneRelease *semver.Version
+}
+
+// roleDefinitions is the registry of all role types. Roles that are reconciled along with a main role are reconciled
+// in the order of this list.
+var roleDefinitions = []RoleDefinition{
+	{
+		Type:                BastionRole,
+		Description:         "Bastion hosts",
+		TrustKind:           TrustKindEC2,
+		TrustPolicyTemplate: ec2TrustIdentityPolicyTemplate,
+		PolicyTemplate:      bastionPolicyTemplate,
+		Main:                true,
+	},
+	{
+		Type:                ControlPlaneRole,
+		Description:         "Control plane nodes",
+		TrustKind:           TrustKindEC2,
+		TrustPolicyTemplate: ec2TrustIdentityPolicyTemplate,
+		PolicyTemplate:      controlPlanePolicyTemplate,
+		Main:                true,
+		CrossplaneRelease:   GiantSwarmReleaseCrossplaneNodesIAMRoles,
+	},
+	{
+		Type:                NodesRole,
+		Description:         "Worker nodes",
+		TrustKind:           TrustKindEC2,
+		TrustPolicyTemplate: ec2TrustIdentityPolicyTemplate,
+		PolicyTemplate:      nodesFullPermissionsTemplate,
+		SelectPolicyTemplate: func(objectLabels map[string]string) string {
+			if objectLabels[AWSReducedInstanceProfileIAMPermissionsForWorkersLabel] == "true" {
+				// Reduce permissions to zero. All applications on worker nodes that want to reach the AWS API must use
+				// IRSA for credentials and must not fall back to the EC2 instance's IAM instance profile.
+				return nodesReducedPermissionsTemplate
+			}
+			// Previous default
+			return nodesFullPermissionsTemplate
+		},
+		Main:              true,
+		CrossplaneRelease: GiantSwarmReleaseCrossplaneNodesIAMRoles,
+	},
+	{
+		Type:                IRSARole,
+		Description:         "external-dns",
+		TrustKind:           TrustKindIRSA,
+		TrustPolicyTemplate: trustIdentityPolicyIRSA,
+		PolicyTemplate:      route53RolePolicyTemplate,
+		ServiceAccounts:     []ServiceAccount{{Namespace: "kube-system", Name: "external-dns"}},
+		Main:                true,
+	},
+	{
+		Type:                Route53Role,
+		NamePattern:         "%s-Route53Manager-Role",
+		Description:         "external-dns",
+		TrustKind:           TrustKindIRSA,
+		TrustPolicyTemplate: trustIdentityPolicyIRSAWildcard,
+		PolicyTemplate:      route53RolePolicyTemplate,
+		ServiceAccounts:     []ServiceAccount{{Namespace: "*", Name: "*external-dns*"}},
+	},
+	{
+		Type:                CertManagerRole,
+		NamePattern:         "%s-CertManager-Role",
+		Description:         "cert-manager",
+		TrustKind:           TrustKindIRSA,
+		TrustPolicyTemplate: trustIdentityPolicyIRSA,
+		PolicyTemplate:      route53RolePolicyTemplateForCertManager,
+		ServiceAccounts:     []ServiceAccount{{Namespace: "kube-system", Name: "cert-manager-app"}},
+	},
+	{
+		Type:                ALBConrollerRole,
+		NamePattern:         "%s-ALBController-Role",
+		Description:         "AWS Load Balancer Controller",
+		TrustKind:           TrustKindIRSA,
+		TrustPolicyTemplate: trustIdentityPolicyIRSAWildcard,
+		PolicyTemplate:      ALBControllerPolicyTemplate,
+		ServiceAccounts:     []ServiceAccount{{Namespace: "*", Name: "aws-load-balancer-controller"}},
+	},
+	{
+		Type:                EBSCSIDriverRole,
+		NamePattern:         "%s-ebs-csi-driver-role",
+		Description:         "EBS CSI driver",
+		TrustKind:           TrustKindIRSA,
+		TrustPolicyTemplate: trustIdentityPolicyIRSA,
+		PolicyTemplate:      EBSCSIDriverPolicyTemplate,
+		ServiceAccounts:     []ServiceAccount{{Namespace: "kube-system", Name: "ebs-csi-controller-sa"}},
+	},
+	{
+		Type:                EFSCSIDriverRole,
+		NamePattern:         "%s-efs-csi-driver-role",
+		Description:         "EFS CSI driver",
+		TrustKind:           TrustKindIRSA,
+		TrustPolicyTemplate: trustIdentityPolicyIRSA,
+		PolicyTemplate:      EFSCSIDriverPolicyTemplate,
+		ServiceAccounts:     []ServiceAccount{{Namespace: "kube-system", Name: "efs-csi-sa"}},
+	},
+	{
+		Type:                ClusterAutoscalerRole,
+		NamePattern:         "%s-cluster-autoscaler-role",
+		Description:         "Cluster Autoscaler",
+		TrustKind:           TrustKindIRSA,
+		TrustPolicyTemplate: trustIdentityPolicyIRSA,
+		PolicyTemplate:      clusterAutoscalerPolicyTemplate,
+		ServiceAccounts:     []ServiceAccount{{Namespace: "kube-system", Name: "cluster-autoscaler"}},
+	},
+	{
+		Type:                KarpenterControllerRole,
+		NamePattern:         "%s-karpenter-controller-role",
+		Description:         "Karpenter controller",
+		TrustKind:           TrustKindIRSA,
+		TrustPolicyTemplate: trustIdentityPolicyIRSA,
+		PolicyTemplate:      karpenterControllerPolicyTemplate,
+		ServiceAccounts:     []ServiceAccount{{Namespace: "kube-system", Name: "karpenter"}},
+		FeatureGate:         FeatureGateKarpenter,
+	},
+}
+
+// RoleDefinitions returns the definitions of all role types.
+func RoleDefinitions() []RoleDefinition {
+	return slices.Clone(roleDefinitions)
+}
+
+// GetRoleDefinition returns the definition of the given role type.
+func GetRoleDefinition(roleType string) (RoleDefinition, bool) {
+	i := slices.IndexFunc(roleDefinitions, func(d RoleDefinition) bool { return d.Type == roleType })
+	if i < 0 {
+		return RoleDefinition{}, false
+	}
+	return roleDefinitions[i], true
+}
+
+// RoleName returns the name of the role for the given cluster.
+func (d RoleDefinition) RoleName(clusterName string) string {
+	return fmt.Sprintf(d.NamePattern, clusterName)
+}
+
+// policyTemplate returns the built-in template of the permissions policy for an object with the given labels.
+func (d RoleDefinition) policyTemplate(objectLabels map[string]string) string {
+	if d.SelectPolicyTemplate != nil {
+		return d.SelectPolicyTemplate(objectLabels)
+	}
+	return d.PolicyTemplate
+}
+
+// managedByCrossplane returns true if the role is managed by Crossplane in the given release.
+func (d RoleDefinition) managedByCrossplane(release *semver.Version) bool {
+	return d.CrossplaneRelease != nil && release.GreaterThanEqual(d.CrossplaneRelease)
+}
+
+// irsaRoleDefinitions returns the definitions of the IRSA roles reconciled along with the main role of a cluster.
+func irsaRoleDefinitions() []RoleDefinition {
+	var definitions []RoleDefinition
+	for _, d := range roleDefinitions {
+		if d.TrustKind == TrustKindIRSA && !d.Main {
+			definitions = append(definitions, d)
+		}
+	}
+	return definitions
+}
+
+// IRSARoleTypes returns the types of all IRSA roles reconciled along with the main role of a cluster.
+func IRSARoleTypes() []string {
+	var roleTypes []string
+	for _, d := range irsaRoleDefinitions() {
+		roleTypes = append(roleTypes, d.Type)
+	}
+	return roleTypes
+}
+
+// ValidateIRSARoleTypes returns an error if any of the given role types is not an IRSA role reconciled along with the
+// main role of a cluster.
+func ValidateIRSARoleTypes(roleTypes []string) error {
+	for _, roleType := range roleTypes {
+		if !slices.Contains(IRSARoleTypes(), roleType) {
+			return fmt.Errorf("unknown IRSA role type %q, expected one of %v", roleType, IRSARoleTypes())
+		}
+	}
+	return nil
+}
+
+// ValidateRoleTypes returns an error if any of the given role types has no role definition.
+func ValidateRoleTypes(roleTypes []string) error {
+	for _, roleType := range roleTypes {
+		if _, ok := GetRoleDefinition(roleType); !ok {
+			var known []string
+			for _, d := range RoleDefinitions() {
+				known = append(known, d.Type)
+			}
+			return fmt.Errorf("unknown role type %q, expected one of %v", roleType, known)
+		}
+	}
+	return nil
+}
+
+// ValidateServiceAccounts returns an error if the service accounts are set for a role type that is not assumed by
+// service accounts or if any of them is invalid.
+func ValidateServiceAccounts(serviceAccounts map[string][]ServiceAccount) error {
+	for roleType, sas := range serviceAccounts {
+		d, ok := GetRoleDefinition(roleType)
+		if !ok || d.TrustKind == TrustKindEC2 {
+			return fmt.Errorf("role type %q is not assumed by service accounts", roleType)
+		}
+		if len(sas) == 0 {
+			return fmt.Errorf("no service accounts for role type %q", roleType)
+		}
+		for _, sa := range sas {
+			if err := sa.Validate(); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func getInlinePolicyTemplate(roleType string, objectLabels map[string]string) string {
+	d, ok := GetRoleDefinition(roleType)
+	if !ok {
+		return ""
+	}
+	return d.policyTemplate(objectLabels)
+}
+
+func getTrustPolicyTemplate(roleType string) string {
+	d, ok := GetRoleDefinition(roleType)
+	if !ok {
+		return ""
+	}
+	return d.TrustPolicyTemplate
+}
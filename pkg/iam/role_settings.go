@@ -0,0 +1,144 @@
+package iam
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/iam"
+)
+
+const (
+	defaultRolePath               = "/"
+	defaultMaxSessionDuration     = int32(3600)
+	minMaxSessionDuration         = int32(3600)
+	maxMaxSessionDuration         = int32(43200)
+	maxRoleDescriptionLength      = 1000
+	maxRolePathLength             = 512
+	roleDescriptionManagedByNotes = "managed by capa-iam-operator"
+)
+
+// RoleSettings holds the attributes of a role besides its policies. Empty fields fall back to the defaults of the role
+// type.
+type RoleSettings struct {
+	Path               string
+	Description        string
+	MaxSessionDuration int32
+}
+
+// Validate returns an error if the settings would be rejected by IAM.
+func (rs RoleSettings) Validate() error {
+	if rs.Path != "" && (!strings.HasPrefix(rs.Path, "/") || !strings.HasSuffix(rs.Path, "/") || len(rs.Path) > maxRolePathLength) {
+		return fmt.Errorf("role path %q must begin and end with '/' and be at most %d characters long", rs.Path, maxRolePathLength)
+	}
+	if len(rs.Description) > maxRoleDescriptionLength {
+		return fmt.Errorf("role description must be at most %d characters long", maxRoleDescriptionLength)
+	}
+	if rs.MaxSessionDuration != 0 && (rs.MaxSessionDuration < minMaxSessionDuration || rs.MaxSessionDuration > maxMaxSessionDuration) {
+		return fmt.Errorf("role max session duration %d must be between %d and %d seconds", rs.MaxSessionDuration, minMaxSessionDuration, maxMaxSessionDuration)
+	}
+	return nil
+}
+
+// RoleSettingsOverrides overrides the settings of the roles of a cluster. Default applies to the roles of all types
+// and ByRoleType to the roles of one type, taking precedence over Default. A description only fits the roles of one
+// type, so it cannot be overridden in Default.
+type RoleSettingsOverrides struct {
+	Default    RoleSettings
+	ByRoleType map[string]RoleSettings
+}
+
+// Validate returns an error if the overrides would be rejected by IAM or name unknown role types.
+func (o RoleSettingsOverrides) Validate() error {
+	if o.Default.Description != "" {
+		return fmt.Errorf("role description can only be overridden per role type")
+	}
+	if err := o.Default.Validate(); err != nil {
+		return err
+	}
+	for roleType, settings := range o.ByRoleType {
+		if _, ok := GetRoleDefinition(roleType); !ok {
+			return fmt.Errorf("unknown role type %q", roleType)
+		}
+		if err := settings.Validate(); err != nil {
+			return fmt.Errorf("role type %q: %w", roleType, err)
+		}
+	}
+	return nil
+}
+
+// override returns the settings with the non-empty fields of overrides applied.
+func (rs RoleSettings) override(overrides RoleSettings) RoleSettings {
+	if overrides.Path != "" {
+		rs.Path = overrides.Path
+	}
+	if overrides.Description != "" {
+		rs.Description = overrides.Description
+	}
+	if overrides.MaxSessionDuration != 0 {
+		rs.MaxSessionDuration = overrides.MaxSessionDuration
+	}
+	return rs
+}
+
+// defaultRoleSettings returns the settings roles of the given type get unless they are overridden for the cluster.
+func defaultRoleSettings(roleType string, clusterName string) RoleSettings {
+	description := roleType
+	if d, ok := GetRoleDefinition(roleType); ok {
+		description = d.Description
+	}
+
+	return RoleSettings{
+		Path:               defaultRolePath,
+		Description:        fmt.Sprintf("%s of cluster %s, %s", description, clusterName, roleDescriptionManagedByNotes),
+		MaxSessionDuration: defaultMaxSessionDuration,
+	}
+}
+
+// roleSettings returns the desired settings of a role of the given type, applying the overrides of the cluster.
+func (s *IAMService) roleSettings(roleType string) RoleSettings {
+	return defaultRoleSettings(roleType, s.clusterName).
+		override(s.roleSettingsOverrides.Default).
+		override(s.roleSettingsOverrides.ByRoleType[roleType])
+}
+
+// reconcileRoleSettings makes sure the description and maximum session duration of an existing role match the desired
+// ones. The path of a role cannot be changed after creation, so a differing path is only reported.
+func (s *IAMService) reconcileRoleSettings(roleName string, roleType string) error {
+	l := s.log.WithValues("role_name", roleName)
+	settings := s.roleSettings(roleType)
+
+	role, err := s.iamClient.GetRole(context.TODO(), &iam.GetRoleInput{
+		RoleName: aws.String(roleName),
+	})
+	if IsNotFound(err) {
+		l.Info("role doesn't exist. Skipping reconciliation of role settings")
+		return nil
+	}
+	if err != nil {
+		l.Error(err, "failed to fetch IAM role")
+		return err
+	}
+
+	if path := aws.ToString(role.Role.Path); path != "" && path != settings.Path {
+		l.Info("path of IAM role differs from the expected one and can only be changed by recreating the role", "path", path, "expected_path", settings.Path)
+	}
+
+	if aws.ToString(role.Role.Description) == settings.Description && aws.ToInt32(role.Role.MaxSessionDuration) == settings.MaxSessionDuration {
+		return nil
+	}
+
+	_, err = s.iamClient.UpdateRole(context.TODO(), &iam.UpdateRoleInput{
+		RoleName:           aws.String(roleName),
+		Description:        aws.String(settings.Description),
+		MaxSessionDuration: aws.Int32(settings.MaxSessionDuration),
+	})
+	if err != nil {
+		l.Error(err, "failed to update IAM role")
+		return err
+	}
+	l.Info("updated description and max session duration of IAM role")
+
+	return nil
+}
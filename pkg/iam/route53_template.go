@@ -1,5 +1,7 @@
 package iam
 
+// trustIdentityPolicyIRSA allows the service accounts of the role to assume it, with one entry per service account in
+// the condition.
 const trustIdentityPolicyIRSA = `{
   "Version": "2012-10-17",
   "Statement": [
@@ -13,7 +15,11 @@ const trustIdentityPolicyIRSA = `{
       "Action": "sts:AssumeRoleWithWebIdentity",
       "Condition": {
         "StringEquals": {
-          "{{ $domain }}:sub": "system:serviceaccount:{{ $.Namespace }}:{{ $.ServiceAccount }}"
+          "{{ $domain }}:sub": [
+            {{- range $i, $sa := $.ServiceAccounts }}
+            {{ if gt $i 0 }},{{ end }}"system:serviceaccount:{{ $sa.Namespace }}:{{ $sa.Name }}"
+            {{- end }}
+          ]
         }
       }
     }
@@ -22,7 +28,9 @@ const trustIdentityPolicyIRSA = `{
 }
 `
 
-const albControllerTrustIdentityPolicyIRSA = `{
+// trustIdentityPolicyIRSAWildcard is like trustIdentityPolicyIRSA, but namespaces and names of the service accounts may
+// contain wildcards.
+const trustIdentityPolicyIRSAWildcard = `{
   "Version": "2012-10-17",
   "Statement": [
     {{- range $index, $domain := .IRSATrustDomains }}
@@ -35,29 +43,11 @@ const albControllerTrustIdentityPolicyIRSA = `{
       "Action": "sts:AssumeRoleWithWebIdentity",
       "Condition": {
         "StringLike": {
-          "{{ $domain }}:sub": "system:serviceaccount:*:{{ $.ServiceAccount }}"
-        }
-      }
-    }
-    {{- end }}
-  ]
-}
-`
-
-const externalDnsTrustIdentityPolicyIRSA = `{
-  "Version": "2012-10-17",
-  "Statement": [
-    {{- range $index, $domain := .IRSATrustDomains }}
-    {{ if gt $index 0 }},{{ end -}}
-    {
-      "Effect": "Allow",
-      "Principal": {
-        "Federated": "arn:{{ $.AWSPartition }}:iam::{{ $.AccountID }}:oidc-provider/{{ $domain }}"
-      },
-      "Action": "sts:AssumeRoleWithWebIdentity",
-      "Condition": {
-        "StringLike": {
-          "{{ $domain }}:sub": "system:serviceaccount:*:*{{ $.ServiceAccount }}*"
+          "{{ $domain }}:sub": [
+            {{- range $i, $sa := $.ServiceAccounts }}
+            {{ if gt $i 0 }},{{ end }}"system:serviceaccount:{{ $sa.Namespace }}:{{ $sa.Name }}"
+            {{- end }}
+          ]
         }
       }
     }
@@ -72,7 +62,7 @@ const route53RolePolicyTemplate = `{
     {
       "Action": "route53:ChangeResourceRecordSets",
       "Resource": [
-        "arn:*:route53:::hostedzone/*"
+        "arn:{{ .AWSPartition }}:route53:::hostedzone/*"
       ],
       "Effect": "Allow"
     },
@@ -94,7 +84,7 @@ const route53RolePolicyTemplateForCertManager = `{
     {
       "Effect": "Allow",
       "Action": "route53:GetChange",
-      "Resource": "arn:*:route53:::change/*"
+      "Resource": "arn:{{ .AWSPartition }}:route53:::change/*"
     },
     {
       "Effect": "Allow",
@@ -102,7 +92,7 @@ const route53RolePolicyTemplateForCertManager = `{
         "route53:ChangeResourceRecordSets",
         "route53:ListResourceRecordSets"
       ],
-      "Resource": "arn:*:route53:::hostedzone/*"
+      "Resource": "arn:{{ .AWSPartition }}:route53:::hostedzone/*"
     },
     {
       "Effect": "Allow",
@@ -0,0 +1,225 @@
+package iam
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/iam"
+	iamtypes "github.com/aws/aws-sdk-go-v2/service/iam/types"
+)
+
+// IAMControllerCustomTagKeysTag records which of the custom tags were set by this operator, so that they can be
+// removed again once they are dropped from the cluster's additional tags. Tags that are not listed here are never
+// removed.
+const IAMControllerCustomTagKeysTag = "capi-iam-controller/custom-tag-keys"
+
+// maxTagValueLength is the maximum length IAM allows for a tag value.
+const maxTagValueLength = 256
+
+// desiredTags returns the tags that roles and instance profiles created by this operator should carry.
+func (s *IAMService) desiredTags() []iamtypes.Tag {
+	tags := []iamtypes.Tag{
+		{
+			Key:   aws.String(IAMControllerOwnedTag),
+			Value: aws.String(""),
+		},
+		{
+			Key:   aws.String(fmt.Sprintf(ClusterIDTag, s.clusterName)),
+			Value: aws.String("owned"),
+		},
+	}
+
+	if s.permissionsBoundary != "" {
+		tags = append(tags, iamtypes.Tag{
+			Key:   aws.String(IAMControllerPermissionsBoundaryTag),
+			Value: aws.String(""),
+		})
+	}
+
+	keys := make([]string, 0, len(s.customTags))
+	for k := range s.customTags {
+		if s.isOwnershipTag(k) || k == IAMControllerCustomTagKeysTag || k == IAMControllerPermissionsBoundaryTag {
+			continue
+		}
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+
+	for _, k := range keys {
+		tags = append(tags, iamtypes.Tag{
+			Key:   aws.String(k),
+			Value: aws.String(s.customTags[k]),
+		})
+	}
+	if len(keys) > 0 {
+		tags = append(tags, iamtypes.Tag{
+			Key:   aws.String(IAMControllerCustomTagKeysTag),
+			Value: aws.String(customTagKeysValue(keys)),
+		})
+	}
+
+	return tags
+}
+
+func (s *IAMService) isOwnershipTag(key string) bool {
+	return key == IAMControllerOwnedTag || key == fmt.Sprintf(ClusterIDTag, s.clusterName)
+}
+
+// reconcileTags makes sure the tags of an existing role and its instance profile match the desired ones. Only roles
+// created by this operator are touched, and the ownership tags are never added to existing roles: they mark roles the
+// operator deletes, so adding them would make the operator delete roles it did not create.
+func (s *IAMService) reconcileTags(roleName string) error {
+	l := s.log.WithValues("role_name", roleName)
+	var desired []iamtypes.Tag
+	for _, t := range s.desiredTags() {
+		if !s.isOwnershipTag(aws.ToString(t.Key)) {
+			desired = append(desired, t)
+		}
+	}
+
+	role, err := s.iamClient.GetRole(context.TODO(), &iam.GetRoleInput{
+		RoleName: aws.String(roleName),
+	})
+	if IsNotFound(err) {
+		l.Info("role doesn't exist. Skipping reconciliation of tags")
+		return nil
+	}
+	if err != nil {
+		l.Error(err, "failed to fetch IAM role")
+		return err
+	}
+	if !hasTag(role.Role.Tags, IAMControllerOwnedTag) {
+		l.Info("role is not owned by the operator. Skipping reconciliation of tags")
+		return nil
+	}
+
+	toTag, toUntag := s.diffTags(role.Role.Tags, desired)
+	if len(toTag) > 0 {
+		_, err = s.iamClient.TagRole(context.TODO(), &iam.TagRoleInput{
+			RoleName: aws.String(roleName),
+			Tags:     toTag,
+		})
+		if err != nil {
+			l.Error(err, "failed to tag IAM role")
+			return err
+		}
+		l.Info("updated tags of IAM role", "tags", tagKeys(toTag))
+	}
+	if len(toUntag) > 0 {
+		_, err = s.iamClient.UntagRole(context.TODO(), &iam.UntagRoleInput{
+			RoleName: aws.String(roleName),
+			TagKeys:  toUntag,
+		})
+		if err != nil {
+			l.Error(err, "failed to untag IAM role")
+			return err
+		}
+		l.Info("removed tags from IAM role", "tags", toUntag)
+	}
+
+	profile, err := s.iamClient.GetInstanceProfile(context.TODO(), &iam.GetInstanceProfileInput{
+		InstanceProfileName: aws.String(roleName),
+	})
+	if IsNotFound(err) {
+		l.Info("instance profile doesn't exist. Skipping reconciliation of tags")
+		return nil
+	}
+	if err != nil {
+		l.Error(err, "failed to fetch instance profile")
+		return err
+	}
+
+	toTag, toUntag = s.diffTags(profile.InstanceProfile.Tags, desired)
+	if len(toTag) > 0 {
+		_, err = s.iamClient.TagInstanceProfile(context.TODO(), &iam.TagInstanceProfileInput{
+			InstanceProfileName: aws.String(roleName),
+			Tags:                toTag,
+		})
+		if err != nil {
+			l.Error(err, "failed to tag instance profile")
+			return err
+		}
+		l.Info("updated tags of instance profile", "tags", tagKeys(toTag))
+	}
+	if len(toUntag) > 0 {
+		_, err = s.iamClient.UntagInstanceProfile(context.TODO(), &iam.UntagInstanceProfileInput{
+			InstanceProfileName: aws.String(roleName),
+			TagKeys:             toUntag,
+		})
+		if err != nil {
+			l.Error(err, "failed to untag instance profile")
+			return err
+		}
+		l.Info("removed tags from instance profile", "tags", toUntag)
+	}
+
+	return nil
+}
+
+// diffTags returns the tags that need to be set and the tag keys that need to be removed to get from the actual to the
+// desired tags. Only custom tags that were previously recorded in IAMControllerCustomTagKeysTag are ever removed.
+func (s *IAMService) diffTags(actual []iamtypes.Tag, desired []iamtypes.Tag) ([]iamtypes.Tag, []string) {
+	actualTags := map[string]string{}
+	for _, t := range actual {
+		actualTags[aws.ToString(t.Key)] = aws.ToString(t.Value)
+	}
+	desiredTags := map[string]string{}
+	for _, t := range desired {
+		desiredTags[aws.ToString(t.Key)] = aws.ToString(t.Value)
+	}
+
+	var toTag []iamtypes.Tag
+	for _, t := range desired {
+		if v, ok := actualTags[aws.ToString(t.Key)]; !ok || v != aws.ToString(t.Value) {
+			toTag = append(toTag, t)
+		}
+	}
+
+	var toUntag []string
+	if managedKeys, ok := actualTags[IAMControllerCustomTagKeysTag]; ok {
+		for _, k := range strings.Fields(managedKeys) {
+			if _, stillDesired := desiredTags[k]; stillDesired || s.isOwnershipTag(k) {
+				continue
+			}
+			if _, present := actualTags[k]; present && !slices.Contains(toUntag, k) {
+				toUntag = append(toUntag, k)
+			}
+		}
+		if _, stillDesired := desiredTags[IAMControllerCustomTagKeysTag]; !stillDesired {
+			toUntag = append(toUntag, IAMControllerCustomTagKeysTag)
+		}
+	}
+
+	return toTag, toUntag
+}
+
+// customTagKeysValue joins the given tag keys into a tag value. Keys containing whitespace cannot be represented and
+// keys that would exceed the maximum tag value length are left out, which means they are never removed again.
+func customTagKeysValue(keys []string) string {
+	value := ""
+	for _, k := range keys {
+		if strings.ContainsFunc(k, func(r rune) bool { return r == ' ' || r == '\t' }) {
+			continue
+		}
+		next := k
+		if value != "" {
+			next = value + " " + k
+		}
+		if len(next) > maxTagValueLength {
+			break
+		}
+		value = next
+	}
+	return value
+}
+
+func tagKeys(tags []iamtypes.Tag) []string {
+	keys := make([]string, 0, len(tags))
+	for _, t := range tags {
+		keys = append(keys, aws.ToString(t.Key))
+	}
+	return keys
+}
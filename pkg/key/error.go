@@ -9,3 +9,7 @@ var clusterValuesConfigMapNotFound = &microerror.Error{
 var baseDomainNotFound = &microerror.Error{
 	Kind: "baseDomainNotFoundError",
 }
+
+var invalidAnnotationError = &microerror.Error{
+	Kind: "invalidAnnotationError",
+}
@@ -3,10 +3,12 @@ package key
 import (
 	"context"
 	"fmt"
+	"math"
 	"slices"
+	"strconv"
 	"strings"
+	"time"
 
-	awsarn "github.com/aws/aws-sdk-go-v2/aws/arn"
 	"github.com/giantswarm/microerror"
 	corev1 "k8s.io/api/core/v1"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -16,12 +18,52 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	"github.com/giantswarm/capa-iam-operator/v3/pkg/iam"
+	"github.com/giantswarm/capa-iam-operator/v3/pkg/partition"
 )
 
 const (
 	ClusterNameLabel        = "cluster.x-k8s.io/cluster-name"
 	ClusterWatchFilterLabel = "cluster.x-k8s.io/watch-filter"
 	ClusterRole             = "cluster.x-k8s.io/role"
+
+	// PermissionsBoundaryAnnotation overrides the operator-wide permissions boundary for the roles of a cluster.
+	PermissionsBoundaryAnnotation = "aws.giantswarm.io/iam-permissions-boundary"
+
+	// RolePathAnnotation and RoleMaxSessionDurationAnnotation override the operator-wide path and maximum session
+	// duration of the roles of a cluster. Durations are given in seconds (e.g. "7200") or as Go durations (e.g. "2h").
+	RolePathAnnotation               = "aws.giantswarm.io/iam-role-path"
+	RoleMaxSessionDurationAnnotation = "aws.giantswarm.io/iam-role-max-session-duration"
+
+	// RolePathAnnotationPrefix, RoleDescriptionAnnotationPrefix and RoleMaxSessionDurationAnnotationPrefix followed by
+	// a role type override the path, description and maximum session duration of the roles of that type.
+	RolePathAnnotationPrefix               = "aws.giantswarm.io/iam-role-path-"
+	RoleDescriptionAnnotationPrefix        = "aws.giantswarm.io/iam-role-description-"
+	RoleMaxSessionDurationAnnotationPrefix = "aws.giantswarm.io/iam-role-max-session-duration-"
+
+	// AdditionalStatementsAnnotation references a ConfigMap in the namespace of the cluster holding extra policy
+	// statements for the roles of the cluster, keyed by role type.
+	AdditionalStatementsAnnotation = "aws.giantswarm.io/iam-additional-statements-configmap"
+
+	// EnabledIRSARolesAnnotation and DisabledIRSARolesAnnotation add IRSA role types to or remove them from the
+	// operator-wide ones for a cluster, as comma-separated lists.
+	EnabledIRSARolesAnnotation  = "aws.giantswarm.io/irsa-roles-enabled"
+	DisabledIRSARolesAnnotation = "aws.giantswarm.io/irsa-roles-disabled"
+
+	// IRSAServiceAccountsAnnotationPrefix followed by a role type overrides the service accounts that may assume the
+	// role, as a comma-separated list of "<namespace>:<name>" pairs.
+	IRSAServiceAccountsAnnotationPrefix = "aws.giantswarm.io/irsa-service-accounts-"
+
+	// KarpenterInterruptionQueueARNAnnotation is the ARN of the SQS queue the Karpenter controller of a cluster
+	// receives interruption events from.
+	KarpenterInterruptionQueueARNAnnotation = "aws.giantswarm.io/karpenter-interruption-queue-arn"
+
+	// ServiceAccountTrustAnnotation sets how service accounts assume the IRSA roles of an EKS cluster, as a
+	// comma-separated list of "irsa" and "pod-identity".
+	ServiceAccountTrustAnnotation = "aws.giantswarm.io/service-account-trust"
+
+	// OIDCProviderThumbprintsAnnotation sets the server certificate thumbprints of the OIDC providers of a cluster, as
+	// a comma-separated list.
+	OIDCProviderThumbprintsAnnotation = "aws.giantswarm.io/irsa-oidc-provider-thumbprints"
 )
 
 func FinalizerName(roleName string) string {
@@ -54,19 +96,6 @@ func GetAWSClusterByName(ctx context.Context, ctrlClient client.Client, clusterN
 	return &awsClusterList.Items[0], nil
 }
 
-func GetAWSClusterRoleIdentity(ctx context.Context, ctrlClient client.Client, awsClusterRoleIdentityName string) (*capa.AWSClusterRoleIdentity, error) {
-	awsClusterRoleIdentity := &capa.AWSClusterRoleIdentity{}
-
-	if err := ctrlClient.Get(ctx, types.NamespacedName{
-		Name:      awsClusterRoleIdentityName,
-		Namespace: "",
-	}, awsClusterRoleIdentity); err != nil {
-		return nil, err
-	}
-
-	return awsClusterRoleIdentity, nil
-}
-
 func HasCapiWatchLabel(labels map[string]string) bool {
 	value, ok := labels[ClusterWatchFilterLabel]
 	if ok {
@@ -98,8 +127,9 @@ func IsBastionAWSMachineTemplate(labels map[string]string) bool {
 }
 
 func IRSADomain(baseDomain string, region string, awsAccount string, clusterName string) string {
-	if IsChinaRegion(region) {
-		return fmt.Sprintf("s3.%s.amazonaws.com.cn/%s-g8s-%s-oidc-pod-identity-v3", region, awsAccount, clusterName)
+	// China regions have no CloudFront, the OIDC discovery documents are served from S3 directly
+	if p := partition.ForRegion(region); p.Name == "aws-cn" {
+		return fmt.Sprintf("s3.%s.%s/%s-g8s-%s-oidc-pod-identity-v3", region, p.DNSSuffix, awsAccount, clusterName)
 	} else {
 		return fmt.Sprintf("irsa.%s", baseDomain)
 	}
@@ -140,16 +170,6 @@ func GetBaseDomain(ctx context.Context, ctrlClient client.Client, clusterName, n
 	return baseDomain, nil
 }
 
-func GetAWSAccountID(awsClusterRoleIdentity *capa.AWSClusterRoleIdentity) (string, error) {
-	arn := awsClusterRoleIdentity.Spec.RoleArn
-	a, err := awsarn.Parse(arn)
-	if err != nil {
-		return "", microerror.Mask(err)
-	}
-
-	return a.AccountID, nil
-}
-
 func GetIRSATrustDomains(awsMachineTemplate *capa.AWSMachineTemplate, awsCluster *capa.AWSCluster, ensurePrimaryIRSATrustDomain string) []string {
 	var values []string
 	if s := GetAnnotation(awsCluster, "aws.giantswarm.io/irsa-trust-domains"); s != "" {
@@ -169,6 +189,167 @@ func GetIRSATrustDomains(awsMachineTemplate *capa.AWSMachineTemplate, awsCluster
 	return irsaTrustDomains
 }
 
+// GetPermissionsBoundary returns the permissions boundary policy ARN for the roles of a cluster, preferring the
+// annotation on the given object over the operator-wide default.
+func GetPermissionsBoundary(o v1.Object, defaultPermissionsBoundary string) string {
+	if s := strings.TrimSpace(GetAnnotation(o, PermissionsBoundaryAnnotation)); s != "" {
+		return s
+	}
+	return defaultPermissionsBoundary
+}
+
+// GetRoleSettings returns the role settings overrides of a cluster, starting from the operator-wide defaults and
+// applying the annotations on the given object.
+func GetRoleSettings(o v1.Object, defaults iam.RoleSettings) (iam.RoleSettingsOverrides, error) {
+	overrides := iam.RoleSettingsOverrides{
+		Default:    defaults,
+		ByRoleType: map[string]iam.RoleSettings{},
+	}
+
+	if s := strings.TrimSpace(GetAnnotation(o, RolePathAnnotation)); s != "" {
+		overrides.Default.Path = s
+	}
+	if s := strings.TrimSpace(GetAnnotation(o, RoleMaxSessionDurationAnnotation)); s != "" {
+		d, err := ParseMaxSessionDuration(s)
+		if err != nil {
+			return iam.RoleSettingsOverrides{}, microerror.Maskf(invalidAnnotationError, "annotation %q: %s", RoleMaxSessionDurationAnnotation, err)
+		}
+		overrides.Default.MaxSessionDuration = d
+	}
+
+	for annotation, value := range o.GetAnnotations() {
+		value = strings.TrimSpace(value)
+		if roleType, ok := strings.CutPrefix(annotation, RolePathAnnotationPrefix); ok {
+			settings := overrides.ByRoleType[roleType]
+			settings.Path = value
+			overrides.ByRoleType[roleType] = settings
+		} else if roleType, ok := strings.CutPrefix(annotation, RoleDescriptionAnnotationPrefix); ok {
+			settings := overrides.ByRoleType[roleType]
+			settings.Description = value
+			overrides.ByRoleType[roleType] = settings
+		} else if roleType, ok := strings.CutPrefix(annotation, RoleMaxSessionDurationAnnotationPrefix); ok {
+			d, err := ParseMaxSessionDuration(value)
+			if err != nil {
+				return iam.RoleSettingsOverrides{}, microerror.Maskf(invalidAnnotationError, "annotation %q: %s", annotation, err)
+			}
+			settings := overrides.ByRoleType[roleType]
+			settings.MaxSessionDuration = d
+			overrides.ByRoleType[roleType] = settings
+		}
+	}
+
+	err := overrides.Validate()
+	if err != nil {
+		return iam.RoleSettingsOverrides{}, microerror.Maskf(invalidAnnotationError, "%s", err)
+	}
+
+	return overrides, nil
+}
+
+// ParseMaxSessionDuration parses a maximum session duration given in seconds (e.g. "7200") or as Go duration
+// (e.g. "2h") and returns it in seconds. Durations that do not fit into 32 bits are rejected instead of wrapping.
+func ParseMaxSessionDuration(s string) (int32, error) {
+	seconds, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return 0, fmt.Errorf("must be a number of seconds or a duration like \"2h\"")
+		}
+		seconds = int64(d / time.Second)
+	}
+	if seconds > math.MaxInt32 || seconds < math.MinInt32 {
+		return 0, fmt.Errorf("must not exceed %d seconds", math.MaxInt32)
+	}
+	return int32(seconds), nil
+}
+
+// GetIRSARoleTypes returns the IRSA role types of a cluster, starting from the operator-wide ones (all if nil) and
+// applying the annotations on the given object. Disabling a role takes precedence over enabling it.
+func GetIRSARoleTypes(o v1.Object, defaultRoleTypes []string) ([]string, error) {
+	roleTypes := slices.Clone(defaultRoleTypes)
+	if roleTypes == nil {
+		roleTypes = iam.IRSARoleTypes()
+	}
+
+	enabled := splitAnnotation(o, EnabledIRSARolesAnnotation)
+	if err := iam.ValidateIRSARoleTypes(enabled); err != nil {
+		return nil, microerror.Maskf(invalidAnnotationError, "annotation %q: %s", EnabledIRSARolesAnnotation, err)
+	}
+	disabled := splitAnnotation(o, DisabledIRSARolesAnnotation)
+	if err := iam.ValidateIRSARoleTypes(disabled); err != nil {
+		return nil, microerror.Maskf(invalidAnnotationError, "annotation %q: %s", DisabledIRSARolesAnnotation, err)
+	}
+
+	for _, roleType := range enabled {
+		if !slices.Contains(roleTypes, roleType) {
+			roleTypes = append(roleTypes, roleType)
+		}
+	}
+	roleTypes = slices.DeleteFunc(roleTypes, func(roleType string) bool { return slices.Contains(disabled, roleType) })
+
+	return roleTypes, nil
+}
+
+// GetServiceAccounts returns the service accounts overridden per role type by the annotations on the given object.
+func GetServiceAccounts(o v1.Object) (map[string][]iam.ServiceAccount, error) {
+	serviceAccounts := map[string][]iam.ServiceAccount{}
+	for annotation, value := range o.GetAnnotations() {
+		roleType, ok := strings.CutPrefix(annotation, IRSAServiceAccountsAnnotationPrefix)
+		if !ok {
+			continue
+		}
+		sas, err := iam.ParseServiceAccounts(value)
+		if err != nil {
+			return nil, microerror.Maskf(invalidAnnotationError, "annotation %q: %s", annotation, err)
+		}
+		serviceAccounts[roleType] = sas
+	}
+
+	err := iam.ValidateServiceAccounts(serviceAccounts)
+	if err != nil {
+		return nil, microerror.Maskf(invalidAnnotationError, "%s", err)
+	}
+
+	return serviceAccounts, nil
+}
+
+// GetServiceAccountTrust returns how service accounts assume the IRSA roles of the cluster as set by the annotation on
+// the given object. Nil means IRSA only.
+func GetServiceAccountTrust(o v1.Object) ([]iam.TrustKind, error) {
+	value := strings.TrimSpace(GetAnnotation(o, ServiceAccountTrustAnnotation))
+	if value == "" {
+		return nil, nil
+	}
+
+	kinds, err := iam.ParseServiceAccountTrust(value)
+	if err != nil {
+		return nil, microerror.Maskf(invalidAnnotationError, "annotation %q: %s", ServiceAccountTrustAnnotation, err)
+	}
+
+	return kinds, nil
+}
+
+// GetOIDCProviderThumbprints returns the thumbprints of the OIDC providers of the cluster as set by the annotation on the
+// given object.
+func GetOIDCProviderThumbprints(o v1.Object) ([]string, error) {
+	thumbprints := splitAnnotation(o, OIDCProviderThumbprintsAnnotation)
+	if err := iam.ValidateOIDCProviderThumbprints(thumbprints); err != nil {
+		return nil, microerror.Maskf(invalidAnnotationError, "annotation %q: %s", OIDCProviderThumbprintsAnnotation, err)
+	}
+	return thumbprints, nil
+}
+
+func splitAnnotation(o v1.Object, annotation string) []string {
+	var values []string
+	for _, value := range strings.Split(GetAnnotation(o, annotation), ",") {
+		value = strings.TrimSpace(value)
+		if value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
 // GetAnnotation returns the value of the specified annotation.
 func GetAnnotation(o v1.Object, annotation string) string {
 	annotations := o.GetAnnotations()
@@ -177,7 +358,3 @@ func GetAnnotation(o v1.Object, annotation string) string {
 	}
 	return annotations[annotation]
 }
-
-func IsChinaRegion(region string) bool {
-	return strings.Contains(region, "cn-")
-}
@@ -0,0 +1,52 @@
+// Package partition resolves the AWS partition of a region, which determines ARNs, DNS names and service principals.
+package partition
+
+import "strings"
+
+// Partition describes an AWS partition.
+type Partition struct {
+	// Name is the partition as used in ARNs, e.g. "aws-us-gov".
+	Name string
+	// DNSSuffix is the domain of the service endpoints, e.g. "amazonaws.com.cn".
+	DNSSuffix string
+	// EC2ServicePrincipal is the principal EC2 instances assume roles as.
+	EC2ServicePrincipal string
+	// STSAudience is the audience of the web identity tokens exchanged for role credentials.
+	STSAudience string
+}
+
+// partitions lists the partitions other than Default by the prefix of their regions. Prefixes are checked in order, so
+// more specific ones come first.
+var partitions = []struct {
+	regionPrefix string
+	partition    Partition
+}{
+	{regionPrefix: "cn-", partition: newPartition("aws-cn", "amazonaws.com.cn")},
+	{regionPrefix: "us-gov-", partition: newPartition("aws-us-gov", "amazonaws.com")},
+	{regionPrefix: "us-isob-", partition: newPartition("aws-iso-b", "sc2s.sgov.gov")},
+	{regionPrefix: "us-isof-", partition: newPartition("aws-iso-f", "csp.hci.ic.gov")},
+	{regionPrefix: "us-iso-", partition: newPartition("aws-iso", "c2s.ic.gov")},
+	{regionPrefix: "eu-isoe-", partition: newPartition("aws-iso-e", "cloud.adc-e.uk")},
+}
+
+// Default is the partition of all regions that do not belong to another partition.
+var Default = newPartition("aws", "amazonaws.com")
+
+func newPartition(name string, dnsSuffix string) Partition {
+	return Partition{
+		Name:                name,
+		DNSSuffix:           dnsSuffix,
+		EC2ServicePrincipal: "ec2." + dnsSuffix,
+		STSAudience:         "sts." + dnsSuffix,
+	}
+}
+
+// ForRegion returns the partition of the given region.
+func ForRegion(region string) Partition {
+	for _, p := range partitions {
+		if strings.HasPrefix(region, p.regionPrefix) {
+			return p.partition
+		}
+	}
+	return Default
+}
@@ -5,10 +5,13 @@
 package mocks
 
 import (
+	context "context"
 	reflect "reflect"
 
 	aws "github.com/aws/aws-sdk-go-v2/aws"
+	sts "github.com/aws/aws-sdk-go-v2/service/sts"
 	gomock "github.com/golang/mock/gomock"
+	v1beta2 "sigs.k8s.io/cluster-api-provider-aws/v2/api/v1beta2"
 )
 
 // MockAwsClientInterface is a mock of AwsClientInterface interface.
@@ -34,17 +37,95 @@ func (m *MockAwsClientInterface) EXPECT() *MockAwsClientInterfaceMockRecorder {
 	return m.recorder
 }
 
+// GetAWSAccountID mocks base method.
+func (m *MockAwsClientInterface) GetAWSAccountID(ctx context.Context, identityRef *v1beta2.AWSIdentityReference, cfg aws.Config) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetAWSAccountID", ctx, identityRef, cfg)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetAWSAccountID indicates an expected call of GetAWSAccountID.
+func (mr *MockAwsClientInterfaceMockRecorder) GetAWSAccountID(ctx, identityRef, cfg interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAWSAccountID", reflect.TypeOf((*MockAwsClientInterface)(nil).GetAWSAccountID), ctx, identityRef, cfg)
+}
+
 // GetAWSClientConfig mocks base method.
-func (m *MockAwsClientInterface) GetAWSClientConfig(awsRoleARN, region string) (aws.Config, error) {
+func (m *MockAwsClientInterface) GetAWSClientConfig(ctx context.Context, identityRef *v1beta2.AWSIdentityReference, namespace, region string) (aws.Config, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetAWSClientConfig", awsRoleARN, region)
+	ret := m.ctrl.Call(m, "GetAWSClientConfig", ctx, identityRef, namespace, region)
 	ret0, _ := ret[0].(aws.Config)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // GetAWSClientConfig indicates an expected call of GetAWSClientConfig.
-func (mr *MockAwsClientInterfaceMockRecorder) GetAWSClientConfig(awsRoleARN, region interface{}) *gomock.Call {
+func (mr *MockAwsClientInterfaceMockRecorder) GetAWSClientConfig(ctx, identityRef, namespace, region interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAWSClientConfig", reflect.TypeOf((*MockAwsClientInterface)(nil).GetAWSClientConfig), ctx, identityRef, namespace, region)
+}
+
+// MockSTSClient is a mock of STSClient interface.
+type MockSTSClient struct {
+	ctrl     *gomock.Controller
+	recorder *MockSTSClientMockRecorder
+}
+
+// MockSTSClientMockRecorder is the mock recorder for MockSTSClient.
+type MockSTSClientMockRecorder struct {
+	mock *MockSTSClient
+}
+
+// NewMockSTSClient creates a new mock instance.
+func NewMockSTSClient(ctrl *gomock.Controller) *MockSTSClient {
+	mock := &MockSTSClient{ctrl: ctrl}
+	mock.recorder = &MockSTSClientMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockSTSClient) EXPECT() *MockSTSClientMockRecorder {
+	return m.recorder
+}
+
+// AssumeRole mocks base method.
+func (m *MockSTSClient) AssumeRole(ctx context.Context, params *sts.AssumeRoleInput, optFns ...func(*sts.Options)) (*sts.AssumeRoleOutput, error) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{ctx, params}
+	for _, a := range optFns {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "AssumeRole", varargs...)
+	ret0, _ := ret[0].(*sts.AssumeRoleOutput)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// AssumeRole indicates an expected call of AssumeRole.
+func (mr *MockSTSClientMockRecorder) AssumeRole(ctx, params interface{}, optFns ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{ctx, params}, optFns...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AssumeRole", reflect.TypeOf((*MockSTSClient)(nil).AssumeRole), varargs...)
+}
+
+// GetCallerIdentity mocks base method.
+func (m *MockSTSClient) GetCallerIdentity(ctx context.Context, params *sts.GetCallerIdentityInput, optFns ...func(*sts.Options)) (*sts.GetCallerIdentityOutput, error) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{ctx, params}
+	for _, a := range optFns {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "GetCallerIdentity", varargs...)
+	ret0, _ := ret[0].(*sts.GetCallerIdentityOutput)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetCallerIdentity indicates an expected call of GetCallerIdentity.
+func (mr *MockSTSClientMockRecorder) GetCallerIdentity(ctx, params interface{}, optFns ...interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAWSClientConfig", reflect.TypeOf((*MockAwsClientInterface)(nil).GetAWSClientConfig), awsRoleARN, region)
+	varargs := append([]interface{}{ctx, params}, optFns...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetCallerIdentity", reflect.TypeOf((*MockSTSClient)(nil).GetCallerIdentity), varargs...)
 }